@@ -0,0 +1,199 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// manifestName is the manifest file written next to the generated codes
+const manifestName = "manifest.json"
+
+// batchManifest describes a delivery so that it can be verified file by file
+type batchManifest struct {
+	Generator string          `json:"generator"`
+	Created   time.Time       `json:"created"`
+	Files     []manifestEntry `json:"files"`
+}
+
+// manifestEntry describes one generated file
+type manifestEntry struct {
+	File            string `json:"file"`
+	Row             int    `json:"row"`
+	Payload         string `json:"payload"`
+	Version         int    `json:"version"`
+	ErrorCorrection string `json:"error_correction"`
+	Mask            int    `json:"mask"`
+	Size            int    `json:"size"`
+	SHA256          string `json:"sha256"`
+}
+
+// batchSink receives the generated files in input order
+type batchSink interface {
+	// Add writes one file; name is relative and slash-separated
+	Add(name string, data []byte, modTime time.Time) error
+
+	// Close flushes the output
+	Close() error
+}
+
+// newBatchSink creates a sink from the output path: a .zip or .tar.gz/.tgz
+// archive, or a directory otherwise
+func newBatchSink(output string) (batchSink, error) {
+	lower := strings.ToLower(output)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		file, err := os.Create(output)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create archive: %w", err)
+		}
+		return &zipSink{file: file, zip: zip.NewWriter(file), names: make(map[string]bool)}, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		file, err := os.Create(output)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create archive: %w", err)
+		}
+		gz := gzip.NewWriter(file)
+		return &tarSink{file: file, gzip: gz, tar: tar.NewWriter(gz), names: make(map[string]bool)}, nil
+	default:
+		if err := os.MkdirAll(output, 0o755); err != nil {
+			return nil, fmt.Errorf("cannot create output directory: %w", err)
+		}
+		return &dirSink{dir: output, names: make(map[string]bool)}, nil
+	}
+}
+
+// dirSink writes the files into a directory
+type dirSink struct {
+	dir   string
+	names map[string]bool
+}
+
+func (s *dirSink) Add(name string, data []byte, modTime time.Time) error {
+	if err := claimName(s.names, name); err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (s *dirSink) Close() error {
+	return nil
+}
+
+// zipSink streams the files into a ZIP archive
+type zipSink struct {
+	file  *os.File
+	zip   *zip.Writer
+	names map[string]bool
+}
+
+func (s *zipSink) Add(name string, data []byte, modTime time.Time) error {
+	if err := claimName(s.names, name); err != nil {
+		return err
+	}
+	// PNG, JPEG, GIF and TIFF data is already compressed; text formats (manifest,
+	// SVG) and BMP benefit from deflate
+	method := zip.Deflate
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".tif", ".tiff":
+		method = zip.Store
+	}
+	w, err := s.zip.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: modTime})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (s *zipSink) Close() error {
+	if err := s.zip.Close(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// tarSink streams the files into a gzip-compressed TAR archive
+type tarSink struct {
+	file  *os.File
+	gzip  *gzip.Writer
+	tar   *tar.Writer
+	names map[string]bool
+}
+
+func (s *tarSink) Add(name string, data []byte, modTime time.Time) error {
+	if err := claimName(s.names, name); err != nil {
+		return err
+	}
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+		Format:   tar.FormatPAX,
+	}
+	if err := s.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := s.tar.Write(data)
+	return err
+}
+
+func (s *tarSink) Close() error {
+	err := s.tar.Close()
+	if gzErr := s.gzip.Close(); err == nil {
+		err = gzErr
+	}
+	if fileErr := s.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
+
+// claimName rejects a second file with the same name in one delivery
+func claimName(names map[string]bool, name string) error {
+	if names[name] {
+		return fmt.Errorf("duplicate file name %q", name)
+	}
+	names[name] = true
+	return nil
+}
+
+// newManifestEntry describes a generated file with its checksum
+func newManifestEntry(name string, row int, payload string, version int, ecLevel string, mask int, data []byte) manifestEntry {
+	sum := sha256.Sum256(data)
+	return manifestEntry{
+		File:            name,
+		Row:             row,
+		Payload:         payload,
+		Version:         version,
+		ErrorCorrection: ecLevel,
+		Mask:            mask,
+		Size:            len(data),
+		SHA256:          hex.EncodeToString(sum[:]),
+	}
+}
+
+// writeManifest adds the manifest as the last file of the delivery
+func writeManifest(sink batchSink, manifest batchManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return sink.Add(manifestName, append(data, '\n'), manifest.Created)
+}
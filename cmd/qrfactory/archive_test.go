@@ -0,0 +1,183 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// readDelivery returns the files of a delivery in write order (sorted by name
+// for a directory)
+func readDelivery(t *testing.T, output string) ([]string, map[string][]byte) {
+	t.Helper()
+	var names []string
+	files := make(map[string][]byte)
+	add := func(name string, r io.Reader) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("cannot read %s: %v", name, err)
+		}
+		names = append(names, name)
+		files[name] = data
+	}
+
+	switch filepath.Ext(output) {
+	case ".zip":
+		archive, err := zip.OpenReader(output)
+		if err != nil {
+			t.Fatalf("zip.OpenReader() error = %v", err)
+		}
+		defer archive.Close()
+		for _, f := range archive.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("cannot open %s: %v", f.Name, err)
+			}
+			add(f.Name, rc)
+			rc.Close()
+		}
+	case ".tgz":
+		file, err := os.Open(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("gzip.NewReader() error = %v", err)
+		}
+		archive := tar.NewReader(gz)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("tar Next() error = %v", err)
+			}
+			add(header.Name, archive)
+		}
+	default:
+		err := filepath.WalkDir(output, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			rel, _ := filepath.Rel(output, path)
+			add(filepath.ToSlash(rel), file)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("cannot walk %s: %v", output, err)
+		}
+	}
+	return names, files
+}
+
+func TestBatchSinkRoundTrip(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	inputs := []struct {
+		name string
+		data []byte
+	}{
+		{"1-hello.png", []byte("\x89PNG hello")},
+		{"codes/2-world.svg", []byte("<svg>world</svg>")},
+		{"3-empty.png", nil},
+	}
+
+	tests := []struct {
+		name      string
+		output    string
+		wantNames []string
+	}{
+		{"Directory", "out", []string{"1-hello.png", "3-empty.png", "codes/2-world.svg", manifestName}},
+		{"ZIP", "out.zip", []string{"1-hello.png", "codes/2-world.svg", "3-empty.png", manifestName}},
+		{"TAR", "out.tgz", []string{"1-hello.png", "codes/2-world.svg", "3-empty.png", manifestName}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), tt.output)
+			sink, err := newBatchSink(output)
+			if err != nil {
+				t.Fatalf("newBatchSink() error = %v", err)
+			}
+
+			manifest := batchManifest{Generator: "QRFactory test", Created: created}
+			for i, in := range inputs {
+				if err := sink.Add(in.name, in.data, created); err != nil {
+					t.Fatalf("Add(%s) error = %v", in.name, err)
+				}
+				manifest.Files = append(manifest.Files, newManifestEntry(in.name, i+1, "payload", 2, "M", i, in.data))
+			}
+			// claimName rejects a second file with the same name, whatever the sink
+			if err := sink.Add(inputs[0].name, []byte("other"), created); err == nil {
+				t.Error("a duplicate file name should be rejected")
+			}
+			if err := writeManifest(sink, manifest); err != nil {
+				t.Fatalf("writeManifest() error = %v", err)
+			}
+			if err := sink.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			names, files := readDelivery(t, output)
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("entries = %v, want %v", names, tt.wantNames)
+			}
+
+			var got batchManifest
+			if err := json.Unmarshal(files[manifestName], &got); err != nil {
+				t.Fatalf("invalid manifest: %v", err)
+			}
+			if got.Generator != manifest.Generator || !got.Created.Equal(created) || len(got.Files) != len(inputs) {
+				t.Fatalf("manifest = %+v, want %d files from %s", got, len(inputs), manifest.Generator)
+			}
+			for i, entry := range got.Files {
+				if entry != manifest.Files[i] {
+					t.Errorf("manifest entry %d = %+v, want %+v", i, entry, manifest.Files[i])
+				}
+				data, ok := files[entry.File]
+				if !ok {
+					t.Errorf("manifest lists %s, missing from the delivery", entry.File)
+					continue
+				}
+				sum := sha256.Sum256(data)
+				if entry.SHA256 != hex.EncodeToString(sum[:]) || entry.Size != len(data) {
+					t.Errorf("%s: checksum %s (%d bytes) does not match its %d bytes", entry.File, entry.SHA256, entry.Size, len(data))
+				}
+			}
+		})
+	}
+}
+
+func TestClaimName(t *testing.T) {
+	names := make(map[string]bool)
+	for _, tt := range []struct {
+		name    string
+		wantErr bool
+	}{
+		{"a.png", false},
+		{"b.png", false},
+		{"a.png", true},
+		{"dir/a.png", false},
+		{"A.png", false},
+	} {
+		if err := claimName(names, tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("claimName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
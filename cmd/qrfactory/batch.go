@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"qrfactory/pkg/batch"
+	"qrfactory/pkg/config"
+	"qrfactory/pkg/qr"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	batchDefaults   *config.QRConfig
+	batchInputType  string
+	batchOutputDir  string
+	batchNameFormat string
+	batchDataColumn string
+	batchWorkers    int
+)
+
+// Batch command to generate many QR codes from a CSV or NDJSON file
+var batchCmd = &cobra.Command{
+	Use:   "batch [input file]",
+	Short: "Generate QR codes from a CSV or JSON Lines file",
+	Long: `Generate one QR code per row of a CSV file (with a header row) or a JSON Lines file.
+Reads from standard input when the file is omitted or "-".
+
+Recognized columns / keys:
+  data       Data to encode (column name set with --data-column)
+  filename   Output file name, relative to the output directory (optional)
+  ec         Error correction level: L, M, Q or H (optional)
+  fg_color   Module color (optional)
+  bg_color   Background color (optional)
+  scale      Image scale (optional)
+
+Missing values fall back to the command flags.
+
+The codes are written into the output directory, or streamed into a ZIP or
+gzip-compressed TAR archive when the output ends in .zip, .tar.gz or .tgz.
+A manifest.json lists every file with its payload, version, error correction
+level, mask and SHA-256 checksum.`,
+	Args: cobra.MaximumNArgs(1),
+	// Row failures are reported in the summary, not with the usage text
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "-"
+		if len(args) == 1 {
+			path = args[0]
+		}
+		return runBatch(cmd.Context(), path)
+	},
+}
+
+func init() {
+	// Default options applied to every row
+	batchDefaults = config.NewDefaultConfig()
+	batchDefaults.Scale = 30
+
+	rootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().StringVar(&batchInputType, "input-format", "", "Input format: csv or ndjson (default: from the file extension)")
+	batchCmd.Flags().StringVarP(&batchOutputDir, "output", "o", ".", "Output directory, or a .zip / .tar.gz archive")
+	batchCmd.Flags().StringVarP(&batchNameFormat, "name", "n", "{{.Row}}-{{.Slug}}.png", "File name template (fields: .Row, .Slug, .Data, .ECLevel)")
+	batchCmd.Flags().StringVar(&batchDataColumn, "data-column", "data", "CSV column or JSON key holding the data to encode")
+	batchCmd.Flags().IntVarP(&batchWorkers, "workers", "w", runtime.NumCPU(), "Number of parallel workers")
+	batchCmd.Flags().IntVarP(&batchDefaults.Version, "version", "v", batchDefaults.Version, "QR code version (1-40)")
+	batchCmd.Flags().StringVarP(&batchDefaults.ErrorCorrectionLevel, "error-correction", "e", "H", "Error correction level (L, M, Q, H)")
+	batchCmd.Flags().StringVar(&batchDefaults.BackgroundColor, "bg-color", batchDefaults.BackgroundColor, "Background color")
+	batchCmd.Flags().StringVar(&batchDefaults.ForegroundColor, "fg-color", batchDefaults.ForegroundColor, "Module color")
+	batchCmd.Flags().Float64Var(&batchDefaults.MinContrast, "min-contrast", batchDefaults.MinContrast, "Minimum WCAG contrast ratio between modules and background (0 disables the check)")
+	batchCmd.Flags().BoolVar(&batchDefaults.AllowInverted, "allow-inverted", false, "Accept modules lighter than the background (reversed polarity)")
+	batchCmd.Flags().IntVarP(&batchDefaults.Scale, "scale", "s", batchDefaults.Scale, "Image scale")
+	batchCmd.Flags().IntVarP(&batchDefaults.QuietZone, "quiet-zone", "q", batchDefaults.QuietZone, "Quiet zone width in modules")
+}
+
+// batchRecord is one input row, mapped from CSV columns or JSON keys
+type batchRecord struct {
+	Row      int
+	Data     string
+	Filename string
+	ECLevel  string
+	FgColor  string
+	BgColor  string
+	Scale    int
+	Err      error
+}
+
+// batchName holds the fields available to the file name template
+type batchName struct {
+	Row     int
+	Slug    string
+	Data    string
+	ECLevel string
+}
+
+// batchFailure is a row that could not be generated
+type batchFailure struct {
+	Row int
+	Err error
+}
+
+func runBatch(ctx context.Context, path string) error {
+	nameTemplate, err := template.New("name").Option("missingkey=error").Parse(batchNameFormat)
+	if err != nil {
+		return fmt.Errorf("invalid file name template: %w", err)
+	}
+
+	input, err := openBatchInput(path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	format := batchInputType
+	if format == "" {
+		format = batchFormatFromPath(path)
+	}
+
+	var records []batchRecord
+	switch format {
+	case "csv":
+		records, err = readCSVRecords(input, batchDataColumn)
+	case "ndjson", "jsonl":
+		records, err = readNDJSONRecords(input, batchDataColumn)
+	default:
+		return fmt.Errorf("unknown input format %q (expected csv or ndjson)", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	sink, err := newBatchSink(batchOutputDir)
+	if err != nil {
+		return err
+	}
+
+	// Build the jobs, keeping invalid rows aside for the summary
+	var failures []batchFailure
+	var jobs []batch.Job
+	var jobRows []int
+	for _, record := range records {
+		job, err := newBatchJob(record, nameTemplate)
+		if err != nil {
+			failures = append(failures, batchFailure{Row: record.Row, Err: err})
+			continue
+		}
+		jobs = append(jobs, job)
+		jobRows = append(jobRows, record.Row)
+	}
+
+	fmt.Printf("QRFactory - Generating %d QR codes with %d workers into %s\n", len(jobs), batchWorkers, batchOutputDir)
+
+	// Silence per-code generation traces, they would drown the progress line
+	qr.SetLogOutput(io.Discard)
+	defer qr.SetLogOutput(os.Stdout)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	queue := make(chan batch.Job)
+	go func() {
+		defer close(queue)
+		for _, job := range jobs {
+			select {
+			case queue <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Results arrive in input order, so files are streamed in that order too
+	manifest := batchManifest{Generator: "QRFactory " + appVersion, Created: time.Now().UTC()}
+	emitted := 0
+	stats, runErr := batch.Run(ctx, queue, batchWorkers, func(r batch.Result) {
+		emitted = r.Index + 1
+		row := jobRows[r.Index]
+		if r.Err == nil {
+			r.Err = addBatchFile(sink, &manifest, row, r)
+		}
+		if r.Err != nil {
+			failures = append(failures, batchFailure{Row: row, Err: r.Err})
+		}
+		// Release the rendered image once it has been written
+		jobs[r.Index] = batch.Job{}
+		fmt.Fprintf(os.Stderr, "\rProgress: %d/%d", r.Index+1, len(jobs))
+	})
+	fmt.Fprintln(os.Stderr)
+
+	// Jobs never dispatched after an interruption are reported as canceled
+	for _, row := range jobRows[emitted:] {
+		failures = append(failures, batchFailure{Row: row, Err: context.Canceled})
+	}
+
+	if err := writeManifest(sink, manifest); err != nil {
+		sink.Close()
+		return fmt.Errorf("cannot write manifest: %w", err)
+	}
+	if err := sink.Close(); err != nil {
+		return fmt.Errorf("cannot finalize output: %w", err)
+	}
+
+	printBatchSummary(stats, len(records), failures)
+
+	if runErr != nil {
+		return fmt.Errorf("batch interrupted, %d of %d rows failed or canceled: %w", len(failures), len(records), runErr)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d rows failed", len(failures), len(records))
+	}
+	return nil
+}
+
+// openBatchInput opens the input file, or standard input for "-"
+func openBatchInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open input: %w", err)
+	}
+	return file, nil
+}
+
+// batchFormatFromPath guesses the input format from the file extension
+func batchFormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson", ".json":
+		return "ndjson"
+	default:
+		return "csv"
+	}
+}
+
+// readCSVRecords reads a CSV file whose first row names the columns, in any
+// case; the byte order mark of spreadsheet exports is ignored
+func readCSVRecords(r io.Reader, dataColumn string) ([]batchRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CSV header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns[strings.ToLower(dataColumn)]; !ok {
+		return nil, fmt.Errorf("CSV header has no %q column", dataColumn)
+	}
+
+	field := func(fields []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	var records []batchRecord
+	for row := 1; ; row++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		record := batchRecord{Row: row}
+		if err != nil {
+			// Keep going: a malformed line only fails its own row
+			record.Err = err
+			records = append(records, record)
+			continue
+		}
+
+		record.Data = field(fields, strings.ToLower(dataColumn))
+		record.Filename = field(fields, "filename")
+		record.ECLevel = strings.ToUpper(field(fields, "ec"))
+		record.FgColor = field(fields, "fg_color")
+		record.BgColor = field(fields, "bg_color")
+		if scale := field(fields, "scale"); scale != "" {
+			record.Scale, err = strconv.Atoi(scale)
+			if err != nil {
+				record.Err = fmt.Errorf("invalid scale %q", scale)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// readNDJSONRecords reads one JSON object per line; blank lines are skipped.
+// Keys are matched in any case, like CSV columns.
+func readNDJSONRecords(r io.Reader, dataKey string) ([]batchRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var records []batchRecord
+	row := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		row++
+
+		record := batchRecord{Row: row}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &object); err != nil {
+			record.Err = fmt.Errorf("invalid JSON: %w", err)
+			records = append(records, record)
+			continue
+		}
+		raw := make(map[string]json.RawMessage, len(object))
+		for key, value := range object {
+			raw[strings.ToLower(key)] = value
+		}
+
+		decode := func(key string, v any) {
+			if value, ok := raw[key]; ok && record.Err == nil {
+				if err := json.Unmarshal(value, v); err != nil {
+					record.Err = fmt.Errorf("invalid %q value %s", key, value)
+				}
+			}
+		}
+		decode(strings.ToLower(dataKey), &record.Data)
+		decode("filename", &record.Filename)
+		decode("ec", &record.ECLevel)
+		decode("fg_color", &record.FgColor)
+		decode("bg_color", &record.BgColor)
+		decode("scale", &record.Scale)
+		record.ECLevel = strings.ToUpper(record.ECLevel)
+
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read JSON Lines input: %w", err)
+	}
+
+	return records, nil
+}
+
+// newBatchJob applies the command defaults to a record and resolves its output path
+func newBatchJob(record batchRecord, nameTemplate *template.Template) (batch.Job, error) {
+	if record.Err != nil {
+		return batch.Job{}, record.Err
+	}
+
+	cfg := *batchDefaults
+	cfg.Data = record.Data
+	if record.ECLevel != "" {
+		cfg.ErrorCorrectionLevel = record.ECLevel
+	}
+	if record.FgColor != "" {
+		cfg.ForegroundColor = record.FgColor
+	}
+	if record.BgColor != "" {
+		cfg.BackgroundColor = record.BgColor
+	}
+	if record.Scale != 0 {
+		cfg.Scale = record.Scale
+	}
+	if err := config.ValidateConfig(&cfg); err != nil {
+		return batch.Job{}, err
+	}
+
+	name := record.Filename
+	if name == "" {
+		var b strings.Builder
+		err := nameTemplate.Execute(&b, batchName{
+			Row:     record.Row,
+			Slug:    slugify(record.Data),
+			Data:    record.Data,
+			ECLevel: cfg.ErrorCorrectionLevel,
+		})
+		if err != nil {
+			return batch.Job{}, fmt.Errorf("file name template: %w", err)
+		}
+		name = b.String()
+	}
+	if !filepath.IsLocal(name) {
+		return batch.Job{}, fmt.Errorf("file name %q must stay inside the output directory", name)
+	}
+	if name == manifestName {
+		return batch.Job{}, fmt.Errorf("file name %q is reserved for the manifest", name)
+	}
+	// The output file is the name inside the delivery; the image is rendered in memory
+	cfg.OutputFile = filepath.ToSlash(name)
+
+	return batch.Job{Config: cfg, Writer: &bytes.Buffer{}}, nil
+}
+
+// addBatchFile writes a generated image to the sink and records it in the manifest
+func addBatchFile(sink batchSink, manifest *batchManifest, row int, r batch.Result) error {
+	data := r.Job.Writer.(*bytes.Buffer).Bytes()
+	name := r.Job.Config.OutputFile
+	if err := sink.Add(name, data, manifest.Created); err != nil {
+		return err
+	}
+
+	manifest.Files = append(manifest.Files, newManifestEntry(name, row, r.Job.Config.Data,
+		r.Symbol.Version, r.Symbol.ErrorCorrectionLevel, r.Symbol.MaskPattern, data))
+	return nil
+}
+
+// slugify turns data into a short, file-system friendly name; accented
+// letters lose their accents
+func slugify(data string) string {
+	const maxLength = 40
+
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFD.String(strings.ToLower(data)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= maxLength {
+			break
+		}
+	}
+
+	slug := strings.Trim(b.String(), "-")
+	if slug == "" {
+		return "qr"
+	}
+	return slug
+}
+
+// printBatchSummary prints the totals and lists the failed rows in input order
+func printBatchSummary(stats batch.Stats, rows int, failures []batchFailure) {
+	fmt.Printf("Generated %d of %d QR codes in %v (%.1f codes/s)\n",
+		stats.Succeeded, rows, stats.Elapsed.Round(time.Millisecond), stats.Throughput())
+
+	if len(failures) == 0 {
+		return
+	}
+
+	// Row-level input errors were collected before generation errors
+	slices.SortStableFunc(failures, func(a, b batchFailure) int {
+		return cmp.Compare(a.Row, b.Row)
+	})
+	fmt.Printf("%d rows failed:\n", len(failures))
+	for _, failure := range failures {
+		if errors.Is(failure.Err, context.Canceled) {
+			fmt.Printf("  row %d: canceled\n", failure.Row)
+			continue
+		}
+		fmt.Printf("  row %d: %v\n", failure.Row, failure.Err)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestReadCSVRecords(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		dataColumn string
+		want       []batchRecord
+		wantErr    bool
+		wantRowErr []bool
+	}{
+		{
+			name:       "Data column only",
+			input:      "data\nHELLO\n\"a, b\"\n",
+			dataColumn: "data",
+			want:       []batchRecord{{Row: 1, Data: "HELLO"}, {Row: 2, Data: "a, b"}},
+		},
+		{
+			name: "Optional columns",
+			input: "Data,filename,ec,fg_color,bg_color,scale\n" +
+				"HELLO, hello.png, q, navy, #fff, 4\n" +
+				"WORLD,,,,,\n",
+			dataColumn: "data",
+			want: []batchRecord{
+				{Row: 1, Data: "HELLO", Filename: "hello.png", ECLevel: "Q", FgColor: "navy", BgColor: "#fff", Scale: 4},
+				{Row: 2, Data: "WORLD"},
+			},
+		},
+		{
+			name:       "Custom data column",
+			input:      "id,url\n1,https://example.com\n",
+			dataColumn: "URL",
+			want:       []batchRecord{{Row: 1, Data: "https://example.com"}},
+		},
+		{
+			name:       "Spreadsheet export with byte order mark",
+			input:      "\ufeffData,filename\r\nHELLO,hello.png\r\n",
+			dataColumn: "data",
+			want:       []batchRecord{{Row: 1, Data: "HELLO", Filename: "hello.png"}},
+		},
+		{
+			name:       "Bad rows keep the following ones",
+			input:      "data,scale\nA,x\n\"B\n",
+			dataColumn: "data",
+			want:       []batchRecord{{Row: 1, Data: "A"}, {Row: 2}},
+			wantRowErr: []bool{true, true},
+		},
+		{
+			name:       "Missing data column",
+			input:      "url\nHELLO\n",
+			dataColumn: "data",
+			wantErr:    true,
+		},
+		{
+			name:       "Empty input",
+			input:      "",
+			dataColumn: "data",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readCSVRecords(strings.NewReader(tt.input), tt.dataColumn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readCSVRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			checkRecords(t, got, tt.want, tt.wantRowErr)
+		})
+	}
+}
+
+func TestReadNDJSONRecords(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		dataKey    string
+		want       []batchRecord
+		wantRowErr []bool
+	}{
+		{
+			name: "All keys",
+			input: `{"data": "HELLO", "filename": "a/hello.svg", "ec": "l", "fg_color": "red", "bg_color": "white", "scale": 3}` + "\n" +
+				"\n" +
+				`{"data": "WORLD"}` + "\n",
+			dataKey: "data",
+			want: []batchRecord{
+				{Row: 1, Data: "HELLO", Filename: "a/hello.svg", ECLevel: "L", FgColor: "red", BgColor: "white", Scale: 3},
+				{Row: 2, Data: "WORLD"},
+			},
+		},
+		{
+			name:    "Custom data key",
+			input:   `{"url": "https://example.com", "data": "ignored"}` + "\n",
+			dataKey: "url",
+			want:    []batchRecord{{Row: 1, Data: "https://example.com"}},
+		},
+		{
+			name:    "Keys in any case",
+			input:   `{"URL": "https://example.com", "FileName": "a.png", "EC": "q"}` + "\n",
+			dataKey: "Url",
+			want:    []batchRecord{{Row: 1, Data: "https://example.com", Filename: "a.png", ECLevel: "Q"}},
+		},
+		{
+			name:       "Bad rows keep the following ones",
+			input:      "{not json}\n" + `{"data": "A", "scale": "big"}` + "\n" + `{"data": "B"}` + "\n",
+			dataKey:    "data",
+			want:       []batchRecord{{Row: 1}, {Row: 2, Data: "A"}, {Row: 3, Data: "B"}},
+			wantRowErr: []bool{true, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readNDJSONRecords(strings.NewReader(tt.input), tt.dataKey)
+			if err != nil {
+				t.Fatalf("readNDJSONRecords() error = %v", err)
+			}
+			checkRecords(t, got, tt.want, tt.wantRowErr)
+		})
+	}
+}
+
+// checkRecords compares records without their errors, then checks which rows failed
+func checkRecords(t *testing.T, got, want []batchRecord, wantRowErr []bool) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range got {
+		failed := got[i].Err != nil
+		if wantFailed := wantRowErr != nil && wantRowErr[i]; failed != wantFailed {
+			t.Errorf("row %d error = %v, want error %v", got[i].Row, got[i].Err, wantFailed)
+		}
+		record := got[i]
+		record.Err = nil
+		if record != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, record, want[i])
+		}
+	}
+}
+
+func TestNewBatchJob(t *testing.T) {
+	nameTemplate := template.Must(template.New("name").Option("missingkey=error").Parse("{{.Row}}-{{.Slug}}.png"))
+
+	tests := []struct {
+		name     string
+		record   batchRecord
+		wantFile string
+		wantEC   string
+		wantErr  bool
+	}{
+		{"Default template", batchRecord{Row: 3, Data: "https://example.com/Menu"}, "3-https-example-com-menu.png", "H", false},
+		{"Explicit filename and level", batchRecord{Row: 1, Data: "A", Filename: "codes/a.svg", ECLevel: "L"}, "codes/a.svg", "L", false},
+		{"Accented data", batchRecord{Row: 2, Data: "Café crème"}, "2-cafe-creme.png", "H", false},
+		{"Row error", batchRecord{Row: 1, Data: "A", Err: os.ErrInvalid}, "", "", true},
+		{"Empty data", batchRecord{Row: 1}, "", "", true},
+		{"Invalid level", batchRecord{Row: 1, Data: "A", ECLevel: "X"}, "", "", true},
+		{"Invalid color", batchRecord{Row: 1, Data: "A", FgColor: "nope"}, "", "", true},
+		{"Absolute filename", batchRecord{Row: 1, Data: "A", Filename: "/tmp/a.png"}, "", "", true},
+		{"Traversing filename", batchRecord{Row: 1, Data: "A", Filename: "../a.png"}, "", "", true},
+		{"Nested traversal", batchRecord{Row: 1, Data: "A", Filename: "codes/../../a.png"}, "", "", true},
+		{"Manifest name", batchRecord{Row: 1, Data: "A", Filename: manifestName}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := newBatchJob(tt.record, nameTemplate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newBatchJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if job.Config.OutputFile != tt.wantFile {
+				t.Errorf("file = %q, want %q", job.Config.OutputFile, tt.wantFile)
+			}
+			if job.Config.ErrorCorrectionLevel != tt.wantEC {
+				t.Errorf("level = %q, want %q", job.Config.ErrorCorrectionLevel, tt.wantEC)
+			}
+			if job.Writer == nil {
+				t.Error("the job should render into memory")
+			}
+		})
+	}
+
+	traversing := template.Must(template.New("name").Parse("../{{.Slug}}.png"))
+	if _, err := newBatchJob(batchRecord{Row: 1, Data: "A"}, traversing); err == nil {
+		t.Error("a template leaving the output directory should be rejected")
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"HELLO WORLD", "hello-world"},
+		{"https://example.com/a?b=1", "https-example-com-a-b-1"},
+		{"Crème brûlée à Noël", "creme-brulee-a-noel"},
+		{"  --déjà--  ", "deja"},
+		{"", "qr"},
+		{"日本語", "qr"},
+		{strings.Repeat("ab ", 30), strings.TrimSuffix(strings.Repeat("ab-", 13)+"a", "-")},
+	}
+
+	for _, tt := range tests {
+		if got := slugify(tt.data); got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestRunBatchReportsBadRows(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "codes.csv")
+	if err := os.WriteFile(input, []byte("data,scale\nHELLO,2\n,2\nWORLD,2\nBAD,x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "out")
+	defer func(o, n, c string, w int) {
+		batchOutputDir, batchNameFormat, batchDataColumn, batchWorkers = o, n, c, w
+	}(batchOutputDir, batchNameFormat, batchDataColumn, batchWorkers)
+	batchOutputDir, batchNameFormat, batchDataColumn, batchWorkers = output, "{{.Row}}-{{.Slug}}.png", "data", 2
+
+	err := runBatch(context.Background(), input)
+	if err == nil || !strings.Contains(err.Error(), "2 of 4 rows failed") {
+		t.Fatalf("runBatch() error = %v, want 2 failed rows", err)
+	}
+	for _, name := range []string{"1-hello.png", "3-world.png", manifestName} {
+		if _, err := os.Stat(filepath.Join(output, name)); err != nil {
+			t.Errorf("missing output %s: %v", name, err)
+		}
+	}
+}
+
+func TestRunBatchReportsCanceledRows(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "codes.csv")
+	if err := os.WriteFile(input, []byte("data,scale\nHELLO,2\n,2\nWORLD,2\nBAD,x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(o, n, c string, w int) {
+		batchOutputDir, batchNameFormat, batchDataColumn, batchWorkers = o, n, c, w
+	}(batchOutputDir, batchNameFormat, batchDataColumn, batchWorkers)
+	batchOutputDir, batchNameFormat, batchDataColumn, batchWorkers = filepath.Join(dir, "out"), "{{.Row}}-{{.Slug}}.png", "data", 2
+
+	// Interrupted before any dispatch: the valid rows count as canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := runBatch(ctx, input)
+	if err == nil || !strings.Contains(err.Error(), "4 of 4 rows failed or canceled") {
+		t.Fatalf("runBatch() error = %v, want all 4 rows reported", err)
+	}
+}
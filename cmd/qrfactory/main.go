@@ -2,19 +2,81 @@ package main
 
 import (
 	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"math"
 	"os"
 	"qrfactory/internal/model"
 	"qrfactory/pkg/config"
 	"qrfactory/pkg/qr"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 )
 
+// appVersion is the QRFactory release number
+const appVersion = "1.0.0"
+
+// terminalScale is the default module size in pixels for sixel and kitty output
+const terminalScale = 8
+
 var (
-	cfg       *config.QRConfig
-	scale     int
-	quietZone int
+	cfg         *config.QRConfig
+	scale       int
+	quietZone   int
+	format      string
+	title       string
+	description string
+	outline     bool
+	sizeMM      float64
+	moduleMM    float64
+	cmyk        string
+	spotColor   string
+	darkTheme   bool
+	asciiOutput bool
+	compression string
+	sizePx      int
+	dpi         float64
+	jpegQuality int
+
+	moduleShape   string
+	functionShape string
+	moduleRadius  float64
+
+	eyeFrameShape string
+	eyePupilShape string
+
+	gradientType  string
+	gradientAngle float64
+
+	logoPath    string
+	logoSize    float64
+	logoPadding float64
+	logoPlate   bool
+	logoShape   string
+
+	halftonePath string
+
+	frameTemplate   string
+	captionText     string
+	captionPosition string
+	captionSize     float64
+	fontPath        string
+	frameColor      string
+	captionColor    string
+	frameBorder     float64
+	frameRadius     float64
+	framePadding    float64
+
+	sizes string
+
+	inverted bool
 )
 
 var rootCmd = &cobra.Command{
@@ -24,73 +86,131 @@ var rootCmd = &cobra.Command{
 It supports various data types, error correction levels, and customization options.
 See https://github.com/le-veilleur for more information.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("QRFactory - Starting...")
+		// Progress goes to stderr when the image itself is written to stdout
+		outputFormat, formatErr := resolveFormat()
+		progress := os.Stdout
+		if cfg.OutputFile == "-" || isTerminalFormat(outputFormat) {
+			progress = os.Stderr
+			qr.SetLogOutput(os.Stderr)
+		}
+
+		fmt.Fprintln(progress, "QRFactory - Starting...")
 		start := time.Now()
 
 		// Validate configuration
-		fmt.Println("Validating configuration...")
+		fmt.Fprintln(progress, "Validating configuration...")
 		if err := config.ValidateConfig(cfg); err != nil {
-			fmt.Printf("Configuration error: %v\n", err)
+			fmt.Fprintf(progress, "Configuration error: %v\n", err)
+			printContrastHint(progress, err)
+			os.Exit(1)
+		}
+		if report, _ := config.CheckContrast(cfg); report.Low {
+			fmt.Fprintf(os.Stderr, "Warning: low contrast between modules and background (%.2f:1, recommended %.2g:1 or more)\n",
+				report.Ratio, cfg.WarnContrast)
+		}
+		if formatErr != nil {
+			fmt.Fprintf(progress, "Configuration error: %v\n", formatErr)
 			os.Exit(1)
 		}
+		opts, err := renderOptions()
+		if err != nil {
+			fmt.Fprintf(progress, "Configuration error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := checkLayers(opts, outputFormat); err != nil {
+			fmt.Fprintf(progress, "Configuration error: %v\n", err)
+			os.Exit(1)
+		}
+		if isTerminalFormat(outputFormat) && !cmd.Flags().Changed("scale") {
+			opts.Scale = terminalScale
+		}
+		// Half-blocks follow the terminal theme unless colors were asked for
+		opts.TerminalColor = cmd.Flags().Changed("fg-color") || cmd.Flags().Changed("bg-color")
 
 		// Initialize Galois Field
-		fmt.Println("Initializing Galois Field...")
+		fmt.Fprintln(progress, "Initializing Galois Field...")
 		qr.InitGaloisField()
-		fmt.Printf("Initialization completed in %v\n", time.Since(start))
+		fmt.Fprintf(progress, "Initialization completed in %v\n", time.Since(start))
 
 		// Create QRCode model
-		fmt.Println("Creating QRCode model...")
+		fmt.Fprintln(progress, "Creating QRCode model...")
 		qrCode := model.NewQRCode(cfg.Data, cfg.Version, cfg.ErrorCorrectionLevel)
 
 		// Detect data type
-		fmt.Println("Detecting data type...")
+		fmt.Fprintln(progress, "Detecting data type...")
 		dataType := qr.DetectDataType(cfg.Data)
-		fmt.Printf("Detected data type: %s\n", dataType)
+		fmt.Fprintf(progress, "Detected data type: %s\n", dataType)
 
 		// Calculate minimum required version
-		fmt.Println("Calculating minimum required version...")
+		fmt.Fprintln(progress, "Calculating minimum required version...")
 		minVersion, err := qr.CalculateMinVersionForDataType(cfg.Data, dataType)
 		if err != nil {
-			fmt.Printf("Error calculating version: %v\n", err)
+			fmt.Fprintf(progress, "Error calculating version: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Printf("Calculated minimum version: %d\n", minVersion)
+		fmt.Fprintf(progress, "Calculated minimum version: %d\n", minVersion)
 
 		// Use minimum version if necessary
 		if cfg.Version < minVersion {
-			fmt.Printf("Version %d is too small for the data. Using version %d.\n", cfg.Version, minVersion)
+			fmt.Fprintf(progress, "Version %d is too small for the data. Using version %d.\n", cfg.Version, minVersion)
 			cfg.Version = minVersion
 			qrCode.Version = minVersion
 			qrCode.Size = minVersion*4 + 17
 		}
 
 		// Generate QR code matrix
-		fmt.Println("Generating QR matrix...")
+		fmt.Fprintln(progress, "Generating QR matrix...")
 		genStart := time.Now()
-		matrix := qr.GenerateQRMatrix(cfg.Version, cfg.Data, cfg.ErrorCorrectionLevel)
-		fmt.Printf("Matrix generation completed in %v\n", time.Since(genStart))
+		var matrix *image.RGBA
+		if opts.Logo != nil {
+			// The logo hides modules: raise the error correction level until they can be restored
+			symbol, err := qr.GenerateSymbolWithLogo(cfg.Version, cfg.Data, cfg.ErrorCorrectionLevel, opts.Logo)
+			if err != nil {
+				fmt.Fprintf(progress, "Error generating QR code: %v\n", err)
+				fmt.Fprintln(progress, "Try a smaller --logo-size or --logo-padding, or a higher --version")
+				os.Exit(1)
+			}
+			if symbol.ErrorCorrectionLevel != cfg.ErrorCorrectionLevel {
+				fmt.Fprintf(progress, "Error correction raised from %s to %s to keep the logo readable\n",
+					cfg.ErrorCorrectionLevel, symbol.ErrorCorrectionLevel)
+				qrCode.ErrorCorrectionLevel = symbol.ErrorCorrectionLevel
+			}
+			matrix = symbol.Matrix
+		} else {
+			matrix = qr.GenerateQRMatrix(cfg.Version, cfg.Data, cfg.ErrorCorrectionLevel)
+		}
+		fmt.Fprintf(progress, "Matrix generation completed in %v\n", time.Since(genStart))
 
 		if matrix == nil {
-			fmt.Println("Error generating QR code")
+			fmt.Fprintln(progress, "Error generating QR code")
 			os.Exit(1)
 		}
 
 		// Associate matrix with the model
-		fmt.Println("Associating matrix with the model...")
+		fmt.Fprintln(progress, "Associating matrix with the model...")
 		qrCode.SetMatrix(matrix)
 
 		// Save image
-		fmt.Println("Saving image...")
+		fmt.Fprintln(progress, "Saving image...")
 		saveStart := time.Now()
-		if err := qr.SaveQRImageWithQuietZone(matrix, cfg.OutputFile, scale, quietZone); err != nil {
-			fmt.Printf("Error saving image: %v\n", err)
+		outputs := []string{cfg.OutputFile}
+		if sizes != "" {
+			outputs, err = saveSizes(matrix, outputFormat, opts)
+		} else {
+			err = saveImage(matrix, outputFormat, opts)
+		}
+		if err != nil {
+			fmt.Fprintf(progress, "Error saving image: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Printf("Save completed in %v\n", time.Since(saveStart))
+		fmt.Fprintf(progress, "Save completed in %v\n", time.Since(saveStart))
 
-		fmt.Printf("QR code successfully generated: %s\n", cfg.OutputFile)
-		fmt.Printf("Total execution time: %v\n", time.Since(start))
+		if isTerminalFormat(outputFormat) || cfg.OutputFile == "-" {
+			fmt.Fprintln(progress, "QR code successfully generated")
+		} else {
+			fmt.Fprintf(progress, "QR code successfully generated: %s\n", strings.Join(outputs, ", "))
+		}
+		fmt.Fprintf(progress, "Total execution time: %v\n", time.Since(start))
 	},
 }
 
@@ -99,7 +219,7 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("QRFactory 1.0.0")
+		fmt.Println("QRFactory " + appVersion)
 	},
 }
 
@@ -114,16 +234,383 @@ func init() {
 	rootCmd.Flags().StringVarP(&cfg.Data, "data", "d", "", "Data to encode in the QR code")
 	rootCmd.Flags().IntVarP(&cfg.Version, "version", "v", cfg.Version, "QR code version (1-40)")
 	rootCmd.Flags().StringVarP(&cfg.ErrorCorrectionLevel, "error-correction", "e", "H", "Error correction level (L, M, Q, H)")
-	rootCmd.Flags().StringVarP(&cfg.OutputFile, "output", "o", "qrcode.png", "Output file path")
-	rootCmd.Flags().StringVar(&cfg.BackgroundColor, "bg-color", cfg.BackgroundColor, "Background color")
-	rootCmd.Flags().StringVar(&cfg.ForegroundColor, "fg-color", cfg.ForegroundColor, "Module color")
+	rootCmd.Flags().StringVarP(&cfg.OutputFile, "output", "o", "qrcode.png", "Output file path, or - for standard output")
+	rootCmd.Flags().StringVar(&cfg.BackgroundColor, "bg-color", cfg.BackgroundColor, "Background and quiet zone color: CSS name, #rgb, #rrggbb, #rrggbbaa, rgb(), rgba() or transparent")
+	rootCmd.Flags().StringVar(&cfg.ForegroundColor, "fg-color", cfg.ForegroundColor, "Module color, in the same formats as --bg-color")
+	rootCmd.Flags().Float64Var(&cfg.MinContrast, "min-contrast", cfg.MinContrast, "Minimum WCAG contrast ratio between modules and background (0 disables the check)")
+	rootCmd.Flags().Float64Var(&cfg.WarnContrast, "warn-contrast", cfg.WarnContrast, "Contrast ratio below which a warning is printed (0 disables the warning)")
+	rootCmd.Flags().BoolVar(&cfg.AllowInverted, "allow-inverted", false, "Accept modules lighter than the background (reversed polarity)")
+	rootCmd.Flags().BoolVar(&inverted, "inverted", false, "Reflectance-reversed symbol for dark backgrounds: modules in --bg-color, quiet zone and light modules in --fg-color")
 	rootCmd.Flags().IntVarP(&scale, "scale", "s", 30, "Image scale (default: 30)")
 	rootCmd.Flags().IntVarP(&quietZone, "quiet-zone", "q", 4, "Quiet zone width in modules (default: 4)")
+	rootCmd.Flags().StringVarP(&format, "format", "f", "", "Output format: png, jpeg, gif, bmp, tiff, svg, pdf, eps, terminal, sixel or kitty (default: from the output file extension)")
+	rootCmd.Flags().StringVar(&title, "title", "", "Title embedded in SVG, PDF and EPS output")
+	rootCmd.Flags().StringVar(&description, "description", "", "Description embedded in SVG and PDF output")
+	rootCmd.Flags().BoolVar(&outline, "outline", false, "Trace module outlines instead of horizontal runs in vector output")
+	rootCmd.Flags().Float64Var(&sizeMM, "size-mm", 0, "Printed size in millimeters, quiet zone included (images at --dpi, PDF, EPS, SVG)")
+	rootCmd.Flags().Float64Var(&moduleMM, "module-mm", 0, "Printed module size in millimeters when --size-mm is not set (images at --dpi, PDF, EPS)")
+	rootCmd.Flags().IntVar(&sizePx, "size-px", 0, "Image size in pixels, quiet zone included; the symbol is centered with the largest whole module size")
+	rootCmd.Flags().Float64Var(&dpi, "dpi", 0, "Resolution used to convert millimeters to pixels and recorded in the image (default: 300 with --size-mm)")
+	rootCmd.Flags().StringVar(&cmyk, "cmyk", "", "Module color as C,M,Y,K percentages for PDF and EPS (default: --fg-color converted to CMYK)")
+	rootCmd.Flags().StringVar(&spotColor, "spot-color", "", "Spot color name for the modules in PDF and EPS, with --cmyk as its alternate")
+	rootCmd.Flags().BoolVar(&darkTheme, "dark-theme", false, "Draw light modules instead of dark ones in terminal output, for light-on-dark terminals")
+	rootCmd.Flags().BoolVar(&asciiOutput, "ascii", false, "Use plain ASCII (##) instead of block characters in terminal output")
+	rootCmd.Flags().IntVar(&jpegQuality, "jpeg-quality", qr.DefaultJPEGQuality, "JPEG quality from 1 to 100")
+	rootCmd.Flags().StringVar(&moduleShape, "module-shape", qr.ShapeSquare, "Data module shape: "+strings.Join(qr.Shapes(), ", ")+" (images, SVG)")
+	rootCmd.Flags().StringVar(&functionShape, "function-shape", qr.ShapeSquare, "Shape of finder, timing and alignment modules, from the same list (images, SVG)")
+	rootCmd.Flags().Float64Var(&moduleRadius, "module-radius", qr.DefaultModuleRadius, "Corner radius of rounded modules, as a fraction of the module from 0 to 0.5")
+	rootCmd.Flags().StringVar(&eyeFrameShape, "eye-frame-shape", "", "Finder pattern frame shape: "+strings.Join(qr.EyeShapes(), ", ")+" (images, SVG)")
+	rootCmd.Flags().StringVar(&eyePupilShape, "eye-pupil-shape", "", "Finder pattern pupil shape, from the same list (images, SVG)")
+	rootCmd.Flags().StringVar(&cfg.EyeFrameColor, "eye-frame-color", "", "Finder pattern frame color (default: --fg-color)")
+	rootCmd.Flags().StringVar(&cfg.EyePupilColor, "eye-pupil-color", "", "Finder pattern pupil color (default: --fg-color)")
+	rootCmd.Flags().StringArrayVar(&cfg.EyeColors, "eye-color", nil, "Color of each finder pattern, repeated in order: top left, top right, bottom left (images, SVG)")
+	rootCmd.Flags().StringArrayVar(&cfg.GradientColors, "gradient-color", nil, "Gradient color replacing --fg-color, repeated for each stop from start to end (images, SVG)")
+	rootCmd.Flags().StringVar(&gradientType, "gradient-type", qr.GradientLinear, "Gradient type: "+strings.Join(qr.GradientTypes(), ", "))
+	rootCmd.Flags().Float64Var(&gradientAngle, "gradient-angle", 0, "Linear gradient direction in degrees, clockwise: 0 runs left to right, 90 top to bottom")
+	rootCmd.Flags().StringVar(&logoPath, "logo", "", "PNG, JPEG or GIF image placed at the center of the symbol (images, SVG)")
+	rootCmd.Flags().Float64Var(&logoSize, "logo-size", qr.DefaultLogoSize, "Logo size as a fraction of the symbol width, quiet zone excluded")
+	rootCmd.Flags().Float64Var(&logoPadding, "logo-padding", 1, "Margin around the logo in modules")
+	rootCmd.Flags().BoolVar(&logoPlate, "logo-plate", true, "Clear the modules under the logo and its margin")
+	rootCmd.Flags().StringVar(&logoShape, "logo-shape", qr.LogoSquare, "Shape of the logo plate and clipping: "+strings.Join(qr.LogoShapes(), ", "))
+	rootCmd.Flags().StringVar(&halftonePath, "halftone", "", "PNG, JPEG or GIF photo dithered into the modules, 3×3 dots per module (images, SVG)")
+	rootCmd.Flags().StringVar(&frameTemplate, "frame", "", "Frame drawn around the symbol: "+strings.Join(qr.FrameTemplates(), ", ")+" (images, SVG)")
+	rootCmd.Flags().StringVar(&captionText, "caption", "", "Caption written above or below the symbol, such as the URL (images, SVG)")
+	rootCmd.Flags().StringVar(&captionPosition, "caption-position", qr.CaptionBottom, "Caption position: "+qr.CaptionBottom+" or "+qr.CaptionTop)
+	rootCmd.Flags().Float64Var(&captionSize, "caption-size", qr.DefaultCaptionSize, "Caption font size in modules, reduced when the text is wider than the frame")
+	rootCmd.Flags().StringVar(&fontPath, "font", "", "TrueType or OpenType font for the caption (default Go Regular, Go Bold for the banner)")
+	rootCmd.Flags().StringVar(&frameColor, "frame-color", "", "Frame border and banner color (default --fg-color)")
+	rootCmd.Flags().StringVar(&captionColor, "caption-color", "", "Caption color (default --frame-color; the banner caption is cut out of the band)")
+	rootCmd.Flags().Float64Var(&frameBorder, "frame-border", 0, "Frame border width in modules (0 uses the template default)")
+	rootCmd.Flags().Float64Var(&frameRadius, "frame-radius", 0, "Frame corner radius in modules (0 uses the template default)")
+	rootCmd.Flags().Float64Var(&framePadding, "frame-padding", 0, "Margin between the quiet zone and the frame border in modules")
+	rootCmd.Flags().StringVar(&sizes, "sizes", "", "Render several image sizes in one run: comma-separated pixel sides (128,256,512) or a preset ("+strings.Join(qr.SizePresets(), ", ")+"), named with suffixes such as @2x")
+	rootCmd.Flags().StringVar(&compression, "png-compression", "default", "PNG compression level: default, none, fast or best")
 
 	// Mark required flags
 	rootCmd.MarkFlagRequired("data")
 }
 
+// resolveFormat returns the --format value, or the one matching the output
+// extension. An explicit --format must agree with a known output extension.
+func resolveFormat() (string, error) {
+	if format == "" {
+		return qr.FormatFromFilename(cfg.OutputFile), nil
+	}
+	f := strings.ToLower(format)
+	if _, err := qr.NewRenderer(f); err != nil {
+		return "", fmt.Errorf("unknown output format %q (expected %s)", format, strings.Join(qr.Formats(), ", "))
+	}
+	// Terminal formats and standard output ignore the file name
+	if cfg.OutputFile == "-" || isTerminalFormat(f) {
+		return f, nil
+	}
+	if implied, ok := qr.ExtensionFormat(cfg.OutputFile); ok && implied != f {
+		return "", fmt.Errorf("--format %s conflicts with %s, whose extension implies %s: change one or drop --format",
+			f, cfg.OutputFile, implied)
+	}
+	return f, nil
+}
+
+// renderOptions builds the vector rendering options from the command flags
+func renderOptions() (qr.RenderOptions, error) {
+	opts := qr.NewDefaultRenderOptions()
+	opts.Scale = scale
+	opts.QuietZone = quietZone
+	opts.Title = title
+	opts.Description = description
+	opts.Outline = outline
+	opts.SizeMM = sizeMM
+	opts.ModuleMM = moduleMM
+	opts.SizePx = sizePx
+	opts.DPI = dpi
+	opts.JPEGQuality = jpegQuality
+	opts.ModuleShape = moduleShape
+	opts.FunctionShape = functionShape
+	opts.ModuleRadius = moduleRadius
+	opts.EyeFrameShape = eyeFrameShape
+	opts.EyePupilShape = eyePupilShape
+	// The eye colors were validated, and their contrast checked, with the configuration
+	for _, eye := range []struct {
+		value  string
+		target *color.Color
+	}{
+		{cfg.EyeFrameColor, &opts.EyeFrameColor},
+		{cfg.EyePupilColor, &opts.EyePupilColor},
+	} {
+		if eye.value != "" {
+			*eye.target, _ = config.ParseColor(eye.value)
+		}
+	}
+	for _, value := range cfg.EyeColors {
+		c, _ := config.ParseColor(value)
+		opts.EyeColors = append(opts.EyeColors, c)
+	}
+	if logoPath != "" {
+		logo, err := loadImage(logoPath, "logo")
+		if err != nil {
+			return opts, err
+		}
+		opts.Logo = &qr.Logo{Image: logo, Size: logoSize, Padding: logoPadding, Plate: logoPlate, Shape: logoShape}
+	}
+	if halftonePath != "" {
+		if logoPath != "" {
+			return opts, fmt.Errorf("--halftone cannot be combined with --logo")
+		}
+		photo, err := loadImage(halftonePath, "halftone image")
+		if err != nil {
+			return opts, err
+		}
+		opts.Halftone = &qr.Halftone{Image: photo}
+	}
+	if frameTemplate != "" || captionText != "" {
+		frame, err := loadFrame()
+		if err != nil {
+			return opts, err
+		}
+		opts.Frame = frame
+	}
+	opts.Inverted = inverted
+	opts.SpotColor = spotColor
+	opts.TerminalInverted = darkTheme
+	opts.TerminalASCII = asciiOutput
+
+	if sizePx > 0 && sizeMM > 0 {
+		return opts, fmt.Errorf("--size-px and --size-mm cannot be used together")
+	}
+	if sizes != "" {
+		if sizeMM > 0 || moduleMM > 0 {
+			return opts, fmt.Errorf("--sizes cannot be combined with --size-mm or --module-mm")
+		}
+		if cfg.OutputFile == "-" {
+			return opts, fmt.Errorf("--sizes writes several files and cannot be used with --output -")
+		}
+	}
+
+	level, err := parseCompression(compression)
+	if err != nil {
+		return opts, err
+	}
+	opts.PNGCompression = level
+
+	fg, bg, err := cfg.Colors()
+	if err != nil {
+		return opts, err
+	}
+	opts.Foreground = fg
+	opts.Background = bg
+
+	stops, err := cfg.Gradient()
+	if err != nil {
+		return opts, err
+	}
+	if stops != nil {
+		opts.Gradient = &qr.Gradient{Type: gradientType, Angle: gradientAngle}
+		for _, c := range stops {
+			opts.Gradient.Colors = append(opts.Gradient.Colors, c)
+		}
+	}
+
+	if cmyk != "" {
+		c, err := parseCMYK(cmyk)
+		if err != nil {
+			return opts, err
+		}
+		opts.Foreground = c
+	}
+
+	return opts, nil
+}
+
+// loadImage decodes the --logo or --halftone image
+func loadImage(path, what string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s: %v", what, err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode %s %s: %v", what, path, err)
+	}
+	return img, nil
+}
+
+// loadFrame builds the --frame and --caption layer, reading the --font file
+func loadFrame() (*qr.Frame, error) {
+	frame := &qr.Frame{
+		Template: frameTemplate,
+		Text:     captionText,
+		Position: captionPosition,
+		TextSize: captionSize,
+		Border:   frameBorder,
+		Radius:   frameRadius,
+		Padding:  framePadding,
+	}
+	// Checked now rather than when the image is saved, after encoding
+	if err := frame.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid --frame or --caption options: %v", err)
+	}
+	for _, c := range []struct {
+		flag, value string
+		target      *color.Color
+	}{
+		{"--frame-color", frameColor, &frame.Color},
+		{"--caption-color", captionColor, &frame.TextColor},
+	} {
+		if c.value == "" {
+			continue
+		}
+		parsed, err := config.ParseColor(c.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", c.flag, err)
+		}
+		*c.target = parsed
+	}
+	if fontPath != "" {
+		data, err := os.ReadFile(fontPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read font: %v", err)
+		}
+		frame.Font = data
+	}
+	return frame, nil
+}
+
+// parseCompression maps a --png-compression name to its encoder level
+func parseCompression(s string) (png.CompressionLevel, error) {
+	switch strings.ToLower(s) {
+	case "", "default":
+		return png.DefaultCompression, nil
+	case "none":
+		return png.NoCompression, nil
+	case "fast":
+		return png.BestSpeed, nil
+	case "best":
+		return png.BestCompression, nil
+	default:
+		return png.DefaultCompression, fmt.Errorf("invalid PNG compression %q (expected default, none, fast or best)", s)
+	}
+}
+
+// parseCMYK reads a "C,M,Y,K" list of percentages
+func parseCMYK(s string) (color.CMYK, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return color.CMYK{}, fmt.Errorf("invalid CMYK color %q (expected C,M,Y,K percentages)", s)
+	}
+
+	var values [4]uint8
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || v < 0 || v > 100 {
+			return color.CMYK{}, fmt.Errorf("invalid CMYK color %q (expected C,M,Y,K percentages)", s)
+		}
+		values[i] = uint8(math.Round(v * 255 / 100))
+	}
+
+	return color.CMYK{C: values[0], M: values[1], Y: values[2], K: values[3]}, nil
+}
+
+// saveImage renders the matrix in the given format to the output file, or to
+// standard output for "-" and the terminal formats
+func saveImage(matrix *image.RGBA, outputFormat string, opts qr.RenderOptions) error {
+	if isTerminalFormat(outputFormat) && !qr.SupportsTerminalFormat(outputFormat, os.Getenv) {
+		fmt.Fprintf(os.Stderr, "Terminal does not advertise %s graphics (TERM=%q, TERM_PROGRAM=%q), using half-blocks\n",
+			outputFormat, os.Getenv("TERM"), os.Getenv("TERM_PROGRAM"))
+		outputFormat = qr.FormatTerminal
+	}
+
+	if cfg.OutputFile == "-" || isTerminalFormat(outputFormat) {
+		return qr.Render(os.Stdout, outputFormat, qr.NewMatrixFromImage(matrix), opts)
+	}
+	return qr.SaveQRImageFormat(matrix, cfg.OutputFile, outputFormat, opts)
+}
+
+// saveSizes renders the matrix once per --sizes variant, next to the output
+// file with the variant suffix. Presets multiply a base size: --size-px, or
+// the image size at --scale. Optional variants too small for one pixel per
+// module, such as the web favicons, are skipped with a warning.
+func saveSizes(matrix *image.RGBA, outputFormat string, opts qr.RenderOptions) ([]string, error) {
+	modules := matrix.Bounds().Dx() + 2*opts.QuietZone
+	base := sizePx
+	if base == 0 {
+		base = modules * max(opts.Scale, 1)
+	}
+	variants, err := qr.ParseSizes(sizes, base)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]string, 0, len(variants))
+	for _, v := range variants {
+		path := v.Filename(cfg.OutputFile)
+		if v.Optional && v.Px < modules {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s, %d px cannot hold %d modules with the quiet zone\n", path, v.Px, modules)
+			continue
+		}
+		opts.SizePx = v.Px
+		if err := qr.SaveQRImageFormat(matrix, path, outputFormat, opts); err != nil {
+			return outputs, fmt.Errorf("%s (%d px): %w", path, v.Px, err)
+		}
+		outputs = append(outputs, path)
+	}
+	return outputs, nil
+}
+
+// printContrastHint explains how to get past a contrast validation error
+func printContrastHint(w io.Writer, err error) {
+	report, _ := config.CheckContrast(cfg)
+	switch err {
+	case config.ErrLowContrast:
+		fmt.Fprintf(w, "Contrast ratio is %.2f:1, at least %.2g:1 is required (see --min-contrast)\n", report.Ratio, cfg.MinContrast)
+	case config.ErrInvertedColors:
+		fmt.Fprintln(w, "Many scanners cannot read light modules on a dark background; pass --allow-inverted to keep these colors")
+	}
+}
+
+// checkLayers rejects the styling options that the output format would
+// silently drop: only images and SVG draw shapes, eye styles, gradients and
+// the logo, halftone, frame and sizes layers
+func checkLayers(opts qr.RenderOptions, outputFormat string) error {
+	if supportsLayers(outputFormat) {
+		return nil
+	}
+	styled := func(shape string) bool {
+		return shape != "" && !strings.EqualFold(shape, qr.ShapeSquare)
+	}
+	for _, layer := range []struct {
+		flag string
+		set  bool
+	}{
+		{"--module-shape is", styled(opts.ModuleShape)},
+		{"--function-shape is", styled(opts.FunctionShape)},
+		{"--eye-frame-shape and --eye-pupil-shape are", styled(opts.EyeFrameShape) || styled(opts.EyePupilShape)},
+		{"--eye-color, --eye-frame-color and --eye-pupil-color are", opts.EyeFrameColor != nil || opts.EyePupilColor != nil || len(opts.EyeColors) > 0},
+		{"--gradient-color is", opts.Gradient != nil},
+		{"--logo is", opts.Logo != nil},
+		{"--frame and --caption are", opts.Frame != nil},
+		{"--halftone is", opts.Halftone != nil},
+		{"--sizes is", sizes != ""},
+	} {
+		if layer.set {
+			return fmt.Errorf("%s only supported for image and SVG output, not %s", layer.flag, outputFormat)
+		}
+	}
+	return nil
+}
+
+// supportsLayers reports whether the format draws the options checked by
+// checkLayers
+func supportsLayers(outputFormat string) bool {
+	return !isTerminalFormat(outputFormat) && outputFormat != qr.FormatPDF && outputFormat != qr.FormatEPS
+}
+
+// isTerminalFormat reports whether the format is displayed on standard output
+func isTerminalFormat(outputFormat string) bool {
+	return outputFormat == qr.FormatTerminal || outputFormat == qr.FormatSixel || outputFormat == qr.FormatKitty
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
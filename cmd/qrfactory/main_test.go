@@ -0,0 +1,188 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"qrfactory/pkg/config"
+	"qrfactory/pkg/qr"
+)
+
+func TestResolveFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{"From extension", "", "out.svg", qr.FormatSVG, false},
+		{"Unknown extension defaults to PNG", "", "out.webp", qr.FormatPNG, false},
+		{"Matching extension", "SVG", "out.svg", qr.FormatSVG, false},
+		{"Extension alias", "jpeg", "out.jpg", qr.FormatJPEG, false},
+		{"Unknown extension", "pdf", "out.bin", qr.FormatPDF, false},
+		{"No extension", "eps", "out", qr.FormatEPS, false},
+		{"Standard output", "svg", "-", qr.FormatSVG, false},
+		{"Terminal format ignores the file", "terminal", "qrcode.png", qr.FormatTerminal, false},
+		{"Conflicting extension", "svg", "out.png", "", true},
+		{"Conflicting extension, upper case", "png", "OUT.PDF", "", true},
+		{"Unknown format", "webp", "out.webp", "", true},
+	}
+
+	defer func(f, o string) { format, cfg.OutputFile = f, o }(format, cfg.OutputFile)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, cfg.OutputFile = tt.format, tt.output
+			got, err := resolveFormat()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEyeColorContrast(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{"Dark eyes", []string{"--eye-color", "navy", "--eye-frame-color", "maroon"}, nil},
+		{"Yellow eye", []string{"--eye-color", "black", "--eye-color", "yellow"}, config.ErrLowContrast},
+		{"Light frame", []string{"--eye-frame-color", "#eee"}, config.ErrLowContrast},
+		{"Light frame without minimum", []string{"--eye-frame-color", "#eee", "--min-contrast", "0"}, nil},
+		{"Light pupil on dark background", []string{"--bg-color", "black", "--fg-color", "white", "--allow-inverted", "--eye-pupil-color", "#222"}, config.ErrLowContrast},
+		{"Inverted eye", []string{"--eye-pupil-color", "white", "--bg-color", "#333", "--fg-color", "black", "--min-contrast", "1"}, config.ErrInvertedColors},
+		{"Too many eyes", []string{"--eye-color", "black", "--eye-color", "black", "--eye-color", "black", "--eye-color", "black"}, config.ErrInvalidEyeColor},
+	}
+
+	saved := *cfg
+	defer func() { *cfg = saved }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*cfg = saved
+			cfg.Data = "HELLO"
+			if err := rootCmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+			if err := config.ValidateConfig(cfg); err != tt.wantErr {
+				t.Fatalf("ValidateConfig() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			opts, err := renderOptions()
+			if err != nil {
+				t.Fatalf("renderOptions() error = %v", err)
+			}
+			if opts.EyeColors == nil && opts.EyeFrameColor == nil {
+				t.Error("the eye colors should reach the render options")
+			}
+		})
+	}
+}
+
+func TestRenderOptionsFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{"Known template", "banner", false},
+		{"Template in upper case", "Rounded", false},
+		{"Unknown template", "ticket", true},
+	}
+
+	defer func(f string) { frameTemplate = f }(frameTemplate)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frameTemplate = tt.template
+			opts, err := renderOptions()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), strings.Join(qr.FrameTemplates(), ", ")) {
+					t.Errorf("error %q should list the templates", err)
+				}
+				return
+			}
+			if opts.Frame == nil {
+				t.Error("the frame should reach the render options")
+			}
+		})
+	}
+}
+
+func TestCheckLayers(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    qr.RenderOptions
+		format  string
+		wantErr string
+	}{
+		{"Plain PDF", qr.RenderOptions{ModuleShape: qr.ShapeSquare, FunctionShape: "Square"}, qr.FormatPDF, ""},
+		{"Shapes in SVG", qr.RenderOptions{ModuleShape: qr.ShapeCircle, EyeFrameShape: qr.EyeLeaf}, qr.FormatSVG, ""},
+		{"Module shape in PDF", qr.RenderOptions{ModuleShape: qr.ShapeCircle}, qr.FormatPDF, "--module-shape"},
+		{"Function shape in EPS", qr.RenderOptions{FunctionShape: qr.ShapeRounded}, qr.FormatEPS, "--function-shape"},
+		{"Eye shape in terminal", qr.RenderOptions{EyePupilShape: qr.EyeCircle}, qr.FormatTerminal, "--eye-pupil-shape"},
+		{"Eye color in PDF", qr.RenderOptions{EyeColors: []color.Color{color.Black}}, qr.FormatPDF, "--eye-color"},
+		{"Gradient in sixel", qr.RenderOptions{Gradient: &qr.Gradient{}}, qr.FormatSixel, "--gradient-color"},
+		{"Logo in EPS", qr.RenderOptions{Logo: &qr.Logo{}}, qr.FormatEPS, "--logo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkLayers(tt.opts, tt.format)
+			if (err != nil) != (tt.wantErr != "") || err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkLayers() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSaveSizes(t *testing.T) {
+	symbol, err := qr.GenerateSymbol(1, "HELLO", "M")
+	if err != nil {
+		t.Fatalf("GenerateSymbol() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{"Web preset skips the favicon too small for the symbol", "web",
+			[]string{"qr-32x32.png", "qr-48x48.png", "qr-180x180.png", "qr-192x192.png", "qr-512x512.png"}, false},
+		{"Explicit size too small", "64,16", []string{"qr.png"}, true},
+	}
+
+	defer func(s, o string) { sizes, cfg.OutputFile = s, o }(sizes, cfg.OutputFile)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			sizes, cfg.OutputFile = tt.spec, filepath.Join(dir, "qr.png")
+			opts := qr.NewDefaultRenderOptions()
+			outputs, err := saveSizes(symbol.Matrix, qr.FormatPNG, opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("saveSizes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			entries, _ := os.ReadDir(dir)
+			if len(outputs) != len(tt.want) || len(entries) != len(tt.want) {
+				t.Fatalf("saveSizes() = %v, %d files, want %v", outputs, len(entries), tt.want)
+			}
+			for i, name := range tt.want {
+				if outputs[i] != filepath.Join(dir, name) {
+					t.Errorf("output %d = %q, want %q", i, outputs[i], name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"qrfactory/pkg/config"
+	"qrfactory/pkg/qr"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	stampCfg      *config.QRConfig
+	stampAnchor   string
+	stampPosition string
+	stampMargin   int
+	stampSize     int
+	stampOpacity  float64
+	stampPlate    bool
+	stampPadding  int
+)
+
+// Stamp command to draw a QR code onto existing artwork
+var stampCmd = &cobra.Command{
+	Use:   "stamp [base image]",
+	Short: "Draw a QR code onto an existing PNG, JPEG or GIF image",
+	Long: `Draw a QR code onto an existing image, such as a flyer or a product photo.
+
+The code is placed at an anchor (bottom-right by default) with a margin from
+the image edges, or at an explicit --position. It is drawn with the given
+--opacity, optionally over a white --plate that keeps it readable on busy
+artwork. The result keeps the size of the base image and is written in the
+format given by the output extension (PNG, JPEG, GIF, BMP or TIFF).`,
+	Args: cobra.ExactArgs(1),
+	// Errors are printed once by main, without the usage text
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStamp(args[0])
+	},
+}
+
+func init() {
+	stampCfg = config.NewDefaultConfig()
+	stampCfg.ErrorCorrectionLevel = "H"
+	stampCfg.OutputFile = ""
+
+	rootCmd.AddCommand(stampCmd)
+
+	stampCmd.Flags().StringVarP(&stampCfg.Data, "data", "d", "", "Data to encode in the QR code")
+	stampCmd.Flags().StringVarP(&stampCfg.OutputFile, "output", "o", "", "Output file (default: the base image name with a -qr suffix)")
+	stampCmd.Flags().IntVarP(&stampCfg.Version, "version", "v", stampCfg.Version, "QR code version (1-40), raised when the data needs it")
+	stampCmd.Flags().StringVarP(&stampCfg.ErrorCorrectionLevel, "error-correction", "e", stampCfg.ErrorCorrectionLevel, "Error correction level (L, M, Q, H)")
+	stampCmd.Flags().StringVar(&stampCfg.ForegroundColor, "fg-color", stampCfg.ForegroundColor, "Module color")
+	stampCmd.Flags().StringVar(&stampCfg.BackgroundColor, "bg-color", stampCfg.BackgroundColor, "Background color, \"transparent\" to let the image show through")
+	stampCmd.Flags().Float64Var(&stampCfg.MinContrast, "min-contrast", stampCfg.MinContrast, "Minimum WCAG contrast ratio between modules and background (0 disables the check)")
+	stampCmd.Flags().Float64Var(&stampCfg.WarnContrast, "warn-contrast", stampCfg.WarnContrast, "Contrast ratio below which a warning is printed (0 disables the warning)")
+	stampCmd.Flags().BoolVar(&stampCfg.AllowInverted, "allow-inverted", false, "Accept modules lighter than the background (reversed polarity)")
+	stampCmd.Flags().IntVarP(&stampCfg.QuietZone, "quiet-zone", "q", stampCfg.QuietZone, "Quiet zone width in modules")
+	stampCmd.Flags().IntVar(&stampSize, "size", 0, "QR code side in pixels, quiet zone included (default: a quarter of the shorter image side)")
+	stampCmd.Flags().StringVar(&stampAnchor, "anchor", qr.AnchorBottomRight, "Position in the image: "+strings.Join(qr.Anchors(), ", "))
+	stampCmd.Flags().StringVar(&stampPosition, "position", "", "Top-left corner of the QR code as X,Y pixels, overriding --anchor")
+	stampCmd.Flags().IntVar(&stampMargin, "margin", 20, "Distance in pixels from the image edges at the anchor")
+	stampCmd.Flags().Float64Var(&stampOpacity, "opacity", 1, "QR code opacity, from 0 to 1")
+	stampCmd.Flags().BoolVar(&stampPlate, "plate", false, "Draw an opaque white plate under the QR code")
+	stampCmd.Flags().IntVar(&stampPadding, "plate-padding", 10, "Plate margin around the QR code in pixels")
+}
+
+func runStamp(path string) error {
+	if stampCfg.OutputFile == "" {
+		ext := filepath.Ext(path)
+		stampCfg.OutputFile = strings.TrimSuffix(path, ext) + "-qr" + ext
+	}
+	if err := config.ValidateConfig(stampCfg); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	if report, _ := config.CheckContrast(stampCfg); report.Low {
+		fmt.Fprintf(os.Stderr, "Warning: low contrast between modules and background (%.2f:1, recommended %.2g:1 or more)\n",
+			report.Ratio, stampCfg.WarnContrast)
+	}
+	if stampOpacity <= 0 || stampOpacity > 1 {
+		return fmt.Errorf("invalid --opacity %g (expected more than 0, up to 1)", stampOpacity)
+	}
+
+	base, err := loadImage(path, "base image")
+	if err != nil {
+		return err
+	}
+
+	stamp := qr.StampOptions{
+		Anchor:       stampAnchor,
+		Margin:       stampMargin,
+		Size:         stampSize,
+		Opacity:      stampOpacity,
+		Plate:        stampPlate,
+		PlatePadding: stampPadding,
+	}
+	if stamp.Size == 0 {
+		stamp.Size = min(base.Bounds().Dx(), base.Bounds().Dy()) / 4
+	}
+	if stampPosition != "" {
+		position, err := parsePosition(stampPosition)
+		if err != nil {
+			return err
+		}
+		stamp.Position = &position
+	}
+
+	opts := qr.NewDefaultRenderOptions()
+	opts.QuietZone = stampCfg.QuietZone
+	fg, bg, err := stampCfg.Colors()
+	if err != nil {
+		return err
+	}
+	opts.Foreground = fg
+	opts.Background = bg
+
+	// Generation traces would hide the result line
+	qr.SetLogOutput(io.Discard)
+	defer qr.SetLogOutput(os.Stdout)
+
+	symbol, err := qr.GenerateSymbol(stampCfg.Version, stampCfg.Data, stampCfg.ErrorCorrectionLevel)
+	if err != nil {
+		return fmt.Errorf("cannot generate QR code: %w", err)
+	}
+	m := qr.NewMatrixFromImage(symbol.Matrix)
+
+	out, err := qr.StampImage(base, m, opts, stamp)
+	if err != nil {
+		return err
+	}
+	if err := writeStamped(stampCfg.OutputFile, out, opts); err != nil {
+		return err
+	}
+
+	fmt.Printf("QR code (version %d, level %s) stamped onto %s: %s\n",
+		symbol.Version, symbol.ErrorCorrectionLevel, path, stampCfg.OutputFile)
+	return nil
+}
+
+// writeStamped encodes the stamped image in the format of the output extension
+func writeStamped(path string, img image.Image, opts qr.RenderOptions) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create output: %w", err)
+	}
+	// A failed encoding does not leave a partial file behind
+	if err := qr.EncodeImage(file, qr.FormatFromFilename(path), img, opts); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+// parsePosition reads an "X,Y" pixel position
+func parsePosition(s string) (image.Point, error) {
+	x, y, ok := strings.Cut(s, ",")
+	if ok {
+		px, errX := strconv.Atoi(strings.TrimSpace(x))
+		py, errY := strconv.Atoi(strings.TrimSpace(y))
+		if errX == nil && errY == nil && px >= 0 && py >= 0 {
+			return image.Pt(px, py), nil
+		}
+	}
+	return image.Point{}, fmt.Errorf("invalid --position %q (expected X,Y pixels)", s)
+}
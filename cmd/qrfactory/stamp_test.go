@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"qrfactory/pkg/config"
+)
+
+func TestRunStampPolarity(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "photo.png")
+	file, err := os.Create(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, image.NewGray(image.Rect(0, 0, 400, 300))); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{"Dark modules", []string{"-d", "HELLO"}, nil},
+		{"Light modules refused", []string{"-d", "HELLO", "--fg-color", "white", "--bg-color", "black"}, config.ErrInvertedColors},
+		{"Light modules allowed", []string{"-d", "HELLO", "--fg-color", "white", "--bg-color", "black", "--allow-inverted"}, nil},
+		{"Low contrast warned, not refused", []string{"-d", "HELLO", "--fg-color", "#777", "--warn-contrast", "7"}, nil},
+	}
+
+	saved := *stampCfg
+	defer func() { *stampCfg = saved }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*stampCfg = saved
+			if err := stampCmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+			stampCfg.OutputFile = filepath.Join(dir, "out.png")
+			if err := runStamp(base); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("runStamp() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
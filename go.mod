@@ -4,6 +4,7 @@ go 1.23.0
 
 require (
 	github.com/spf13/cobra v1.9.1
+	golang.org/x/image v0.27.0
 	golang.org/x/text v0.25.0
 )
 
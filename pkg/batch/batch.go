@@ -0,0 +1,244 @@
+// Package batch génère des lots de QR codes avec un pool borné de workers.
+// Les résultats sont rapportés dans l'ordre d'entrée, quel que soit l'ordre
+// dans lequel les workers terminent.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"qrfactory/pkg/config"
+	"qrfactory/pkg/qr"
+)
+
+// Erreurs standard de la génération par lot
+var (
+	ErrInvalidScale = errors.New("échelle invalide, doit être supérieure à 0")
+	ErrNoOutput     = errors.New("aucune sortie définie pour le travail")
+)
+
+// pendingPerWorker borne le nombre de résultats en attente de réordonnancement
+// par worker, pour qu'un travail lent ne fasse pas grossir la mémoire sans limite
+const pendingPerWorker = 4
+
+// Job décrit un QR code à générer : données, options et cible de sortie
+type Job struct {
+	// Données, options de génération et fichier de sortie
+	Config config.QRConfig
+
+	// Destination de l'image ; si elle est définie, Config.OutputFile ne sert
+	// qu'à choisir le format d'après son extension
+	Writer io.Writer
+}
+
+// Result rend compte de la génération d'un travail
+type Result struct {
+	// Position du travail dans le flux d'entrée (à partir de 0)
+	Index int
+
+	// Travail d'origine
+	Job Job
+
+	// Symbole généré, nil si la génération a échoué
+	Symbol *qr.Symbol
+
+	// Durée de génération et d'écriture
+	Duration time.Duration
+
+	// Erreur éventuelle, y compris l'annulation du contexte
+	Err error
+}
+
+// Stats résume l'exécution d'un lot
+type Stats struct {
+	// Nombre de travaux rapportés
+	Total int
+
+	// Nombre de travaux générés avec succès
+	Succeeded int
+
+	// Nombre de travaux en échec ou annulés
+	Failed int
+
+	// Durée totale du lot
+	Elapsed time.Duration
+}
+
+// Throughput retourne le débit du lot en QR codes générés par seconde
+func (s Stats) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Succeeded) / s.Elapsed.Seconds()
+}
+
+// task associe un travail à sa position dans le flux d'entrée
+type task struct {
+	index int
+	job   Job
+}
+
+// Run génère les travaux reçus sur jobs avec au plus workers goroutines
+// (runtime.GOMAXPROCS si workers < 1) jusqu'à la fermeture du canal.
+// emit est appelé pour chaque résultat dans l'ordre d'entrée, toujours depuis la
+// goroutine appelante. L'annulation de ctx arrête la lecture des travaux : ceux
+// déjà lus mais pas encore générés sont rapportés avec l'erreur du contexte, qui
+// est aussi retournée.
+func Run(ctx context.Context, jobs <-chan Job, workers int, emit func(Result)) (Stats, error) {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// Initialisation unique avant le démarrage des workers
+	qr.InitGaloisField()
+
+	start := time.Now()
+	tasks := make(chan task)
+	done := make(chan Result, workers)
+	window := make(chan struct{}, workers*pendingPerWorker)
+
+	// Distribution des travaux, limitée par la fenêtre de réordonnancement
+	go func() {
+		defer close(tasks)
+		for index := 0; ; index++ {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			var job Job
+			var ok bool
+			select {
+			case job, ok = <-jobs:
+			case <-ctx.Done():
+			}
+			if !ok {
+				<-window
+				return
+			}
+			tasks <- task{index: index, job: job}
+		}
+	}()
+
+	// Démarrage des workers
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				done <- process(ctx, t)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Remise des résultats dans l'ordre d'entrée
+	var stats Stats
+	pending := make(map[int]Result)
+	next := 0
+	for r := range done {
+		pending[r.Index] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			stats.Total++
+			if r.Err != nil {
+				stats.Failed++
+			} else {
+				stats.Succeeded++
+			}
+			if emit != nil {
+				emit(r)
+			}
+			<-window
+		}
+	}
+	stats.Elapsed = time.Since(start)
+
+	return stats, ctx.Err()
+}
+
+// process génère un travail, sauf si le lot a été annulé entre-temps
+func process(ctx context.Context, t task) Result {
+	result := Result{Index: t.index, Job: t.job}
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+
+	start := time.Now()
+	result.Symbol, result.Err = Generate(t.job)
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+// Generate valide la configuration d'un travail, génère son symbole et écrit l'image
+func Generate(job Job) (*qr.Symbol, error) {
+	cfg := job.Config
+	if err := config.ValidateConfig(&cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Scale < 1 {
+		return nil, ErrInvalidScale
+	}
+	if job.Writer == nil && cfg.OutputFile == "" {
+		return nil, ErrNoOutput
+	}
+
+	symbol, err := qr.GenerateSymbol(cfg.Version, cfg.Data, cfg.ErrorCorrectionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Le format suit l'extension du fichier de sortie, y compris pour un Writer
+	opts := qr.NewDefaultRenderOptions()
+	opts.Scale = cfg.Scale
+	opts.QuietZone = cfg.QuietZone
+	fg, bg, err := cfg.Colors()
+	if err != nil {
+		return nil, err
+	}
+	opts.Foreground = fg
+	opts.Background = bg
+	format, m := qr.FormatFromFilename(cfg.OutputFile), qr.NewMatrixFromImage(symbol.Matrix)
+
+	if job.Writer != nil {
+		if err := qr.Render(job.Writer, format, m, opts); err != nil {
+			return nil, err
+		}
+		return symbol, nil
+	}
+
+	file, err := os.Create(cfg.OutputFile)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la création du fichier : %v", err)
+	}
+	// Un rendu impossible ne laisse pas de fichier incomplet, et une écriture
+	// qui échoue à la fermeture (disque plein) n'est pas un succès
+	if err := qr.Render(file, format, m, opts); err != nil {
+		file.Close()
+		os.Remove(cfg.OutputFile)
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("erreur lors de l'écriture du fichier : %v", err)
+	}
+	return symbol, nil
+}
@@ -0,0 +1,203 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"qrfactory/pkg/config"
+	"qrfactory/pkg/qr"
+)
+
+// newJob crée un travail écrivant dans un tampon mémoire
+func newJob(data string) Job {
+	cfg := config.NewDefaultConfig()
+	cfg.Data = data
+	cfg.Scale = 2
+	return Job{Config: *cfg, Writer: &bytes.Buffer{}}
+}
+
+// feed envoie les travaux sur un canal fermé à la fin
+func feed(jobs ...Job) <-chan Job {
+	ch := make(chan Job, len(jobs))
+	for _, job := range jobs {
+		ch <- job
+	}
+	close(ch)
+	return ch
+}
+
+func TestRunPreservesInputOrder(t *testing.T) {
+	qr.SetLogOutput(io.Discard)
+	defer qr.SetLogOutput(os.Stdout)
+
+	data := []string{"HELLO", "", "12345", "https://example.com", "WORLD", "", "42"}
+	jobs := make([]Job, len(data))
+	for i, d := range data {
+		jobs[i] = newJob(d)
+	}
+
+	var results []Result
+	stats, err := Run(context.Background(), feed(jobs...), 3, func(r Result) {
+		results = append(results, r)
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(results) != len(data) {
+		t.Fatalf("%d résultats, attendu %d", len(results), len(data))
+	}
+	for i, r := range results {
+		if r.Index != i || r.Job.Config.Data != data[i] {
+			t.Errorf("résultat %d : index %d, données %q ; attendu l'ordre d'entrée", i, r.Index, r.Job.Config.Data)
+		}
+		if data[i] == "" {
+			if r.Err != config.ErrEmptyData {
+				t.Errorf("résultat %d : erreur %v, attendu %v", i, r.Err, config.ErrEmptyData)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("résultat %d : erreur inattendue %v", i, r.Err)
+			continue
+		}
+		if r.Symbol == nil || r.Symbol.Version < 1 {
+			t.Errorf("résultat %d : symbole manquant", i)
+		}
+		if _, err := png.Decode(r.Job.Writer.(*bytes.Buffer)); err != nil {
+			t.Errorf("résultat %d : PNG illisible : %v", i, err)
+		}
+	}
+
+	if stats.Total != 7 || stats.Succeeded != 5 || stats.Failed != 2 {
+		t.Errorf("stats = %+v, attendu 7 travaux dont 5 réussis et 2 en échec", stats)
+	}
+	if stats.Throughput() <= 0 {
+		t.Errorf("Throughput() = %f, attendu > 0", stats.Throughput())
+	}
+}
+
+func TestRunWritesFiles(t *testing.T) {
+	qr.SetLogOutput(io.Discard)
+	defer qr.SetLogOutput(os.Stdout)
+
+	job := newJob("HELLO")
+	job.Writer = nil
+	job.Config.OutputFile = filepath.Join(t.TempDir(), "hello.png")
+
+	noOutput := newJob("HELLO")
+	noOutput.Writer = nil
+	noOutput.Config.OutputFile = ""
+
+	var results []Result
+	if _, err := Run(context.Background(), feed(job, noOutput), 2, func(r Result) {
+		results = append(results, r)
+	}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if results[0].Err != nil {
+		t.Errorf("écriture du fichier : %v", results[0].Err)
+	}
+	if !errors.Is(results[1].Err, ErrNoOutput) {
+		t.Errorf("travail sans sortie : erreur %v, attendu %v", results[1].Err, ErrNoOutput)
+	}
+}
+
+func TestGenerateAppliesColors(t *testing.T) {
+	qr.SetLogOutput(io.Discard)
+	defer qr.SetLogOutput(os.Stdout)
+
+	job := newJob("HELLO")
+	job.Config.ForegroundColor = "#800000"
+	job.Config.BackgroundColor = "ivory"
+
+	if _, err := Generate(job); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	img, err := png.Decode(job.Writer.(*bytes.Buffer))
+	if err != nil {
+		t.Fatalf("PNG illisible : %v", err)
+	}
+
+	// Coin de la zone calme, puis premier module du motif de position
+	quiet := job.Config.QuietZone * job.Config.Scale
+	if got := color.NRGBAModel.Convert(img.At(0, 0)); got != (color.NRGBA{0xff, 0xff, 0xf0, 0xff}) {
+		t.Errorf("zone calme = %v, attendu ivory", got)
+	}
+	if got := color.NRGBAModel.Convert(img.At(quiet, quiet)); got != (color.NRGBA{0x80, 0x00, 0x00, 0xff}) {
+		t.Errorf("module sombre = %v, attendu #800000", got)
+	}
+
+	job.Config.BackgroundColor = "pas une couleur"
+	if _, err := Generate(job); err != config.ErrInvalidBackgroundColor {
+		t.Errorf("Generate() error = %v, attendu %v", err, config.ErrInvalidBackgroundColor)
+	}
+}
+
+func TestRunCancellation(t *testing.T) {
+	qr.SetLogOutput(io.Discard)
+	defer qr.SetLogOutput(os.Stdout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs := make(chan Job)
+
+	// Le producteur ne ferme jamais le canal : seule l'annulation termine le lot
+	go func() {
+		for {
+			select {
+			case jobs <- newJob("HELLO"):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	count := 0
+	_, err := Run(ctx, jobs, 2, func(r Result) {
+		if r.Index != count {
+			t.Errorf("résultat %d reçu en position %d", r.Index, count)
+		}
+		count++
+		if count == 3 {
+			cancel()
+		}
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, attendu %v", err, context.Canceled)
+	}
+	if count < 3 {
+		t.Errorf("%d résultats rapportés avant l'annulation, attendu au moins 3", count)
+	}
+}
+
+func TestGenerateRemovesFailedFile(t *testing.T) {
+	qr.SetLogOutput(io.Discard)
+	defer qr.SetLogOutput(os.Stdout)
+
+	// Moteur qui écrit un début de fichier puis échoue
+	errRender := errors.New("rendu impossible")
+	qr.RegisterRenderer("batchfail", qr.RendererFunc(func(w io.Writer, m *qr.Matrix, opts qr.RenderOptions) error {
+		io.WriteString(w, "partiel")
+		return errRender
+	}))
+	qr.RegisterExtension(".batchfail", "batchfail")
+
+	job := newJob("HELLO")
+	job.Writer = nil
+	job.Config.OutputFile = filepath.Join(t.TempDir(), "hello.batchfail")
+	if _, err := Generate(job); !errors.Is(err, errRender) {
+		t.Fatalf("Generate() error = %v, attendu %v", err, errRender)
+	}
+	if _, err := os.Stat(job.Config.OutputFile); !os.IsNotExist(err) {
+		t.Errorf("le fichier incomplet devrait être supprimé (Stat : %v)", err)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    color.NRGBA
+		wantErr bool
+	}{
+		{"black", color.NRGBA{0, 0, 0, 255}, false},
+		{" RebeccaPurple ", color.NRGBA{0x66, 0x33, 0x99, 255}, false},
+		{"transparent", color.NRGBA{0, 0, 0, 0}, false},
+		{"#f80", color.NRGBA{0xff, 0x88, 0x00, 255}, false},
+		{"#F808", color.NRGBA{0xff, 0x88, 0x00, 0x88}, false},
+		{"#1a2b3c", color.NRGBA{0x1a, 0x2b, 0x3c, 255}, false},
+		{"#1a2b3c80", color.NRGBA{0x1a, 0x2b, 0x3c, 0x80}, false},
+		{"rgb(255, 128, 0)", color.NRGBA{255, 128, 0, 255}, false},
+		{"rgba(0,0,255,0.5)", color.NRGBA{0, 0, 255, 128}, false},
+		{"rgb(100% 50% 0% / 25%)", color.NRGBA{255, 128, 0, 64}, false},
+		{"", color.NRGBA{}, true},
+		{"blanc", color.NRGBA{}, true},
+		{"#12345", color.NRGBA{}, true},
+		{"#ggg", color.NRGBA{}, true},
+		{"rgb(256, 0, 0)", color.NRGBA{}, true},
+		{"rgba(0, 0, 0, 2)", color.NRGBA{}, true},
+		{"rgb(0, 0)", color.NRGBA{}, true},
+		{"rgb(0, 0, 0", color.NRGBA{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseColor(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseColor(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseColor(%q) = %v, attendu %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQRConfigColors(t *testing.T) {
+	cfg := &QRConfig{}
+	fg, bg, err := cfg.Colors()
+	if err != nil || fg != DefaultForeground || bg != DefaultBackground {
+		t.Errorf("Colors() = %v, %v, %v ; attendu les couleurs par défaut", fg, bg, err)
+	}
+
+	cfg.ForegroundColor = "navy"
+	cfg.BackgroundColor = "#fff8"
+	fg, bg, err = cfg.Colors()
+	if err != nil || fg != (color.NRGBA{0, 0, 0x80, 255}) || bg != (color.NRGBA{255, 255, 255, 0x88}) {
+		t.Errorf("Colors() = %v, %v, %v ; attendu navy sur blanc translucide", fg, bg, err)
+	}
+
+	cfg.BackgroundColor = "nope"
+	if _, _, err := cfg.Colors(); err == nil {
+		t.Errorf("Colors() avec une couleur invalide : erreur attendue")
+	}
+}
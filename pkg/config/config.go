@@ -11,12 +11,37 @@ type QRConfig struct {
 	// Échelle pour l'image de sortie
 	Scale int
 
-	// Couleur de fond
+	// Largeur de la zone calme en modules
+	QuietZone int
+
+	// Couleur de fond et de la zone calme, au format CSS (voir ParseColor)
 	BackgroundColor string
 
-	// Couleur des modules
+	// Couleur des modules, au format CSS (voir ParseColor)
 	ForegroundColor string
 
+	// Couleurs d'un dégradé remplaçant ForegroundColor, au même format ; vide
+	// pour des modules unis
+	GradientColors []string
+
+	// Couleurs du cadre et de la pupille des motifs de repérage, au même
+	// format ; vides pour reprendre celle des modules
+	EyeFrameColor string
+	EyePupilColor string
+
+	// Couleur de chaque motif de repérage (haut gauche, haut droit, bas
+	// gauche), au même format ; au plus trois
+	EyeColors []string
+
+	// Rapport de contraste WCAG minimal entre les modules et le fond (0 pour désactiver)
+	MinContrast float64
+
+	// Rapport de contraste sous lequel un avertissement est émis (0 pour désactiver)
+	WarnContrast float64
+
+	// Autoriser des modules plus clairs que le fond (polarité inversée)
+	AllowInverted bool
+
 	// Chemin du fichier de sortie
 	OutputFile string
 
@@ -30,8 +55,11 @@ func NewDefaultConfig() *QRConfig {
 		Version:              1,
 		ErrorCorrectionLevel: "M",
 		Scale:                10,
+		QuietZone:            4,
 		BackgroundColor:      "white",
 		ForegroundColor:      "black",
+		MinContrast:          DefaultMinContrast,
+		WarnContrast:         DefaultWarnContrast,
 		OutputFile:           "qrcode.png",
 		Data:                 "",
 	}
@@ -52,6 +80,30 @@ func ValidateConfig(cfg *QRConfig) error {
 		return ErrInvalidErrorCorrectionLevel
 	}
 
+	if cfg.ForegroundColor != "" {
+		if _, err := ParseColor(cfg.ForegroundColor); err != nil {
+			return ErrInvalidForegroundColor
+		}
+	}
+
+	if cfg.BackgroundColor != "" {
+		if _, err := ParseColor(cfg.BackgroundColor); err != nil {
+			return ErrInvalidBackgroundColor
+		}
+	}
+
+	if _, err := cfg.Gradient(); err != nil {
+		return err
+	}
+
+	if _, err := cfg.EyeInks(); err != nil {
+		return err
+	}
+
+	if _, err := CheckContrast(cfg); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -60,6 +112,12 @@ var (
 	ErrInvalidVersion              = NewError("version QR invalide, doit être entre 1 et 40")
 	ErrEmptyData                   = NewError("les données ne peuvent pas être vides")
 	ErrInvalidErrorCorrectionLevel = NewError("niveau de correction d'erreur invalide, doit être L, M, Q ou H")
+	ErrInvalidForegroundColor      = NewError("couleur des modules invalide, doit être un nom CSS, #rgb, #rrggbb, #rrggbbaa, rgb(), rgba() ou transparent")
+	ErrInvalidBackgroundColor      = NewError("couleur de fond invalide, doit être un nom CSS, #rgb, #rrggbb, #rrggbbaa, rgb(), rgba() ou transparent")
+	ErrInvalidGradient             = NewError("dégradé invalide, il faut au moins deux couleurs au format de ForegroundColor")
+	ErrInvalidEyeColor             = NewError("couleur des motifs de repérage invalide, au format de ForegroundColor et trois au plus par motif")
+	ErrLowContrast                 = NewError("contraste insuffisant entre les modules et le fond, le QR code risque d'être illisible")
+	ErrInvertedColors              = NewError("modules plus clairs que le fond (polarité inversée), à autoriser explicitement")
 )
 
 // Error représente une erreur de configuration
@@ -69,6 +69,37 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: ErrInvalidErrorCorrectionLevel,
 		},
+		{
+			name: "couleurs valides",
+			config: &QRConfig{
+				Version:              1,
+				ErrorCorrectionLevel: "M",
+				Data:                 "test",
+				ForegroundColor:      "#1a2b3c",
+				BackgroundColor:      "transparent",
+			},
+			wantErr: nil,
+		},
+		{
+			name: "couleur des modules invalide",
+			config: &QRConfig{
+				Version:              1,
+				ErrorCorrectionLevel: "M",
+				Data:                 "test",
+				ForegroundColor:      "#12345",
+			},
+			wantErr: ErrInvalidForegroundColor,
+		},
+		{
+			name: "couleur de fond invalide",
+			config: &QRConfig{
+				Version:              1,
+				ErrorCorrectionLevel: "M",
+				Data:                 "test",
+				BackgroundColor:      "blanc",
+			},
+			wantErr: ErrInvalidBackgroundColor,
+		},
 	}
 
 	for _, tt := range tests {
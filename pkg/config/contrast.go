@@ -0,0 +1,103 @@
+package config
+
+import (
+	"image/color"
+	"math"
+)
+
+// Seuils de contraste par défaut : en dessous de DefaultMinContrast la
+// configuration est refusée, en dessous de DefaultWarnContrast elle est signalée
+const (
+	DefaultMinContrast  = 3.0
+	DefaultWarnContrast = 4.5
+)
+
+// ContrastReport décrit le contraste entre les modules et le fond
+type ContrastReport struct {
+	// Rapport de contraste WCAG, de 1 (aucun contraste) à 21 (noir sur blanc)
+	Ratio float64
+
+	// Modules, ou certains d'entre eux, plus clairs que le fond (polarité inversée)
+	Inverted bool
+
+	// Contraste sous le seuil d'avertissement, mais accepté
+	Low bool
+}
+
+// RelativeLuminance calcule la luminance relative WCAG d'une couleur opaque, de 0 (noir) à 1 (blanc)
+func RelativeLuminance(c color.NRGBA) float64 {
+	linear := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
+
+// ContrastRatio calcule le rapport de contraste WCAG entre deux couleurs opaques
+func ContrastRatio(a, b color.NRGBA) float64 {
+	la, lb := RelativeLuminance(a), RelativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// CheckContrast évalue le contraste entre les couleurs de la configuration. Un fond
+// translucide est supposé posé sur du blanc, et les modules translucides sur le
+// fond. Avec un dégradé ou des couleurs propres aux motifs de repérage, chaque
+// couleur est évaluée : la moins contrastée donne le rapport, et une seule
+// couleur plus claire que le fond rend la polarité inversée. Retourne ErrLowContrast sous MinContrast, et
+// ErrInvertedColors si les modules sont plus clairs que le fond sans
+// AllowInverted. Un seuil nul désactive la vérification correspondante.
+func CheckContrast(cfg *QRConfig) (ContrastReport, error) {
+	fg, bg, err := cfg.Colors()
+	if err != nil {
+		return ContrastReport{}, err
+	}
+	inks, err := cfg.Gradient()
+	if err != nil {
+		return ContrastReport{}, err
+	}
+	if inks == nil {
+		inks = []color.NRGBA{fg}
+	}
+	eyes, err := cfg.EyeInks()
+	if err != nil {
+		return ContrastReport{}, err
+	}
+	inks = append(inks, eyes...)
+
+	bg = over(bg, DefaultBackground)
+
+	var report ContrastReport
+	for i, ink := range inks {
+		ink = over(ink, bg)
+		if ratio := ContrastRatio(ink, bg); i == 0 || ratio < report.Ratio {
+			report.Ratio = ratio
+		}
+		// Une seule couleur plus claire que le fond suffit à inverser la polarité
+		report.Inverted = report.Inverted || RelativeLuminance(ink) > RelativeLuminance(bg)
+	}
+
+	if cfg.MinContrast > 0 && report.Ratio < cfg.MinContrast {
+		return report, ErrLowContrast
+	}
+	if report.Inverted && !cfg.AllowInverted {
+		return report, ErrInvertedColors
+	}
+	report.Low = cfg.WarnContrast > 0 && report.Ratio < cfg.WarnContrast
+
+	return report, nil
+}
+
+// over compose une couleur translucide sur un fond opaque
+func over(c, backdrop color.NRGBA) color.NRGBA {
+	a := float64(c.A) / 255
+	mix := func(v, b uint8) uint8 {
+		return uint8(math.Round(float64(v)*a + float64(b)*(1-a)))
+	}
+	return color.NRGBA{R: mix(c.R, backdrop.R), G: mix(c.G, backdrop.G), B: mix(c.B, backdrop.B), A: 0xFF}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b color.NRGBA
+		want float64
+	}{
+		{"Noir sur blanc", DefaultForeground, DefaultBackground, 21},
+		{"Symétrique", DefaultBackground, DefaultForeground, 21},
+		{"Couleur identique", color.NRGBA{0x80, 0x80, 0x80, 0xff}, color.NRGBA{0x80, 0x80, 0x80, 0xff}, 1},
+		{"Gris moyen sur blanc", color.NRGBA{0x77, 0x77, 0x77, 0xff}, DefaultBackground, 4.48},
+		{"Jaune pâle sur blanc", color.NRGBA{0xff, 0xff, 0xe0, 0xff}, DefaultBackground, 1.02},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContrastRatio(tt.a, tt.b); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("ContrastRatio() = %.3f, attendu %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckContrast(t *testing.T) {
+	tests := []struct {
+		name          string
+		fg, bg        string
+		allowInverted bool
+		wantErr       error
+		wantInverted  bool
+		wantLow       bool
+	}{
+		{"Noir sur blanc", "black", "white", false, nil, false, false},
+		{"Jaune pâle sur blanc", "lightyellow", "white", false, ErrLowContrast, false, false},
+		{"Gris moyen, avertissement", "#888888", "white", false, nil, false, true},
+		{"Modules clairs sur fond sombre", "white", "#101010", false, ErrInvertedColors, true, false},
+		{"Polarité inversée autorisée", "white", "#101010", true, nil, true, false},
+		{"Fond transparent sur blanc", "navy", "transparent", false, nil, false, false},
+		{"Modules blancs sur fond transparent", "white", "transparent", true, ErrLowContrast, false, false},
+		{"Modules translucides", "#00000030", "white", false, ErrLowContrast, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.ForegroundColor = tt.fg
+			cfg.BackgroundColor = tt.bg
+			cfg.AllowInverted = tt.allowInverted
+
+			report, err := CheckContrast(cfg)
+			if err != tt.wantErr {
+				t.Fatalf("CheckContrast() error = %v, attendu %v (rapport %.2f)", err, tt.wantErr, report.Ratio)
+			}
+			if report.Inverted != tt.wantInverted {
+				t.Errorf("Inverted = %v, attendu %v", report.Inverted, tt.wantInverted)
+			}
+			if report.Low != tt.wantLow {
+				t.Errorf("Low = %v, attendu %v (rapport %.2f)", report.Low, tt.wantLow, report.Ratio)
+			}
+		})
+	}
+}
+
+func TestCheckContrastGradient(t *testing.T) {
+	tests := []struct {
+		name     string
+		colors   []string
+		wantErr  error
+		minRatio float64
+	}{
+		{"Dégradé sombre", []string{"black", "navy"}, nil, 15},
+		{"Couleur claire en fin de dégradé", []string{"black", "lightyellow"}, ErrLowContrast, 1},
+		{"Couleur intermédiaire signalée", []string{"black", "#888888", "navy"}, nil, 3},
+		{"Une seule couleur", []string{"black"}, ErrInvalidGradient, 0},
+		{"Couleur invalide", []string{"black", "nocolor"}, ErrInvalidGradient, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.GradientColors = tt.colors
+
+			report, err := CheckContrast(cfg)
+			if err != tt.wantErr {
+				t.Fatalf("CheckContrast() error = %v, attendu %v (rapport %.2f)", err, tt.wantErr, report.Ratio)
+			}
+			if err == nil && (report.Ratio < tt.minRatio || report.Ratio > 21) {
+				t.Errorf("rapport %.2f, attendu au moins %.2g", report.Ratio, tt.minRatio)
+			}
+		})
+	}
+
+	// Le rapport retenu est celui de la couleur la moins contrastée
+	cfg := NewDefaultConfig()
+	cfg.GradientColors = []string{"black", "#888888", "navy"}
+	if report, _ := CheckContrast(cfg); !report.Low {
+		t.Errorf("Low = false pour un gris moyen dans le dégradé (rapport %.2f)", report.Ratio)
+	}
+}
+
+func TestCheckContrastEyes(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   string
+		pupil   string
+		eyes    []string
+		wantErr error
+		wantLow bool
+	}{
+		{"Yeux sombres", "navy", "maroon", []string{"black", "darkgreen"}, nil, false},
+		{"Cadre presque blanc", "#eee", "", nil, ErrLowContrast, false},
+		{"Œil jaune", "", "", []string{"black", "yellow"}, ErrLowContrast, false},
+		{"Pupille grise signalée", "", "#777", nil, nil, true},
+		{"Œil gris foncé", "", "", []string{"#333"}, nil, false},
+		{"Quatre yeux", "", "", []string{"black", "black", "black", "black"}, ErrInvalidEyeColor, false},
+		{"Couleur invalide", "nocolor", "", nil, ErrInvalidEyeColor, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.EyeFrameColor, cfg.EyePupilColor, cfg.EyeColors = tt.frame, tt.pupil, tt.eyes
+
+			report, err := CheckContrast(cfg)
+			if err != tt.wantErr {
+				t.Fatalf("CheckContrast() error = %v, attendu %v (rapport %.2f)", err, tt.wantErr, report.Ratio)
+			}
+			if report.Low != tt.wantLow {
+				t.Errorf("Low = %v, attendu %v (rapport %.2f)", report.Low, tt.wantLow, report.Ratio)
+			}
+		})
+	}
+
+	// Un œil clair sur fond sombre relève de la polarité inversée
+	cfg := NewDefaultConfig()
+	cfg.ForegroundColor, cfg.BackgroundColor = "white", "black"
+	cfg.AllowInverted = true
+	cfg.EyeColors = []string{"white", "black"}
+	if _, err := CheckContrast(cfg); err != ErrLowContrast {
+		t.Errorf("CheckContrast() error = %v, attendu %v pour un œil noir sur fond noir", err, ErrLowContrast)
+	}
+	cfg.EyeColors = []string{"white", "#eee"}
+	cfg.AllowInverted = false
+	if _, err := CheckContrast(cfg); err != ErrInvertedColors {
+		t.Errorf("CheckContrast() error = %v, attendu %v", err, ErrInvertedColors)
+	}
+}
+
+func TestValidateConfigContrast(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Data = "test"
+	cfg.ForegroundColor = "white"
+	cfg.BackgroundColor = "black"
+	if err := ValidateConfig(cfg); err != ErrInvertedColors {
+		t.Errorf("ValidateConfig() error = %v, attendu %v", err, ErrInvertedColors)
+	}
+
+	cfg.AllowInverted = true
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("ValidateConfig() error = %v, attendu nil", err)
+	}
+
+	// Seuil nul : vérification désactivée
+	cfg.ForegroundColor = "#eee"
+	cfg.BackgroundColor = "white"
+	cfg.MinContrast = 0
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("ValidateConfig() error = %v, attendu nil", err)
+	}
+}
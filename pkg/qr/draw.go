@@ -0,0 +1,79 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// drawReference est l'échelle du rendu qui mesure le symbole ; multiple de
+// HalftoneCell, elle n'est pas arrondie en mode tramé
+const drawReference = 2 * HalftoneCell
+
+// drawSupersampling est le nombre de pixels rendus par pixel de destination et
+// par côté avant la réduction d'un dessin anticrénelé
+const drawSupersampling = 4
+
+// Draw dessine le symbole, zone calme comprise, dans le rectangle r de dst,
+// centré et aussi grand que possible. Les modules mesurent un nombre entier de
+// pixels, au risque de laisser une marge, sauf avec AntiAlias : le symbole
+// remplit alors le rectangle avec des modules de taille fractionnaire aux
+// bords lissés. Le fond est composé sur dst, qui reste visible sous un fond
+// transparent. Les options de taille (SizePx, SizeMM, ModuleMM, Scale) sont
+// ignorées.
+func Draw(dst draw.Image, r image.Rectangle, m *Matrix, opts RenderOptions) error {
+	opts.SizePx, opts.SizeMM, opts.ModuleMM = 0, 0, 0
+
+	// Dimensions pour un pixel par module, un cadre pouvant rendre l'image rectangulaire
+	opts.Scale = drawReference
+	reference, err := RenderImage(m, opts)
+	if err != nil {
+		return err
+	}
+	w := float64(reference.Bounds().Dx()) / drawReference
+	h := float64(reference.Bounds().Dy()) / drawReference
+	fit := math.Min(float64(r.Dx())/w, float64(r.Dy())/h)
+	if fit < 1 && !opts.AntiAlias {
+		return fmt.Errorf("rectangle de %d×%d pixels trop petit pour un symbole de %d×%d modules, zone calme comprise",
+			r.Dx(), r.Dy(), int(math.Ceil(w)), int(math.Ceil(h)))
+	}
+
+	var img image.Image
+	if opts.AntiAlias {
+		opts.Scale = max(int(math.Ceil(fit*drawSupersampling)), 1)
+		if img, err = RenderImage(m, opts); err != nil {
+			return err
+		}
+		size := image.Pt(int(math.Round(w*fit)), int(math.Round(h*fit)))
+		if size.X < 1 || size.Y < 1 {
+			return fmt.Errorf("rectangle de %d×%d pixels vide", r.Dx(), r.Dy())
+		}
+		scaled := image.NewNRGBA(image.Rectangle{Max: size})
+		xdraw.CatmullRom.Scale(scaled, scaled.Rect, img, img.Bounds(), xdraw.Src, nil)
+		img = scaled
+	} else {
+		// L'arrondi de l'échelle (trame) peut agrandir l'image : on réduit
+		// l'échelle jusqu'à ce qu'elle tienne
+		for scale := int(fit); scale >= 1 && img == nil; scale-- {
+			opts.Scale = scale
+			rendered, err := RenderImage(m, opts)
+			if err != nil {
+				return err
+			}
+			if size := rendered.Bounds().Size(); size.X <= r.Dx() && size.Y <= r.Dy() {
+				img = rendered
+			}
+		}
+		if img == nil {
+			return fmt.Errorf("rectangle de %d×%d pixels trop petit pour le symbole tramé", r.Dx(), r.Dy())
+		}
+	}
+
+	size := img.Bounds().Size()
+	at := r.Min.Add(r.Size().Sub(size).Div(2))
+	draw.Draw(dst, image.Rectangle{Min: at, Max: at.Add(size)}, img, img.Bounds().Min, draw.Over)
+	return nil
+}
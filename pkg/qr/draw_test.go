@@ -0,0 +1,56 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDraw(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	red := color.NRGBA{R: 0xFF, A: 0xFF}
+	black := color.NRGBA{A: 0xFF}
+	white := color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+
+	tests := []struct {
+		name string
+		r    image.Rectangle
+		opts RenderOptions
+		x, y int
+		want color.NRGBA
+	}{
+		// Symbole de 33 modules, zone calme comprise : 3 pixels par module dans
+		// 100 pixels, la colonne restante est laissée à droite
+		{"Modules entiers, marge laissée", image.Rect(0, 0, 100, 100), RenderOptions{QuietZone: 4}, 99, 50, red},
+		{"Modules entiers, fond", image.Rect(0, 0, 100, 100), RenderOptions{QuietZone: 4}, 0, 50, white},
+		{"Modules entiers, motif de repérage", image.Rect(0, 0, 100, 100), RenderOptions{QuietZone: 4}, 13, 13, black},
+		{"Décalé et centré", image.Rect(200, 100, 300, 300), RenderOptions{QuietZone: 4}, 200 + 13, 150 + 13, black},
+		{"Anticrénelé, rectangle rempli", image.Rect(0, 0, 100, 100), RenderOptions{QuietZone: 4, AntiAlias: true}, 99, 50, white},
+		{"Anticrénelé, motif de repérage", image.Rect(0, 0, 100, 100), RenderOptions{QuietZone: 4, AntiAlias: true}, 14, 14, black},
+		{"Fond transparent", image.Rect(0, 0, 100, 100), RenderOptions{QuietZone: 4, Background: color.Transparent}, 5, 50, red},
+		{"Taille ignorée", image.Rect(0, 0, 66, 66), RenderOptions{QuietZone: 4, SizePx: 600, Scale: 30}, 0, 0, white},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := image.NewNRGBA(image.Rect(0, 0, 400, 400))
+			for i := 0; i < len(dst.Pix); i += 4 {
+				dst.Pix[i], dst.Pix[i+3] = 0xFF, 0xFF
+			}
+			if err := Draw(dst, tt.r, m, tt.opts); err != nil {
+				t.Fatalf("Draw() error = %v", err)
+			}
+			if got := dst.NRGBAAt(tt.x, tt.y); got != tt.want {
+				t.Errorf("pixel (%d, %d) = %v, attendu %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, 400, 400))
+	if err := Draw(dst, image.Rect(0, 0, 20, 20), m, RenderOptions{QuietZone: 4}); err == nil {
+		t.Error("un rectangle plus petit que le symbole devrait être refusé sans anticrénelage")
+	}
+	if err := Draw(dst, image.Rect(0, 0, 20, 20), m, RenderOptions{QuietZone: 4, AntiAlias: true}); err != nil {
+		t.Errorf("Draw() anticrénelé error = %v", err)
+	}
+}
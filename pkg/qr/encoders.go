@@ -0,0 +1,258 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"math"
+	"strings"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// DefaultJPEGQuality est la qualité JPEG utilisée si les options n'en précisent
+// pas : assez haute pour que les bords des modules restent nets
+const DefaultJPEGQuality = 90
+
+// ImageEncoder encode l'image matricielle du symbole. Elle est le plus souvent
+// une *image.Paletted dont l'indice 0 est le fond et l'indice 1 les modules,
+// les indices suivants portant les teintes d'anticrénelage des formes
+// arrondies ; un dégradé, des yeux de couleurs différentes ou un logo imposent
+// une image RVBA, de même qu'un cadre qui agrandit l'image autour du symbole.
+type ImageEncoder func(w io.Writer, img image.Image, opts RenderOptions) error
+
+// RasterRenderer adapte un encodeur d'images à l'interface Renderer : la matrice
+// est dessinée par RenderImage puis confiée à l'encodeur
+func RasterRenderer(enc ImageEncoder) Renderer {
+	return RendererFunc(func(w io.Writer, m *Matrix, opts RenderOptions) error {
+		img, err := RenderImage(m, opts)
+		if err != nil {
+			return err
+		}
+		return enc(w, img, opts)
+	})
+}
+
+// RenderImage dessine la matrice selon les options (échelle, taille, zone
+// calme, couleurs, dégradé, trame, logo, cadre) dans une image en mémoire
+func RenderImage(m *Matrix, opts RenderOptions) (image.Image, error) {
+	m, opts, err := prepareHalftone(m, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts = opts.polarity()
+	frame, err := newFrameLayout(opts.Frame, m.size+2*opts.QuietZone, opts)
+	if err != nil {
+		return nil, err
+	}
+	m, logo, err := prepareLogo(m, opts)
+	if err != nil {
+		return nil, err
+	}
+	img, err := rasterize(m, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out image.Image = img
+	if opts.painted() {
+		if out, err = paint(img, m, opts); err != nil {
+			return nil, err
+		}
+	}
+	if logo != nil {
+		if out, err = logo.draw(out, m, opts); err != nil {
+			return nil, err
+		}
+	}
+	if frame != nil {
+		out = frame.draw(out, opts)
+	}
+	return out, nil
+}
+
+// imageEncoders associe chaque format matriciel à son encodeur
+var imageEncoders = map[string]ImageEncoder{
+	FormatPNG:  encodePNG,
+	FormatJPEG: encodeJPEG,
+	FormatGIF:  encodeGIF,
+	FormatBMP:  encodeBMP,
+	FormatTIFF: encodeTIFF,
+}
+
+// EncodeImage encode une image quelconque au format matriciel demandé, avec
+// les réglages d'encodage des options (compression, qualité, résolution)
+func EncodeImage(w io.Writer, format string, img image.Image, opts RenderOptions) error {
+	enc, ok := imageEncoders[strings.ToLower(format)]
+	if !ok {
+		return fmt.Errorf("format d'image matricielle inconnu : %q", format)
+	}
+	return enc(w, img, opts)
+}
+
+// encodeJPEG encode l'image en JPEG ; le format n'ayant pas de transparence, les
+// couleurs translucides sont posées sur du blanc
+func encodeJPEG(w io.Writer, img image.Image, opts RenderOptions) error {
+	quality := opts.JPEGQuality
+	if quality == 0 {
+		quality = DefaultJPEGQuality
+	}
+	if quality < 1 || quality > 100 {
+		return fmt.Errorf("qualité JPEG invalide : %d (attendu de 1 à 100)", quality)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flatten(img, false), &jpeg.Options{Quality: quality}); err != nil {
+		return fmt.Errorf("erreur lors de l'encodage JPEG : %v", err)
+	}
+
+	data := buf.Bytes()
+	if opts.physical() {
+		data = withJPEGResolution(data, opts.dpi())
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture JPEG : %v", err)
+	}
+	return nil
+}
+
+// withJPEGResolution insère après le marqueur SOI un segment APP0 JFIF donnant
+// la résolution en points par pouce
+func withJPEGResolution(data []byte, dpi float64) []byte {
+	density := uint16(math.Min(math.Round(dpi), math.MaxUint16))
+
+	segment := []byte{0xFF, 0xE0, 0, 16, 'J', 'F', 'I', 'F', 0, 1, 1, 1}
+	segment = binary.BigEndian.AppendUint16(segment, density)
+	segment = binary.BigEndian.AppendUint16(segment, density)
+	segment = append(segment, 0, 0) // Pas de vignette
+
+	out := make([]byte, 0, len(data)+len(segment))
+	out = append(out, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+// encodeGIF encode l'image en GIF ; un fond totalement transparent est conservé,
+// les autres couleurs translucides sont posées sur du blanc
+func encodeGIF(w io.Writer, img image.Image, opts RenderOptions) error {
+	if err := gif.Encode(w, flatten(img, true), nil); err != nil {
+		return fmt.Errorf("erreur lors de l'encodage GIF : %v", err)
+	}
+	return nil
+}
+
+// encodeBMP encode l'image en BMP indexé, les couleurs translucides étant posées sur du blanc
+func encodeBMP(w io.Writer, img image.Image, opts RenderOptions) error {
+	if err := bmp.Encode(w, flatten(img, false)); err != nil {
+		return fmt.Errorf("erreur lors de l'encodage BMP : %v", err)
+	}
+	return nil
+}
+
+// encodeTIFF encode l'image en TIFF compressé (Deflate). Une palette TIFF
+// n'ayant pas de canal alpha, les couleurs translucides imposent une image RVBA.
+func encodeTIFF(w io.Writer, img image.Image, opts RenderOptions) error {
+	src := img
+	if p, ok := img.(*image.Paletted); ok && !opaquePalette(p.Palette) {
+		nrgba := image.NewNRGBA(img.Bounds())
+		draw.Draw(nrgba, nrgba.Bounds(), img, image.Point{}, draw.Src)
+		src = nrgba
+	}
+
+	var buf bytes.Buffer
+	if err := tiff.Encode(&buf, src, &tiff.Options{Compression: tiff.Deflate}); err != nil {
+		return fmt.Errorf("erreur lors de l'encodage TIFF : %v", err)
+	}
+
+	data := buf.Bytes()
+	if opts.physical() {
+		setTIFFResolution(data, opts.dpi())
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture TIFF : %v", err)
+	}
+	return nil
+}
+
+// Étiquettes TIFF de résolution, exprimées en fraction de points par pouce
+const (
+	tiffTagXResolution = 282
+	tiffTagYResolution = 283
+	tiffTypeRational   = 5
+)
+
+// setTIFFResolution remplace les résolutions horizontale et verticale du premier
+// répertoire d'image (72 DPI par défaut pour l'encodeur) par dpi, au centième près
+func setTIFFResolution(data []byte, dpi float64) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if string(data[:2]) == "MM" {
+		order = binary.BigEndian
+	}
+
+	ifd := int(order.Uint32(data[4:]))
+	count := int(order.Uint16(data[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := data[ifd+2+12*i:]
+		tag, kind := order.Uint16(entry), order.Uint16(entry[2:])
+		if (tag != tiffTagXResolution && tag != tiffTagYResolution) || kind != tiffTypeRational {
+			continue
+		}
+		value := int(order.Uint32(entry[8:]))
+		order.PutUint32(data[value:], uint32(math.Round(dpi*100)))
+		order.PutUint32(data[value+4:], 100)
+	}
+}
+
+// flatten pose les couleurs translucides de l'image sur du blanc ; pour une
+// image à palette, keepTransparent conserve les couleurs totalement transparentes
+func flatten(img image.Image, keepTransparent bool) image.Image {
+	if p, ok := img.(*image.Paletted); ok {
+		return withPalette(p, flattenPalette(p.Palette, keepTransparent))
+	}
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Over)
+	return out
+}
+
+// withPalette retourne une image partageant les pixels de img avec une autre palette
+func withPalette(img *image.Paletted, palette color.Palette) *image.Paletted {
+	out := *img
+	out.Palette = palette
+	return &out
+}
+
+// flattenPalette pose les couleurs translucides de la palette sur du blanc ;
+// avec keepTransparent, les couleurs totalement transparentes sont conservées
+func flattenPalette(palette color.Palette, keepTransparent bool) color.Palette {
+	out := make(color.Palette, len(palette))
+	for i, c := range palette {
+		nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+		if nrgba.A == 0xFF || (keepTransparent && nrgba.A == 0) {
+			out[i] = nrgba
+			continue
+		}
+		a := uint32(nrgba.A)
+		mix := func(v uint8) uint8 {
+			return uint8((uint32(v)*a + 0xFF*(0xFF-a) + 0x7F) / 0xFF)
+		}
+		out[i] = color.NRGBA{R: mix(nrgba.R), G: mix(nrgba.G), B: mix(nrgba.B), A: 0xFF}
+	}
+	return out
+}
+
+// opaquePalette indique si toutes les couleurs de la palette sont opaques
+func opaquePalette(palette color.Palette) bool {
+	for _, c := range palette {
+		if _, _, _, a := c.RGBA(); a != 0xFFFF {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+func TestRasterEncoders(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	opts := RenderOptions{QuietZone: 4, Scale: 6}
+
+	tests := []struct {
+		format string
+		decode func(data []byte) (image.Image, error)
+	}{
+		{FormatJPEG, func(data []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(data)) }},
+		{FormatGIF, func(data []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(data)) }},
+		{FormatBMP, func(data []byte) (image.Image, error) { return bmp.Decode(bytes.NewReader(data)) }},
+		{FormatTIFF, func(data []byte) (image.Image, error) { return tiff.Decode(bytes.NewReader(data)) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Render(&buf, tt.format, m, opts); err != nil {
+				t.Fatalf("Render(%q) error = %v", tt.format, err)
+			}
+			img, err := tt.decode(buf.Bytes())
+			if err != nil {
+				t.Fatalf("image illisible : %v", err)
+			}
+
+			// Centre de chaque module : la compression JPEG floute les bords
+			total := m.Size() + 2*opts.QuietZone
+			if img.Bounds().Dx() != total*opts.Scale {
+				t.Fatalf("image de %d pixels, attendu %d", img.Bounds().Dx(), total*opts.Scale)
+			}
+			for y := 0; y < total; y++ {
+				for x := 0; x < total; x++ {
+					gray := color.GrayModel.Convert(img.At(x*opts.Scale+opts.Scale/2, y*opts.Scale+opts.Scale/2)).(color.Gray)
+					if dark := m.Get(x-opts.QuietZone, y-opts.QuietZone); dark != (gray.Y < 0x80) {
+						t.Fatalf("module (%d,%d) de niveau %d, sombre attendu = %v", x, y, gray.Y, dark)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRasterEncodersTransparency(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows(cornerRows))
+	opts := RenderOptions{
+		QuietZone:  1,
+		Scale:      2,
+		Foreground: color.NRGBA{0x00, 0x00, 0x80, 0x80},
+		Background: color.Transparent,
+	}
+
+	tests := []struct {
+		format     string
+		decode     func(data []byte) (image.Image, error)
+		background color.NRGBA
+		foreground color.NRGBA
+	}{
+		// Pas de transparence en JPEG et BMP : tout est posé sur du blanc
+		{FormatBMP, func(data []byte) (image.Image, error) { return bmp.Decode(bytes.NewReader(data)) },
+			color.NRGBA{0xff, 0xff, 0xff, 0xff}, color.NRGBA{0x7f, 0x7f, 0xbf, 0xff}},
+		// GIF : fond transparent conservé, modules translucides posés sur du blanc
+		{FormatGIF, func(data []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(data)) },
+			color.NRGBA{}, color.NRGBA{0x7f, 0x7f, 0xbf, 0xff}},
+		// TIFF : canal alpha conservé
+		{FormatTIFF, func(data []byte) (image.Image, error) { return tiff.Decode(bytes.NewReader(data)) },
+			color.NRGBA{}, color.NRGBA{0x00, 0x00, 0x80, 0x80}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Render(&buf, tt.format, m, opts); err != nil {
+				t.Fatalf("Render(%q) error = %v", tt.format, err)
+			}
+			img, err := tt.decode(buf.Bytes())
+			if err != nil {
+				t.Fatalf("image illisible : %v", err)
+			}
+
+			nrgba := func(x, y int) color.NRGBA {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				if c.A == 0 {
+					return color.NRGBA{}
+				}
+				return c
+			}
+			if got := nrgba(0, 0); got != tt.background {
+				t.Errorf("fond = %v, attendu %v", got, tt.background)
+			}
+			// Coin haut gauche du symbole, toujours sombre dans cornerRows
+			if got := nrgba(2, 2); got != tt.foreground {
+				t.Errorf("module = %v, attendu %v", got, tt.foreground)
+			}
+		})
+	}
+}
+
+func TestRasterEncodersResolution(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(1))
+	opts := RenderOptions{QuietZone: 4, SizeMM: 25, DPI: 600}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, FormatJPEG, m, opts); err != nil {
+		t.Fatalf("Render(jpeg) error = %v", err)
+	}
+	data := buf.Bytes()
+	if !bytes.Equal(data[2:4], []byte{0xFF, 0xE0}) || string(data[6:11]) != "JFIF\x00" {
+		t.Fatalf("segment JFIF absent après SOI : % x", data[:12])
+	}
+	if unit, x, y := data[13], binary.BigEndian.Uint16(data[14:]), binary.BigEndian.Uint16(data[16:]); unit != 1 || x != 600 || y != 600 {
+		t.Errorf("densité JFIF = %d×%d unité %d, attendu 600×600 DPI", x, y, unit)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("JPEG illisible : %v", err)
+	}
+
+	buf.Reset()
+	if err := Render(&buf, FormatTIFF, m, opts); err != nil {
+		t.Fatalf("Render(tiff) error = %v", err)
+	}
+	data = buf.Bytes()
+	found := 0
+	ifd := int(binary.LittleEndian.Uint32(data[4:]))
+	for i := 0; i < int(binary.LittleEndian.Uint16(data[ifd:])); i++ {
+		entry := data[ifd+2+12*i:]
+		if tag := binary.LittleEndian.Uint16(entry); tag == tiffTagXResolution || tag == tiffTagYResolution {
+			value := binary.LittleEndian.Uint32(entry[8:])
+			num, den := binary.LittleEndian.Uint32(data[value:]), binary.LittleEndian.Uint32(data[value+4:])
+			if float64(num)/float64(den) != 600 {
+				t.Errorf("résolution TIFF (étiquette %d) = %d/%d, attendu 600", tag, num, den)
+			}
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("%d étiquettes de résolution TIFF trouvées, attendu 2", found)
+	}
+	if _, err := tiff.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("TIFF illisible : %v", err)
+	}
+}
+
+func TestEncodeJPEGQuality(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(5))
+
+	sizes := make(map[int]int)
+	for _, quality := range []int{0, 10, 100, -1, 101} {
+		var buf bytes.Buffer
+		err := Render(&buf, FormatJPEG, m, RenderOptions{QuietZone: 4, Scale: 4, JPEGQuality: quality})
+		if invalid := quality < 0 || quality > 100; (err != nil) != invalid {
+			t.Fatalf("qualité %d : error = %v, erreur attendue %v", quality, err, invalid)
+		}
+		sizes[quality] = buf.Len()
+	}
+	if sizes[10] >= sizes[100] {
+		t.Errorf("qualité 10 (%d octets) pas plus compacte que qualité 100 (%d octets)", sizes[10], sizes[100])
+	}
+}
@@ -0,0 +1,100 @@
+package qr
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strings"
+)
+
+// SaveQRImageEPS sauvegarde la matrice QR en PostScript encapsulé
+func SaveQRImageEPS(matrix *image.RGBA, outputFile string, opts RenderOptions) error {
+	return saveRendered(outputFile, RendererFunc(RenderEPS), matrix, opts)
+}
+
+// RenderEPS écrit la matrice dans w en PostScript encapsulé (EPSF-3.0), avec une
+// boîte englobante aux dimensions exactes du symbole, zone calme comprise. Les
+// modules sombres forment un unique chemin rempli, en CMJN ou en ton direct.
+func RenderEPS(w io.Writer, m *Matrix, opts RenderOptions) error {
+	layout, err := newPrintLayout(m, opts)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	// En-tête DSC
+	fmt.Fprintf(bw, "%%!PS-Adobe-3.0 EPSF-3.0\n")
+	fmt.Fprintf(bw, "%%%%BoundingBox: 0 0 %d %d\n", int(math.Ceil(layout.size)), int(math.Ceil(layout.size)))
+	fmt.Fprintf(bw, "%%%%HiResBoundingBox: 0 0 %s %s\n", formatNumber(layout.size), formatNumber(layout.size))
+	fmt.Fprintf(bw, "%%%%Creator: QRFactory\n")
+	if opts.Title != "" {
+		fmt.Fprintf(bw, "%%%%Title: %s\n", dscText(opts.Title))
+	}
+	if opts.SpotColor != "" {
+		fmt.Fprintf(bw, "%%%%DocumentCustomColors: (%s)\n", psString(opts.SpotColor))
+		fmt.Fprintf(bw, "%%%%CMYKCustomColor: %s (%s)\n", cmykComponents(layout.foreground), psString(opts.SpotColor))
+	}
+	fmt.Fprintf(bw, "%%%%LanguageLevel: 2\n")
+	fmt.Fprintf(bw, "%%%%Pages: 1\n")
+	fmt.Fprintf(bw, "%%%%EndComments\n")
+
+	// Procédures abrégées pour alléger le chemin
+	fmt.Fprintf(bw, "%%%%BeginProlog\n")
+	fmt.Fprintf(bw, "/m { moveto } bind def\n/l { lineto } bind def\n")
+	fmt.Fprintf(bw, "/r { moveto dup 0 rlineto 0 1 rlineto neg 0 rlineto closepath } bind def\n")
+	fmt.Fprintf(bw, "%%%%EndProlog\n")
+
+	// Repère en modules, origine en haut à gauche comme dans la matrice
+	fmt.Fprintf(bw, "%%%%Page: 1 1\ngsave\n")
+	fmt.Fprintf(bw, "0 %s translate %s %s scale\n",
+		formatNumber(layout.size), formatNumber(layout.module), formatNumber(-layout.module))
+
+	if bg := layout.background; bg != nil {
+		fmt.Fprintf(bw, "%s setcmykcolor\n0 0 %d %d rectfill\n", cmykComponents(*bg), layout.modules, layout.modules)
+	}
+
+	if opts.SpotColor != "" {
+		// Teinte t de la couleur d'accompagnement : t × la couleur de substitution en CMJN
+		c := layout.foreground
+		fmt.Fprintf(bw, "[/Separation (%s) /DeviceCMYK { dup %s mul exch dup %s mul exch dup %s mul exch %s mul }] setcolorspace 1 setcolor\n",
+			psString(opts.SpotColor),
+			formatNumber(float64(c.C)/255), formatNumber(float64(c.M)/255),
+			formatNumber(float64(c.Y)/255), formatNumber(float64(c.K)/255))
+	} else {
+		fmt.Fprintf(bw, "%s setcmykcolor\n", cmykComponents(layout.foreground))
+	}
+
+	// Un seul chemin rempli selon la règle nonzero
+	offset := opts.QuietZone
+	fmt.Fprintf(bw, "newpath\n")
+	if opts.Outline {
+		for _, loop := range outlineLoops(m) {
+			fmt.Fprintf(bw, "%d %d m", loop[0].X+offset, loop[0].Y+offset)
+			for _, p := range loop[1:] {
+				fmt.Fprintf(bw, " %d %d l", p.X+offset, p.Y+offset)
+			}
+			fmt.Fprintf(bw, " closepath\n")
+		}
+	} else {
+		for _, r := range darkRuns(m) {
+			fmt.Fprintf(bw, "%d %d %d r\n", r.length, r.x+offset, r.y+offset)
+		}
+	}
+	fmt.Fprintf(bw, "fill\ngrestore\nshowpage\n%%%%Trailer\n%%%%EOF\n")
+
+	return bw.Flush()
+}
+
+// psString échappe un texte pour une chaîne PostScript entre parenthèses
+func psString(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", `\r`, "\n", `\n`)
+	return replacer.Replace(s)
+}
+
+// dscText ramène un texte sur une seule ligne pour un commentaire DSC
+func dscText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
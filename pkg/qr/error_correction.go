@@ -2,6 +2,7 @@ package qr
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 )
 
@@ -27,7 +28,7 @@ func InitGaloisField() {
 		return
 	}
 
-	fmt.Println("Initialisation des tables de Galois...")
+	logf("Initialisation des tables de Galois...\n")
 
 	// Initialisation des tables de multiplication du champ de Galois
 	// IMPORTANT: Cette initialisation évite d'appeler GfMultiply de manière récursive
@@ -51,7 +52,7 @@ func InitGaloisField() {
 	}
 
 	gfInitialized = true
-	fmt.Println("Initialisation des tables de Galois terminée.")
+	logf("Initialisation des tables de Galois terminée.\n")
 }
 
 // GfMultiply effectue une multiplication dans le champ de Galois
@@ -65,7 +66,7 @@ func GfMultiply(x, y byte) byte {
 	if !gfInitialized {
 		// Au lieu d'appeler InitGaloisField() qui pourrait créer une récursion infinie,
 		// on retourne une valeur par défaut qui permet de continuer l'exécution
-		fmt.Println("ATTENTION: Tentative d'utiliser GfMultiply avant initialisation")
+		logf("ATTENTION: Tentative d'utiliser GfMultiply avant initialisation\n")
 		return 0
 	}
 
@@ -73,11 +74,14 @@ func GfMultiply(x, y byte) byte {
 	gfMutex.RLock()
 	defer gfMutex.RUnlock()
 
-	return gfExp[(gfLog[x]+gfLog[y])%255]
+	// La somme des logarithmes dépasse un octet : elle est calculée en entiers
+	return gfExp[(int(gfLog[x])+int(gfLog[y]))%255]
 }
 
 // GenerateReedSolomon génère les octets de correction d'erreur Reed-Solomon
+// d'un bloc, en initialisant au besoin le champ de Galois
 func GenerateReedSolomon(data []byte, numECBytes int) []byte {
+	InitGaloisField()
 	generator := generateGenerator(numECBytes)
 	remainder := make([]byte, numECBytes)
 
@@ -113,21 +117,20 @@ func GenerateErrorCorrection(data []byte, level string) []byte {
 	return GenerateReedSolomon(data, numECBytes)
 }
 
-// generateGenerator génère le polynôme générateur pour Reed-Solomon
+// generateGenerator retourne le polynôme générateur de degré degree, produit
+// des (x - α^i) pour i de 0 à degree-1 : ses coefficients par degrés
+// décroissants, sans le coefficient dominant qui vaut 1
 func generateGenerator(degree int) []byte {
-	generator := make([]byte, degree)
-	generator[0] = 1
-
+	generator := []byte{1}
 	for i := 0; i < degree; i++ {
-		for j := i; j >= 0; j-- {
-			generator[j] = GfMultiply(generator[j], byte(i+1))
-			if j > 0 {
-				generator[j] ^= generator[j-1]
-			}
+		next := make([]byte, len(generator)+1)
+		for j, c := range generator {
+			next[j] ^= c
+			next[j+1] ^= GfMultiply(c, gfExp[i])
 		}
+		generator = next
 	}
-
-	return generator
+	return generator[1:]
 }
 
 // Polynômes générateurs pour différents niveaux de correction d'erreur
@@ -138,34 +141,48 @@ var GeneratorPolynomials = map[string][]int{
 	"H": {1, 1, 1, 1, 1}, // 30% de correction
 }
 
-// AddErrorCorrectionEC ajoute les codes de correction d'erreur aux données
-// avec une implémentation plus robuste
+// AddErrorCorrectionEC découpe les mots de données en blocs Reed-Solomon selon
+// la version et le niveau (ECBlockCount, ECCodewordsPerBlock), calcule les mots
+// de correction de chaque bloc et les entrelace comme la norme ISO/IEC 18004 :
+// le i-ème mot de données de chaque bloc, les blocs courts précédant les blocs
+// longs, puis le i-ème mot de correction de chaque bloc. data contient les mots
+// de données de la version, remplissage compris.
 func AddErrorCorrectionEC(data string, ecLevel string, version int) string {
-	// Calculer le nombre de mots de code de correction d'erreur nécessaires
-	ecWords := calculateECWords(version, ecLevel)
-
 	// Convertir les données binaires en bytes
-	dataBytes := make([]byte, 0)
+	dataBytes := make([]byte, 0, len(data)/8)
 	for i := 0; i < len(data); i += 8 {
-		end := i + 8
-		if end > len(data) {
-			end = len(data)
-		}
-		byteVal := binaryStringToByte(data[i:end])
-		dataBytes = append(dataBytes, byteVal)
+		dataBytes = append(dataBytes, binaryStringToByte(data[i:min(i+8, len(data))]))
 	}
 
-	// Générer les mots de code de correction d'erreur
-	ecBytes := generateECBytes(dataBytes, ecWords)
-
-	// Convertir les bytes de correction d'erreur en binaire
-	var result string
-	result = data
-	for _, b := range ecBytes {
-		result += fmt.Sprintf("%08b", b)
+	// Seuls les derniers blocs ont un mot de données de plus
+	count, ecWords := ECBlockCount[ecLevel][version], ECCodewordsPerBlock[ecLevel][version]
+	short, long := len(dataBytes)/count, len(dataBytes)%count
+	blocks := make([][]byte, count)
+	ecBlocks := make([][]byte, count)
+	for b, offset := 0, 0; b < count; b++ {
+		n := short
+		if b >= count-long {
+			n++
+		}
+		blocks[b] = dataBytes[offset : offset+n]
+		ecBlocks[b] = GenerateReedSolomon(blocks[b], ecWords)
+		offset += n
 	}
 
-	return result
+	var result strings.Builder
+	for i := 0; i <= short; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				fmt.Fprintf(&result, "%08b", block[i])
+			}
+		}
+	}
+	for i := 0; i < ecWords; i++ {
+		for _, block := range ecBlocks {
+			fmt.Fprintf(&result, "%08b", block[i])
+		}
+	}
+	return result.String()
 }
 
 // Convertit une chaîne binaire en byte
@@ -178,38 +195,3 @@ func binaryStringToByte(binary string) byte {
 	}
 	return result
 }
-
-// Calcule le nombre de mots de code de correction d'erreur nécessaires
-func calculateECWords(version int, ecLevel string) int {
-	// Table simplifiée pour les versions 1-5
-	ecWordsTable := map[int]map[string]int{
-		1: {"L": 7, "M": 10, "Q": 13, "H": 17},
-		2: {"L": 10, "M": 16, "Q": 22, "H": 28},
-		3: {"L": 15, "M": 26, "Q": 36, "H": 44},
-		4: {"L": 20, "M": 36, "Q": 52, "H": 64},
-		5: {"L": 26, "M": 48, "Q": 72, "H": 88},
-	}
-
-	if words, ok := ecWordsTable[version][ecLevel]; ok {
-		return words
-	}
-	return 10 // Valeur par défaut pour version 1-M
-}
-
-// Génère les bytes de correction d'erreur
-func generateECBytes(data []byte, numECBytes int) []byte {
-	// Initialiser le tableau de correction d'erreur
-	ecBytes := make([]byte, numECBytes)
-
-	// Copier les données dans le tableau de correction d'erreur
-	for i := 0; i < len(data); i++ {
-		feedback := data[i]
-		for j := 0; j < len(ecBytes); j++ {
-			temp := ecBytes[j]
-			ecBytes[j] = byte(int(feedback) ^ int(temp))
-			feedback = temp
-		}
-	}
-
-	return ecBytes
-}
@@ -0,0 +1,182 @@
+package qr
+
+import (
+	"slices"
+	"testing"
+)
+
+// rsSyndromes évalue le bloc, mot de poids fort en tête, en α^0 à α^(ecWords-1)
+func rsSyndromes(block []byte, ecWords int) []byte {
+	syndromes := make([]byte, ecWords)
+	for i := range syndromes {
+		for _, c := range block {
+			syndromes[i] = GfMultiply(syndromes[i], gfExp[i]) ^ c
+		}
+	}
+	return syndromes
+}
+
+// rsEval évalue un polynôme aux coefficients par degrés croissants
+func rsEval(poly []byte, x byte) byte {
+	var y byte
+	for i := len(poly) - 1; i >= 0; i-- {
+		y = GfMultiply(y, x) ^ poly[i]
+	}
+	return y
+}
+
+// rsDiv divise dans le champ de Galois, b non nul
+func rsDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[(int(gfLog[a])-int(gfLog[b])+255)%255]
+}
+
+// rsCorrect corrige sur place un bloc Reed-Solomon, données puis ecWords mots
+// de correction, comme un lecteur qui ignore où sont les erreurs
+// (Berlekamp-Massey, Chien, Forney). Retourne le nombre de mots corrigés ; ok
+// est faux si le bloc est au-delà de la capacité de correction.
+func rsCorrect(block []byte, ecWords int) (corrected int, ok bool) {
+	InitGaloisField()
+	syndromes := rsSyndromes(block, ecWords)
+	if !slices.ContainsFunc(syndromes, func(s byte) bool { return s != 0 }) {
+		return 0, true
+	}
+
+	// Polynôme localisateur, par degrés croissants
+	locator, previous := []byte{1}, []byte{1}
+	errors, shift, last := 0, 1, byte(1)
+	for n := 0; n < ecWords; n++ {
+		d := syndromes[n]
+		for i := 1; i <= errors && i < len(locator); i++ {
+			d ^= GfMultiply(locator[i], syndromes[n-i])
+		}
+		if d == 0 {
+			shift++
+			continue
+		}
+		saved := slices.Clone(locator)
+		for len(locator) < len(previous)+shift {
+			locator = append(locator, 0)
+		}
+		coef := rsDiv(d, last)
+		for i, c := range previous {
+			locator[i+shift] ^= GfMultiply(coef, c)
+		}
+		if 2*errors <= n {
+			errors, previous, last, shift = n+1-errors, saved, d, 1
+		} else {
+			shift++
+		}
+	}
+	if 2*errors > ecWords {
+		return 0, false
+	}
+
+	// Évaluateur Ω = S·Λ mod x^ecWords et dérivée formelle de Λ
+	evaluator := make([]byte, ecWords)
+	for i, s := range syndromes {
+		for j, l := range locator {
+			if i+j < ecWords {
+				evaluator[i+j] ^= GfMultiply(s, l)
+			}
+		}
+	}
+	derivative := make([]byte, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+
+	// Le mot d'indice j porte la puissance p = len(block)-1-j
+	for p := 0; p < len(block); p++ {
+		inverse := gfExp[(255-p%255)%255]
+		if rsEval(locator, inverse) != 0 {
+			continue
+		}
+		magnitude := GfMultiply(gfExp[p%255], rsDiv(rsEval(evaluator, inverse), rsEval(derivative, inverse)))
+		block[len(block)-1-p] ^= magnitude
+		corrected++
+	}
+	if corrected != errors || slices.ContainsFunc(rsSyndromes(block, ecWords), func(s byte) bool { return s != 0 }) {
+		return 0, false
+	}
+	return corrected, true
+}
+
+func TestGenerateReedSolomon(t *testing.T) {
+	// Exemple « HELLO WORLD » en version 1-M : 16 mots de données, 10 de correction
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := GenerateReedSolomon(data, len(want)); !slices.Equal(got, want) {
+		t.Errorf("GenerateReedSolomon() = %v, attendu %v", got, want)
+	}
+
+	// Le décodeur des tests restaure jusqu'à la moitié des mots de correction
+	block := append(slices.Clone(data), want...)
+	tests := []struct {
+		name      string
+		positions []int
+		wantOK    bool
+	}{
+		{"Sans erreur", nil, true},
+		{"Une donnée", []int{3}, true},
+		{"Données et correction", []int{0, 7, 15, 16, 25}, true},
+		{"Au-delà de la capacité", []int{0, 2, 4, 6, 8, 10, 12, 14, 16, 18, 20}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			damaged := slices.Clone(block)
+			for _, p := range tt.positions {
+				damaged[p] ^= 0x5A
+			}
+			corrected, ok := rsCorrect(damaged, len(want))
+			if ok != tt.wantOK {
+				t.Fatalf("rsCorrect() ok = %v, attendu %v", ok, tt.wantOK)
+			}
+			if ok && (corrected != len(tt.positions) || !slices.Equal(damaged, block)) {
+				t.Errorf("%d mots corrigés, bloc %v, attendu %v", corrected, damaged, block)
+			}
+		})
+	}
+}
+
+func TestAddErrorCorrectionInterleaving(t *testing.T) {
+	// Version 5-Q : deux blocs de 15 mots de données puis deux de 16, 18 mots de correction chacun
+	data := make([]byte, dataCodewords(5, "Q"))
+	var bits []byte
+	for i := range data {
+		data[i] = byte(i)
+		bits = append(bits, []byte(byteBits(data[i]))...)
+	}
+	got := AddErrorCorrectionEC(string(bits), "Q", 5)
+	if len(got) != totalCodewords(5)*8 {
+		t.Fatalf("%d bits, attendu %d", len(got), totalCodewords(5)*8)
+	}
+	codeword := func(i int) byte { return binaryStringToByte(got[8*i : 8*i+8]) }
+
+	// Premiers mots de chaque bloc, puis le mot de plus des blocs longs
+	for i, want := range []byte{0, 15, 30, 46, 1, 16, 31, 47} {
+		if c := codeword(i); c != want {
+			t.Errorf("mot %d = %d, attendu %d", i, c, want)
+		}
+	}
+	if c0, c1 := codeword(60), codeword(61); c0 != 45 || c1 != 61 {
+		t.Errorf("mots 60 et 61 = %d, %d, attendu les derniers mots des blocs longs", c0, c1)
+	}
+	firstBlock := GenerateReedSolomon(data[:15], 18)
+	for i := 0; i < 18; i++ {
+		if c := codeword(62 + 4*i); c != firstBlock[i] {
+			t.Errorf("mot %d = %d, attendu le mot de correction %d du premier bloc", 62+4*i, c, i)
+		}
+	}
+}
+
+// byteBits retourne les 8 bits d'un octet, poids fort en tête
+func byteBits(b byte) string {
+	bits := make([]byte, 8)
+	for i := range bits {
+		bits[i] = '0' + (b>>(7-i))&1
+	}
+	return string(bits)
+}
@@ -0,0 +1,164 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+)
+
+// Formes du cadre et de la pupille des motifs de repérage (« yeux »)
+const (
+	EyeSquare  = "square"
+	EyeRounded = "rounded"
+	EyeCircle  = "circle"
+	EyeLeaf    = "leaf"
+)
+
+// Dimensions d'un œil en modules : cadre de 7, évidement de 5, pupille de 3
+const (
+	eyeSize   = 7
+	eyeHole   = 5
+	pupilSize = 3
+)
+
+// EyeShapes retourne la liste des formes d'yeux disponibles
+func EyeShapes() []string {
+	return []string{EyeSquare, EyeRounded, EyeCircle, EyeLeaf}
+}
+
+// eyeStyle décrit le dessin des trois motifs de repérage, substitué à leurs
+// modules au moment du rendu ; la matrice elle-même n'est pas modifiée
+type eyeStyle struct {
+	frameShape, pupilShape string
+	frameColor, pupilColor color.NRGBA
+}
+
+// eyesStyled indique si les options personnalisent le dessin des yeux
+func (opts RenderOptions) eyesStyled() bool {
+	return opts.EyeFrameShape != "" || opts.EyePupilShape != "" || opts.EyeFrameColor != nil || opts.EyePupilColor != nil ||
+		len(opts.EyeColors) > 0
+}
+
+// newEyeStyle valide le style des yeux ; il retourne nil si les yeux gardent
+// le dessin standard des modules
+func newEyeStyle(opts RenderOptions) (*eyeStyle, error) {
+	if !opts.eyesStyled() {
+		return nil, nil
+	}
+
+	fg := toNRGBA(opts.Foreground, color.Black)
+	e := &eyeStyle{
+		frameShape: strings.ToLower(opts.EyeFrameShape),
+		pupilShape: strings.ToLower(opts.EyePupilShape),
+		frameColor: toNRGBA(opts.EyeFrameColor, fg),
+		pupilColor: toNRGBA(opts.EyePupilColor, fg),
+	}
+	for _, shape := range []*string{&e.frameShape, &e.pupilShape} {
+		if *shape == "" {
+			*shape = EyeSquare
+		}
+		if !slices.Contains(EyeShapes(), *shape) {
+			return nil, fmt.Errorf("forme d'œil inconnue : %q (attendu %s)", *shape, strings.Join(EyeShapes(), ", "))
+		}
+	}
+	return e, nil
+}
+
+// square indique si les yeux sont dessinés avec des angles droits
+func (e *eyeStyle) square() bool {
+	return e == nil || (e.frameShape == EyeSquare && e.pupilShape == EyeSquare)
+}
+
+// eyeOrigins retourne le coin haut gauche des trois motifs de repérage :
+// haut gauche, haut droite, bas gauche
+func eyeOrigins(size int) [3]image.Point {
+	return [3]image.Point{{0, 0}, {size - eyeSize, 0}, {0, size - eyeSize}}
+}
+
+// eyeGeometry retourne la forme d'un carré de côté size, en retrait de inset
+// modules dans l'œil numéro eye. La feuille arrondit deux coins opposés, en
+// miroir pour les yeux de droite et du bas afin de rester symétrique.
+func eyeGeometry(shape string, eye int, inset, size float64) moduleGeometry {
+	g := moduleGeometry{x0: inset, y0: inset, x1: inset + size, y1: inset + size}
+	switch shape {
+	case EyeRounded:
+		r := size * 2 / eyeSize
+		g.radii = [4]float64{r, r, r, r}
+	case EyeCircle:
+		r := size / 2
+		g.radii = [4]float64{r, r, r, r}
+	case EyeLeaf:
+		r := size / 2
+		if eye == 0 {
+			g.radii = [4]float64{r, 0, r, 0}
+		} else {
+			g.radii = [4]float64{0, r, 0, r}
+		}
+	}
+	return g
+}
+
+// frame retourne les contours extérieur et intérieur du cadre de l'œil numéro eye
+func (e *eyeStyle) frame(eye int) (outer, inner moduleGeometry) {
+	return eyeGeometry(e.frameShape, eye, 0, eyeSize), eyeGeometry(e.frameShape, eye, 1, eyeHole)
+}
+
+// pupil retourne la forme de la pupille de l'œil numéro eye
+func (e *eyeStyle) pupil(eye int) moduleGeometry {
+	return eyeGeometry(e.pupilShape, eye, 2, pupilSize)
+}
+
+// withoutEyes retourne une copie de la matrice dont les motifs de repérage sont
+// effacés, pour être dessinés à part
+func withoutEyes(m *Matrix) *Matrix {
+	out := m.Clone()
+	for _, o := range eyeOrigins(m.size) {
+		for y := o.Y; y < o.Y+eyeSize; y++ {
+			for x := o.X; x < o.X+eyeSize; x++ {
+				out.Set(x, y, false)
+			}
+		}
+	}
+	return out
+}
+
+// drawEyes dessine le cadre et la pupille des trois yeux, anticrénelés, avec
+// les encres 1 (cadre) et 2 (pupille) de la palette
+func drawEyes(img *image.Paletted, e *eyeStyle, size, origin, module int) {
+	step := 1 / float64(module*shapeSamples)
+	for i, o := range eyeOrigins(size) {
+		outer, inner := e.frame(i)
+		pupil := e.pupil(i)
+
+		left, top := origin+o.X*module, origin+o.Y*module
+		for py := 0; py < eyeSize*module; py++ {
+			for px := 0; px < eyeSize*module; px++ {
+				var frameCoverage, pupilCoverage uint8
+				for sy := 0; sy < shapeSamples; sy++ {
+					for sx := 0; sx < shapeSamples; sx++ {
+						// Coordonnées en modules dans l'œil
+						x := (float64(px*shapeSamples+sx) + 0.5) * step
+						y := (float64(py*shapeSamples+sy) + 0.5) * step
+						switch {
+						case pupil.contains(x, y):
+							pupilCoverage++
+						case outer.contains(x, y) && !inner.contains(x, y):
+							frameCoverage++
+						}
+					}
+				}
+
+				// Cadre et pupille sont séparés d'un module : un pixel ne touche que l'un des deux
+				index := shadeIndex(0, 0)
+				if frameCoverage > 0 {
+					index = shadeIndex(1, frameCoverage)
+				} else if pupilCoverage > 0 {
+					index = shadeIndex(2, pupilCoverage)
+				}
+				img.Pix[(top+py)*img.Stride+left+px] = index
+			}
+		}
+	}
+}
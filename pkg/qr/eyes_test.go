@@ -0,0 +1,142 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestNewEyeStyle(t *testing.T) {
+	red := color.NRGBA{0xc0, 0x39, 0x2b, 0xff}
+	navy := color.NRGBA{0x00, 0x00, 0x80, 0xff}
+
+	tests := []struct {
+		name    string
+		opts    RenderOptions
+		want    *eyeStyle
+		wantErr bool
+	}{
+		{"Yeux standard", RenderOptions{}, nil, false},
+		{"Forme seule, couleur des modules", RenderOptions{Foreground: navy, EyeFrameShape: "Circle"},
+			&eyeStyle{frameShape: EyeCircle, pupilShape: EyeSquare, frameColor: navy, pupilColor: navy}, false},
+		{"Couleurs seules", RenderOptions{EyeFrameColor: red, EyePupilColor: navy},
+			&eyeStyle{frameShape: EyeSquare, pupilShape: EyeSquare, frameColor: red, pupilColor: navy}, false},
+		{"Forme inconnue", RenderOptions{EyePupilShape: "star"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newEyeStyle(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newEyeStyle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("newEyeStyle() = %+v, attendu %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithoutEyes(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	before := m.Clone()
+	cleared := withoutEyes(m)
+
+	size := m.Size()
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if m.Get(x, y) != before.Get(x, y) {
+				t.Fatalf("la matrice d'origine a été modifiée en (%d,%d)", x, y)
+			}
+			eye := (x < eyeSize && y < eyeSize) || (x >= size-eyeSize && y < eyeSize) || (x < eyeSize && y >= size-eyeSize)
+			if want := m.Get(x, y) && !eye; cleared.Get(x, y) != want {
+				t.Fatalf("module (%d,%d) = %v, attendu %v", x, y, cleared.Get(x, y), want)
+			}
+		}
+	}
+}
+
+func TestRasterizeEyes(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	red := color.NRGBA{0xc0, 0x39, 0x2b, 0xff}
+	navy := color.NRGBA{0x00, 0x00, 0x80, 0xff}
+	opts := RenderOptions{QuietZone: 4, Scale: 10, EyeFrameShape: EyeCircle, EyePupilShape: EyeLeaf, EyeFrameColor: red, EyePupilColor: navy}
+
+	img, err := rasterize(m, opts)
+	if err != nil {
+		t.Fatalf("rasterize() error = %v", err)
+	}
+
+	at := func(x, y float64) color.NRGBA {
+		return color.NRGBAModel.Convert(img.At(int(x*10), int(y*10))).(color.NRGBA)
+	}
+	white := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	for i, o := range eyeOrigins(m.Size()) {
+		ox, oy := float64(o.X+4), float64(o.Y+4)
+		checks := []struct {
+			name string
+			x, y float64
+			want color.NRGBA
+		}{
+			{"milieu du bord du cadre", ox + 3.5, oy + 0.5, red},
+			{"coin du cadre circulaire", ox + 0.1, oy + 0.1, white},
+			{"évidement", ox + 1.5, oy + 3.5, white},
+			{"centre de la pupille", ox + 3.5, oy + 3.5, navy},
+		}
+		for _, c := range checks {
+			if got := at(c.x, c.y); got != c.want {
+				t.Errorf("œil %d, %s : %v, attendu %v", i, c.name, got, c.want)
+			}
+		}
+	}
+
+	// Les modules hors des yeux gardent la couleur par défaut
+	for y := 0; y < m.Size(); y++ {
+		for x := 0; x < m.Size(); x++ {
+			if x < 8 && y < 8 || x >= m.Size()-8 && y < 8 || x < 8 && y >= m.Size()-8 {
+				continue
+			}
+			want := white
+			if m.Get(x, y) {
+				want = color.NRGBA{0, 0, 0, 0xff}
+			}
+			if got := at(float64(x+4)+0.5, float64(y+4)+0.5); got != want {
+				t.Fatalf("module (%d,%d) = %v, attendu %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestRenderSVGEyes(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	opts := RenderOptions{QuietZone: 4, EyeFrameShape: EyeRounded, EyeFrameColor: color.NRGBA{0xc0, 0x39, 0x2b, 0xff}}
+
+	var buf bytes.Buffer
+	if err := RenderSVG(&buf, m, opts); err != nil {
+		t.Fatalf("RenderSVG() error = %v", err)
+	}
+	doc := buf.String()
+
+	if !strings.Contains(doc, `<path fill="#c0392b" fill-rule="evenodd" d="`) {
+		t.Error("chemin des cadres absent ou sans règle pair-impair")
+	}
+	paths := svgPathAttr.FindAllStringSubmatch(doc, -1)
+	if len(paths) != 3 {
+		t.Fatalf("%d chemins, attendu modules, cadres et pupilles", len(paths))
+	}
+	// Trois cadres de deux contours et trois pupilles
+	if got := strings.Count(paths[1][1], "z"); got != 6 {
+		t.Errorf("%d contours de cadre, attendu 6", got)
+	}
+	if got := strings.Count(paths[2][1], "z"); got != 3 {
+		t.Errorf("%d pupilles, attendu 3", got)
+	}
+	if strings.Contains(doc, "crispEdges") {
+		t.Error("des cadres arrondis ne doivent pas être rendus en crispEdges")
+	}
+
+	// Les modules restants correspondent à la matrice sans les yeux
+	polygons := parseSVGPath(t, paths[0][1])
+	assertSameModules(t, rasterizePolygons(t, polygons, m.Size(), 4), withoutEyes(m))
+}
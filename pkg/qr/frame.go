@@ -0,0 +1,478 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// Modèles de cadre autour du symbole
+const (
+	// Légende seule, sans bordure
+	FrameCaption = "caption"
+
+	// Bordure à angles droits, légende à l'intérieur
+	FrameBorder = "border"
+
+	// Bordure à coins arrondis, légende à l'intérieur
+	FrameRounded = "rounded"
+
+	// Bordure arrondie prolongée par un bandeau plein où la légende est évidée
+	FrameBanner = "banner"
+)
+
+// Position de la légende par rapport au symbole
+const (
+	CaptionBottom = "bottom"
+	CaptionTop    = "top"
+)
+
+// DefaultCaptionSize est la taille de la légende (corps de la police), en modules
+const DefaultCaptionSize = 3
+
+// DefaultBannerText est la légende du modèle FrameBanner si aucune n'est donnée
+const DefaultBannerText = "SCAN ME"
+
+// frameRadius est le rayon des coins des modèles arrondis, en modules
+const frameRadius = 2
+
+// captionMargin est l'espace au-dessus et au-dessous de la légende, en fraction du corps
+const captionMargin = 0.25
+
+// arcKappa place les points de contrôle d'une courbe de Bézier cubique
+// approchant un quart de cercle
+const arcKappa = 0.5522847498
+
+// FrameTemplates retourne la liste des modèles de cadre disponibles
+func FrameTemplates() []string {
+	return []string{FrameCaption, FrameBorder, FrameRounded, FrameBanner}
+}
+
+// Frame décrit un cadre dessiné autour du symbole, zone calme comprise : une
+// bordure et une légende au-dessus ou au-dessous. Les dimensions sont en
+// modules pour suivre l'échelle du symbole ; une valeur nulle prend celle du
+// modèle.
+type Frame struct {
+	// Modèle parmi FrameTemplates (FrameCaption s'il est vide)
+	Template string
+
+	// Légende ; DefaultBannerText pour FrameBanner si elle est vide
+	Text string
+
+	// CaptionBottom ou CaptionTop (CaptionBottom si elle est vide)
+	Position string
+
+	// Police TrueType ou OpenType de la légende ; Go Regular, ou Go Bold pour
+	// FrameBanner, si elle est nulle
+	Font []byte
+
+	// Corps de la légende en modules (DefaultCaptionSize s'il est nul), réduit
+	// si le texte est plus large que le cadre
+	TextSize float64
+
+	// Couleur de la bordure et du bandeau (Foreground si elle n'est pas définie)
+	Color color.Color
+
+	// Couleur de la légende : Color par défaut ; pour FrameBanner, la légende
+	// est évidée dans le bandeau si elle n'est pas définie
+	TextColor color.Color
+
+	// Épaisseur de la bordure, rayon de ses coins extérieurs et marge entre la
+	// zone calme et la bordure, en modules
+	Border  float64
+	Radius  float64
+	Padding float64
+}
+
+// frameLayout est un cadre validé, en modules depuis le coin haut gauche de
+// l'image ; le symbole, zone calme comprise, occupe un carré de côté symbol
+type frameLayout struct {
+	width, height    float64
+	symbolX, symbolY float64
+	symbol           float64
+
+	// Bordure et bandeau, légende
+	shapes, text outline
+
+	// La légende est évidée dans le bandeau plutôt que dessinée
+	knockout bool
+
+	color, textColor color.NRGBA
+}
+
+// Validate vérifie le modèle, la position de la légende et les dimensions du
+// cadre avant tout rendu, avec les mêmes erreurs que le rendu
+func (f *Frame) Validate() error {
+	_, _, err := f.resolve()
+	return err
+}
+
+// resolve retourne le modèle et la position de la légende du cadre, valeurs
+// par défaut appliquées, après avoir vérifié ses dimensions
+func (f *Frame) resolve() (template, position string, err error) {
+	template = strings.ToLower(f.Template)
+	if template == "" {
+		template = FrameCaption
+	}
+	if !slices.Contains(FrameTemplates(), template) {
+		return "", "", fmt.Errorf("modèle de cadre inconnu : %q (attendu %s)", f.Template, strings.Join(FrameTemplates(), ", "))
+	}
+	position = strings.ToLower(f.Position)
+	if position == "" {
+		position = CaptionBottom
+	}
+	if position != CaptionBottom && position != CaptionTop {
+		return "", "", fmt.Errorf("position de légende inconnue : %q (attendu %s ou %s)", f.Position, CaptionBottom, CaptionTop)
+	}
+	if f.Border < 0 || f.Radius < 0 || f.Padding < 0 || f.TextSize < 0 {
+		return "", "", fmt.Errorf("dimensions de cadre invalides : bordure %g, rayon %g, marge %g, corps %g",
+			f.Border, f.Radius, f.Padding, f.TextSize)
+	}
+	return template, position, nil
+}
+
+// newFrameLayout valide le cadre et le dispose autour d'un symbole de total
+// modules, zone calme comprise ; il retourne nil sans cadre
+func newFrameLayout(f *Frame, total int, opts RenderOptions) (*frameLayout, error) {
+	if f == nil {
+		return nil, nil
+	}
+	template, position, err := f.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	border, radius, size := f.dimensions()
+	text := f.Text
+	if text == "" && template == FrameBanner {
+		text = DefaultBannerText
+	}
+
+	fontData := f.Font
+	if fontData == nil {
+		fontData = goregular.TTF
+		if template == FrameBanner {
+			fontData = gobold.TTF
+		}
+	}
+	face, err := sfnt.Parse(fontData)
+	if err != nil {
+		return nil, fmt.Errorf("police de légende illisible : %v", err)
+	}
+
+	fg := toNRGBA(opts.Foreground, color.Black)
+	l := &frameLayout{
+		symbol:    float64(total),
+		knockout:  template == FrameBanner && f.TextColor == nil,
+		color:     toNRGBA(f.Color, fg),
+		textColor: toNRGBA(f.TextColor, toNRGBA(f.Color, fg)),
+	}
+	l.width = l.symbol + 2*(f.Padding+border)
+	inner := l.width - 2*border
+
+	// Bande de la légende, le corps étant réduit pour que le texte tienne
+	var band, ascent float64
+	var glyphs outline
+	if text != "" {
+		width, err := measureText(face, text, size)
+		if err != nil {
+			return nil, err
+		}
+		if available := inner - 2*captionMargin*size; width > available {
+			size *= available / width
+			width = available
+		}
+		metrics, err := face.Metrics(nil, fontPPEM(face), font.HintingNone)
+		if err != nil {
+			return nil, fmt.Errorf("police de légende illisible : %v", err)
+		}
+		unit := size / float64(face.UnitsPerEm())
+		ascent = float64(metrics.Ascent) / 64 * unit
+		band = ascent + float64(metrics.Descent)/64*unit + 2*captionMargin*size
+
+		bandTop := border + 2*f.Padding + l.symbol
+		if position == CaptionTop {
+			bandTop = border
+		}
+		if err := glyphs.text(face, text, size, (l.width-width)/2, bandTop+captionMargin*size+ascent); err != nil {
+			return nil, err
+		}
+		if template == FrameBanner {
+			rin := math.Max(radius-border, 0)
+			radii := [4]float64{0, 0, rin, rin}
+			if position == CaptionTop {
+				radii = [4]float64{rin, rin, 0, 0}
+			}
+			l.shapes.roundedRect(moduleGeometry{x0: border, y0: bandTop, x1: l.width - border, y1: bandTop + band, radii: radii}, false)
+		}
+	}
+	l.height = l.width + band
+	l.text = glyphs
+
+	l.symbolX, l.symbolY = border+f.Padding, border+f.Padding
+	if position == CaptionTop {
+		l.symbolY += band
+	}
+
+	if radius > math.Min(l.width, l.height)/2 {
+		return nil, fmt.Errorf("rayon de cadre trop grand : %g modules pour un cadre de %g × %g", radius, l.width, l.height)
+	}
+	if border > 0 {
+		r, rin := radius, math.Max(radius-border, 0)
+		l.shapes.roundedRect(moduleGeometry{x1: l.width, y1: l.height, radii: [4]float64{r, r, r, r}}, false)
+		// L'intérieur, parcouru en sens inverse, évide la bordure
+		l.shapes.roundedRect(moduleGeometry{x0: border, y0: border, x1: l.width - border, y1: l.height - border,
+			radii: [4]float64{rin, rin, rin, rin}}, true)
+	}
+	return l, nil
+}
+
+// dimensions retourne l'épaisseur de la bordure, le rayon de ses coins et le
+// corps de la légende, les valeurs nulles prenant celles du modèle
+func (f *Frame) dimensions() (border, radius, size float64) {
+	template := strings.ToLower(f.Template)
+	border, radius, size = f.Border, f.Radius, f.TextSize
+	if border == 0 && template != "" && template != FrameCaption {
+		border = 1
+	}
+	if radius == 0 && (template == FrameRounded || template == FrameBanner) {
+		radius = frameRadius
+	}
+	if size == 0 {
+		size = DefaultCaptionSize
+	}
+	return border, radius, size
+}
+
+// fontPPEM retourne le corps de chargement des glyphes : une unité de la
+// police par pixel, pour garder toute sa précision
+func fontPPEM(f *sfnt.Font) fixed.Int26_6 {
+	return fixed.Int26_6(f.UnitsPerEm()) << 6
+}
+
+// measureText retourne la largeur du texte au corps size
+func measureText(f *sfnt.Font, text string, size float64) (float64, error) {
+	var buf sfnt.Buffer
+	ppem := fontPPEM(f)
+	var width fixed.Int26_6
+	prev := sfnt.GlyphIndex(0)
+	for i, r := range []rune(text) {
+		index, err := f.GlyphIndex(&buf, r)
+		if err != nil {
+			return 0, fmt.Errorf("caractère %q absent de la police : %v", r, err)
+		}
+		if i > 0 {
+			// Sans table de crénage, Kern retourne une erreur : l'écart reste nul
+			if kern, err := f.Kern(&buf, prev, index, ppem, font.HintingNone); err == nil {
+				width += kern
+			}
+		}
+		advance, err := f.GlyphAdvance(&buf, index, ppem, font.HintingNone)
+		if err != nil {
+			return 0, fmt.Errorf("caractère %q absent de la police : %v", r, err)
+		}
+		width += advance
+		prev = index
+	}
+	return float64(width) / 64 * size / float64(f.UnitsPerEm()), nil
+}
+
+// pathSegment est un élément de contour : 'M' (déplacement), 'L' (segment),
+// 'Q' et 'C' (courbes de Bézier quadratique et cubique), 'Z' (fermeture)
+type pathSegment struct {
+	op  byte
+	pts []float64
+}
+
+// outline est un ensemble de contours en modules, tracé à l'identique en SVG
+// et dans les images
+type outline []pathSegment
+
+// add ajoute un élément au contour
+func (o *outline) add(op byte, pts ...float64) {
+	*o = append(*o, pathSegment{op: op, pts: pts})
+}
+
+// roundedRect ajoute le rectangle g à coins arrondis, dans le sens horaire ou,
+// avec reverse, dans le sens inverse
+func (o *outline) roundedRect(g moduleGeometry, reverse bool) {
+	r := g.radii
+	// Coins dans le sens horaire depuis le haut gauche : sommet, début et fin de l'arc
+	corners := [4][6]float64{
+		{g.x0, g.y0, g.x0, g.y0 + r[0], g.x0 + r[0], g.y0},
+		{g.x1, g.y0, g.x1 - r[1], g.y0, g.x1, g.y0 + r[1]},
+		{g.x1, g.y1, g.x1, g.y1 - r[2], g.x1 - r[2], g.y1},
+		{g.x0, g.y1, g.x0 + r[3], g.y1, g.x0, g.y1 - r[3]},
+	}
+	order := []int{0, 1, 2, 3}
+	if reverse {
+		order = []int{0, 3, 2, 1}
+	}
+	for i, k := range order {
+		c := corners[k]
+		from, to := [2]float64{c[2], c[3]}, [2]float64{c[4], c[5]}
+		if reverse {
+			from, to = to, from
+		}
+		if i == 0 {
+			o.add('M', from[0], from[1])
+		} else {
+			o.add('L', from[0], from[1])
+		}
+		if from != to {
+			o.add('C', from[0]+arcKappa*(c[0]-from[0]), from[1]+arcKappa*(c[1]-from[1]),
+				to[0]+arcKappa*(c[0]-to[0]), to[1]+arcKappa*(c[1]-to[1]), to[0], to[1])
+		}
+	}
+	o.add('Z')
+}
+
+// text ajoute les contours des glyphes du texte au corps size, la ligne de base
+// commençant en (x, baseline)
+func (o *outline) text(f *sfnt.Font, text string, size, x, baseline float64) error {
+	var buf sfnt.Buffer
+	ppem := fontPPEM(f)
+	unit := size / float64(f.UnitsPerEm()) / 64
+	pen := x
+	prev := sfnt.GlyphIndex(0)
+	for i, r := range []rune(text) {
+		index, err := f.GlyphIndex(&buf, r)
+		if err != nil {
+			return fmt.Errorf("caractère %q absent de la police : %v", r, err)
+		}
+		if i > 0 {
+			if kern, err := f.Kern(&buf, prev, index, ppem, font.HintingNone); err == nil {
+				pen += float64(kern) * unit
+			}
+		}
+		segments, err := f.LoadGlyph(&buf, index, ppem, nil)
+		if err != nil {
+			return fmt.Errorf("glyphe de %q illisible : %v", r, err)
+		}
+		open := false
+		for _, s := range segments {
+			var pts []float64
+			for _, p := range s.Args[:segmentPoints(s.Op)] {
+				pts = append(pts, pen+float64(p.X)*unit, baseline+float64(p.Y)*unit)
+			}
+			switch s.Op {
+			case sfnt.SegmentOpMoveTo:
+				if open {
+					o.add('Z')
+				}
+				o.add('M', pts...)
+				open = true
+			case sfnt.SegmentOpLineTo:
+				o.add('L', pts...)
+			case sfnt.SegmentOpQuadTo:
+				o.add('Q', pts...)
+			case sfnt.SegmentOpCubeTo:
+				o.add('C', pts...)
+			}
+		}
+		if open {
+			o.add('Z')
+		}
+
+		advance, err := f.GlyphAdvance(&buf, index, ppem, font.HintingNone)
+		if err != nil {
+			return fmt.Errorf("caractère %q absent de la police : %v", r, err)
+		}
+		pen += float64(advance) * unit
+		prev = index
+	}
+	return nil
+}
+
+// segmentPoints retourne le nombre de points d'un élément de glyphe
+func segmentPoints(op sfnt.SegmentOp) int {
+	switch op {
+	case sfnt.SegmentOpQuadTo:
+		return 2
+	case sfnt.SegmentOpCubeTo:
+		return 3
+	default:
+		return 1
+	}
+}
+
+// svg retourne le contour en données de chemin SVG
+func (o outline) svg() string {
+	var d strings.Builder
+	for _, s := range o {
+		d.WriteByte(s.op)
+		for i, v := range s.pts {
+			if i > 0 {
+				d.WriteByte(' ')
+			}
+			d.WriteString(formatNumber(v))
+		}
+	}
+	return d.String()
+}
+
+// mask rastérise le contour, anticrénelé, dans un masque de la taille de r ;
+// scale est le nombre de pixels par module
+func (o outline) mask(r image.Rectangle, scale float64) *image.Alpha {
+	z := vector.NewRasterizer(r.Dx(), r.Dy())
+	p := func(i int, pts []float64) (float32, float32) {
+		return float32(pts[2*i] * scale), float32(pts[2*i+1] * scale)
+	}
+	for _, s := range o {
+		switch s.op {
+		case 'M':
+			z.MoveTo(p(0, s.pts))
+		case 'L':
+			z.LineTo(p(0, s.pts))
+		case 'Q':
+			x1, y1 := p(0, s.pts)
+			x2, y2 := p(1, s.pts)
+			z.QuadTo(x1, y1, x2, y2)
+		case 'C':
+			x1, y1 := p(0, s.pts)
+			x2, y2 := p(1, s.pts)
+			x3, y3 := p(2, s.pts)
+			z.CubeTo(x1, y1, x2, y2, x3, y3)
+		case 'Z':
+			z.ClosePath()
+		}
+	}
+	dst := image.NewAlpha(r)
+	z.Draw(dst, r, image.Opaque, image.Point{})
+	return dst
+}
+
+// draw compose le cadre autour de l'image du symbole, dont le côté en pixels
+// fixe l'échelle
+func (l *frameLayout) draw(img image.Image, opts RenderOptions) *image.NRGBA {
+	scale := float64(img.Bounds().Dx()) / l.symbol
+	rect := image.Rect(0, 0, int(math.Round(l.width*scale)), int(math.Round(l.height*scale)))
+
+	out := image.NewNRGBA(rect)
+	draw.Draw(out, rect, image.NewUniform(toNRGBA(opts.Background, color.White)), image.Point{}, draw.Src)
+	at := image.Pt(int(math.Round(l.symbolX*scale)), int(math.Round(l.symbolY*scale)))
+	draw.Draw(out, img.Bounds().Sub(img.Bounds().Min).Add(at), img, img.Bounds().Min, draw.Src)
+
+	shapes, text := l.shapes.mask(rect, scale), l.text.mask(rect, scale)
+	if l.knockout {
+		for i, a := range text.Pix {
+			shapes.Pix[i] = uint8(uint32(shapes.Pix[i]) * uint32(0xFF-a) / 0xFF)
+		}
+	}
+	draw.DrawMask(out, rect, image.NewUniform(l.color), image.Point{}, shapes, image.Point{}, draw.Over)
+	if !l.knockout {
+		draw.DrawMask(out, rect, image.NewUniform(l.textColor), image.Point{}, text, image.Point{}, draw.Over)
+	}
+	return out
+}
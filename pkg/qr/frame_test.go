@@ -0,0 +1,224 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestNewFrameLayout(t *testing.T) {
+	tests := []struct {
+		name         string
+		frame        Frame
+		wantWidth    float64
+		wantSymbolY  float64
+		wantText     bool
+		wantKnockout bool
+		wantErr      bool
+	}{
+		{"Légende seule", Frame{Text: "Scannez-moi"}, 33, 0, true, false, false},
+		{"Bordure sans légende", Frame{Template: FrameBorder}, 35, 1, false, false, false},
+		{"Légende au-dessus, marge", Frame{Template: "Rounded", Text: "Menu", Position: "TOP", Padding: 1}, 37, 2, true, false, false},
+		{"Bandeau, légende par défaut", Frame{Template: FrameBanner}, 35, 1, true, true, false},
+		{"Bandeau, légende colorée", Frame{Template: FrameBanner, TextColor: color.White}, 35, 1, true, false, false},
+		{"Modèle inconnu", Frame{Template: "ticket"}, 0, 0, false, false, true},
+		{"Position inconnue", Frame{Text: "Menu", Position: "left"}, 0, 0, false, false, true},
+		{"Bordure négative", Frame{Template: FrameBorder, Border: -1}, 0, 0, false, false, true},
+		{"Rayon trop grand", Frame{Template: FrameRounded, Radius: 20}, 0, 0, false, false, true},
+		{"Police illisible", Frame{Text: "Menu", Font: []byte("pas une police")}, 0, 0, false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := newFrameLayout(&tt.frame, 33, RenderOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newFrameLayout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if l.width != tt.wantWidth {
+				t.Errorf("largeur %g, attendu %g", l.width, tt.wantWidth)
+			}
+			if l.symbolY < tt.wantSymbolY || (tt.frame.Position == "" && l.symbolY != tt.wantSymbolY) {
+				t.Errorf("symbole en y = %g, attendu %g", l.symbolY, tt.wantSymbolY)
+			}
+			if (len(l.text) > 0) != tt.wantText {
+				t.Errorf("légende présente = %v, attendu %v", len(l.text) > 0, tt.wantText)
+			}
+			if hasText := len(l.text) > 0; hasText && l.height <= l.width || !hasText && l.height != l.width {
+				t.Errorf("hauteur %g pour une largeur %g", l.height, l.width)
+			}
+			if l.knockout != tt.wantKnockout {
+				t.Errorf("légende évidée = %v, attendu %v", l.knockout, tt.wantKnockout)
+			}
+		})
+	}
+}
+
+func TestFrameValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   Frame
+		wantErr string
+	}{
+		{"Par défaut", Frame{}, ""},
+		{"Modèle en majuscules", Frame{Template: "BANNER", Position: "Top"}, ""},
+		{"Modèle inconnu", Frame{Template: "ticket"}, "caption, border, rounded, banner"},
+		{"Position inconnue", Frame{Position: "left"}, "position de légende inconnue"},
+		{"Marge négative", Frame{Padding: -1}, "dimensions de cadre invalides"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.frame.Validate()
+			if (err != nil) != (tt.wantErr != "") || err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, attendu %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFrameCaptionShrink(t *testing.T) {
+	short, err := newFrameLayout(&Frame{Text: "Menu"}, 33, RenderOptions{})
+	if err != nil {
+		t.Fatalf("newFrameLayout() error = %v", err)
+	}
+	long, err := newFrameLayout(&Frame{Text: strings.Repeat("https://example.com/", 4)}, 33, RenderOptions{})
+	if err != nil {
+		t.Fatalf("newFrameLayout() error = %v", err)
+	}
+	if long.height >= short.height {
+		t.Errorf("une légende trop large devrait être réduite : bande de %g contre %g", long.height-long.width, short.height-short.width)
+	}
+	for i, s := range long.text {
+		for j := 0; j < len(s.pts); j += 2 {
+			if x := s.pts[j]; x < 0 || x > long.width {
+				t.Fatalf("segment %d : x = %g hors du cadre de %g", i, x, long.width)
+			}
+		}
+	}
+}
+
+func TestOutlineRoundedRect(t *testing.T) {
+	tests := []struct {
+		name    string
+		g       moduleGeometry
+		reverse bool
+		want    string
+	}{
+		{"Coins droits", moduleGeometry{x1: 2, y1: 1}, false, "M0 0L2 0L2 1L0 1Z"},
+		{"Sens inverse", moduleGeometry{x1: 2, y1: 1}, true, "M0 0L0 1L2 1L2 0Z"},
+		{"Un coin arrondi", moduleGeometry{x1: 2, y1: 2, radii: [4]float64{0, 0, 1, 0}}, false,
+			"M0 0L2 0L2 1C2 1.552285 1.552285 2 1 2L0 2Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o outline
+			o.roundedRect(tt.g, tt.reverse)
+			if got := o.svg(); got != tt.want {
+				t.Errorf("svg() = %q, attendu %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPNGFrame(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	red := color.NRGBA{R: 0xFF, A: 0xFF}
+	white := color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	opts := RenderOptions{QuietZone: 4, Scale: 10, Frame: &Frame{Template: FrameBorder, Text: "Menu", Color: red}}
+
+	var buf bytes.Buffer
+	if err := RenderPNG(&buf, m, opts); err != nil {
+		t.Fatalf("RenderPNG() error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("PNG illisible : %v", err)
+	}
+
+	// Symbole de 33 modules, zone calme comprise, dans une bordure d'un module
+	if w, h := img.Bounds().Dx(), img.Bounds().Dy(); w != 350 || h <= 350 {
+		t.Fatalf("image de %d×%d, attendu 350 de large et une bande de légende", w, h)
+	}
+	tests := []struct {
+		name string
+		x, y int
+		want color.NRGBA
+	}{
+		{"Bordure", 5, 100, red},
+		{"Zone calme", 15, 100, white},
+		{"Module sombre du motif de repérage", 10 + 45, 10 + 45, color.NRGBA{A: 0xFF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := color.NRGBAModel.Convert(img.At(tt.x, tt.y)); got != tt.want {
+				t.Errorf("pixel (%d, %d) = %v, attendu %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+
+	// La légende est dessinée dans la bande, sous le symbole
+	found := false
+	for y := 350; y < img.Bounds().Dy()-10 && !found; y++ {
+		for x := 10; x < 340; x++ {
+			if c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA); c.R == 0xFF && c.G < 0x80 {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		t.Error("légende absente de la bande")
+	}
+
+	for _, format := range []string{FormatJPEG, FormatGIF, FormatBMP, FormatTIFF} {
+		if err := Render(&bytes.Buffer{}, format, m, opts); err != nil {
+			t.Errorf("Render(%s) error = %v", format, err)
+		}
+	}
+}
+
+func TestRenderSVGFrame(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+
+	tests := []struct {
+		name string
+		opts RenderOptions
+		want []string
+	}{
+		{"Bordure", RenderOptions{QuietZone: 4, Scale: 10, Frame: &Frame{Template: FrameBorder}}, []string{
+			`viewBox="0 0 35 35" width="350" height="350"`,
+			`<rect width="35" height="35" fill="#ffffff"/>`,
+			`<g transform="translate(1 1)">`,
+			`<path fill="#000000" fill-rule="evenodd" shape-rendering="geometricPrecision" d="M0 0L35 0L35 35L0 35ZM1 1L1 34L34 34L34 1Z"/>`,
+		}},
+		{"Légende au-dessus", RenderOptions{QuietZone: 4, SizeMM: 33, Frame: &Frame{Text: "Menu", Position: CaptionTop, TextColor: color.NRGBA{B: 0xFF, A: 0xFF}}}, []string{
+			`viewBox="0 0 33 `,
+			`width="33mm" height="`,
+			`<path fill="#0000ff" shape-rendering="geometricPrecision" d="M`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := RenderSVG(&buf, m, tt.opts); err != nil {
+				t.Fatalf("RenderSVG() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("document sans %q", want)
+				}
+			}
+		})
+	}
+
+	if err := RenderSVG(&bytes.Buffer{}, m, RenderOptions{Frame: &Frame{Template: "ticket"}}); err == nil {
+		t.Error("un modèle de cadre inconnu devrait être refusé")
+	}
+}
@@ -4,9 +4,8 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
-	"math"
-	"os"
+	"io"
+	"math/bits"
 	"strings"
 	"sync"
 )
@@ -97,36 +96,65 @@ func CalculateMinVersionForDataType(data string, dataType string) (int, error) {
 	return 0, fmt.Errorf("impossible de stocker les données %s de type %s même avec la version maximale", data, dataType)
 }
 
+// Symbol regroupe une matrice QR générée et les paramètres effectivement retenus
+type Symbol struct {
+	// Matrice du symbole, un pixel par module
+	Matrix *image.RGBA
+
+	// Version finale, éventuellement relevée pour contenir les données
+	Version int
+
+	// Niveau de correction d'erreur appliqué (L, M, Q, H)
+	ErrorCorrectionLevel string
+
+	// Masque sélectionné (0-7)
+	MaskPattern int
+
+	// Type de données détecté (numeric, alphanumeric, kanji, byte)
+	DataType string
+}
+
 // GenerateQRMatrix génère la matrice QR pour les données fournies
+// Retourne nil si le symbole ne peut pas être généré
 func GenerateQRMatrix(version int, data string, errorCorrectionLevel string) *image.RGBA {
+	symbol, err := GenerateSymbol(version, data, errorCorrectionLevel)
+	if err != nil {
+		logf("ERREUR: %v\n", err)
+		return nil
+	}
+	return symbol.Matrix
+}
+
+// GenerateSymbol génère le symbole QR pour les données fournies et retourne
+// la matrice avec la version, le niveau de correction et le masque retenus
+func GenerateSymbol(version int, data string, errorCorrectionLevel string) (*Symbol, error) {
 	// Valider le niveau de correction d'erreur
 	if errorCorrectionLevel != "L" && errorCorrectionLevel != "M" &&
 		errorCorrectionLevel != "Q" && errorCorrectionLevel != "H" {
 		// Par défaut, utiliser le niveau M
 		errorCorrectionLevel = "M"
-		fmt.Printf("Niveau de correction d'erreur invalide, utilisation du niveau M (15%%)\n")
+		logf("Niveau de correction d'erreur invalide, utilisation du niveau M (15%%)\n")
 	}
 
 	// Détecter le type de données
 	dataType := DetectDataType(data)
-	fmt.Printf("Type de données détecté: %s\n", dataType)
+	logf("Type de données détecté: %s\n", dataType)
 
 	// Calculer la version minimale nécessaire
 	minVersion, minVersionErr := CalculateMinVersionForDataType(data, dataType)
 	if minVersionErr != nil {
-		fmt.Printf("ERREUR: %v\n", minVersionErr)
-		return nil
+		return nil, minVersionErr
 	}
 
 	// Utiliser la version minimale si la version fournie est trop petite
 	if version < minVersion {
-		fmt.Printf("La version %d est trop petite pour les données. Utilisation de la version %d.\n", version, minVersion)
+		logf("La version %d est trop petite pour les données. Utilisation de la version %d.\n", version, minVersion)
 		version = minVersion
 	}
 
 	// Vérifier la validité de la version
 	if version < 1 || version > 40 {
-		return nil
+		return nil, fmt.Errorf("version QR invalide: %d, doit être entre 1 et 40", version)
 	}
 
 	// Préparer l'encodage des données
@@ -176,8 +204,7 @@ func GenerateQRMatrix(version int, data string, errorCorrectionLevel string) *im
 	}
 
 	if encodingErr != nil {
-		fmt.Printf("Erreur d'encodage: %v\n", encodingErr)
-		return nil
+		return nil, fmt.Errorf("erreur d'encodage: %w", encodingErr)
 	}
 
 	// Ajouter l'indicateur de mode
@@ -206,219 +233,198 @@ func GenerateQRMatrix(version int, data string, errorCorrectionLevel string) *im
 	AddAlignmentPatterns(matrix, version)
 	AddTimingPatterns(matrix)
 
-	// Calculer la capacité disponible
-	capacity := calculateAvailableCapacity(size)
+	// Capacité des mots de données, une fois la correction de tous les blocs retirée
+	capacity := dataCodewords(version, errorCorrectionLevel) * 8
 
 	// Vérifier si les données encodées dépassent la capacité
 	if encodedData.Len() > capacity {
 		// Si c'est le cas, essayer avec une version supérieure
 		newVersion := version + 1
-		fmt.Printf("ATTENTION: Les données encodées (%d bits) dépassent la capacité (%d bits)\n", encodedData.Len(), capacity)
-		fmt.Printf("Tentative avec la version %d...\n", newVersion)
-		return GenerateQRMatrix(newVersion, data, errorCorrectionLevel) // Appel récursif avec version supérieure
+		logf("ATTENTION: Les données encodées (%d bits) dépassent la capacité (%d bits)\n", encodedData.Len(), capacity)
+		logf("Tentative avec la version %d...\n", newVersion)
+		return GenerateSymbol(newVersion, data, errorCorrectionLevel) // Appel récursif avec version supérieure
 	}
 
-	// Ajouter le terminateur
-	for encodedData.Len() < capacity && encodedData.Len() < capacity-4 {
-		encodedData.WriteString("0")
+	// Ajouter le terminateur (au plus 4 bits), compléter l'octet puis remplir
+	// les mots de données restants en alternant 11101100 et 00010001
+	encodedData.WriteString(strings.Repeat("0", min(4, capacity-encodedData.Len())))
+	encodedData.WriteString(strings.Repeat("0", (8-encodedData.Len()%8)%8))
+	for pad := 0; encodedData.Len() < capacity; pad++ {
+		encodedData.WriteString([]string{"11101100", "00010001"}[pad%2])
 	}
 
 	// Ajouter la correction d'erreur
 	finalData := AddErrorCorrection(encodedData.String(), errorCorrectionLevel, version)
 
-	fmt.Printf("Niveau de correction: %s\n", errorCorrectionLevel)
-	fmt.Printf("Capacité disponible: %d bits\n", capacity)
-	fmt.Printf("Longueur des données encodées: %d bits\n", len(finalData))
-	fmt.Printf("Données encodées: %s\n", finalData)
+	logf("Niveau de correction: %s\n", errorCorrectionLevel)
+	logf("Capacité disponible: %d bits\n", capacity)
+	logf("Longueur des données encodées: %d bits\n", len(finalData))
+	logf("Données encodées: %s\n", finalData)
 
 	// Placer les données
 	PlaceData(matrix, finalData)
 
-	// Appliquer le meilleur masque
-	bestScore := math.MaxInt32
-	var bestMatrix *image.RGBA
-	bestMask := 0
-
-	fmt.Println("Évaluation des masques:")
-	for mask := 0; mask < 8; mask++ {
-		maskedMatrix := ApplyMask(matrix, mask)
-		score := EvaluateMask(maskedMatrix)
-		fmt.Printf("  Masque %d: score %d\n", mask, score)
-
-		if score < bestScore {
-			bestScore = score
-			bestMatrix = maskedMatrix
-			bestMask = mask
-		}
-	}
-
-	fmt.Printf("Meilleur masque sélectionné: %d (score: %d)\n", bestMask, bestScore)
+	// Appliquer le meilleur masque, informations de format comprises
+	matrix, mask, _ := selectBestMask(matrix, errorCorrectionLevel)
 
-	if bestMatrix != nil {
-		matrix = bestMatrix
-		// Ajouter les informations de format après avoir appliqué le masque
-		AddFormatInfo(matrix, errorCorrectionLevel, bestMask)
-	}
-
-	return matrix
+	return &Symbol{
+		Matrix:               matrix,
+		Version:              version,
+		ErrorCorrectionLevel: errorCorrectionLevel,
+		MaskPattern:          mask,
+		DataType:             dataType,
+	}, nil
 }
 
 // EvaluateMask évalue la qualité d'un masque selon les règles de pénalité du QR code
+// (ISO/IEC 18004, section 7.8.3). La matrice doit être le symbole final, informations
+// de format comprises, pour que le score corresponde à celui d'un lecteur.
 func EvaluateMask(matrix *image.RGBA) int {
-	score := 0
-	size := matrix.Bounds().Max.X
+	m := NewMatrixFromImage(matrix)
+	scratch := acquireMaskScratch(m.size)
+	defer releaseMaskScratch(scratch)
+
+	return penaltyScore(penaltyRules(m, scratch))
+}
+
+// Poids des règles de pénalité (N1 à N4 dans la norme)
+const (
+	penaltyN1 = 3
+	penaltyN2 = 3
+	penaltyN3 = 40
+	penaltyN4 = 10
+)
 
-	// Règle 1: Pénalité pour 5+ modules de même couleur consécutifs
-	score += evaluateRule1(matrix, size)
+// penaltyRules calcule séparément les pénalités des règles 1 à 4 sur une
+// matrice compacte, en un seul parcours des lignes puis des colonnes
+func penaltyRules(m *Matrix, scratch *maskScratch) [4]int {
+	var rules [4]int
+	size := m.size
 
-	// Règle 2: Pénalité pour les blocs de couleur 2x2
-	score += evaluateRule2(matrix, size)
+	line := scratch.line[:size]
+	for y := 0; y < size; y++ {
+		m.readRow(y, line)
+		// Règle 1: Pénalité pour 5+ modules de même couleur consécutifs
+		rules[0] += runPenalty(line)
+		// Règle 3: Pénalité pour motifs spécifiques ressemblant aux finder patterns
+		rules[2] += finderPenalty(line)
+		// Règle 2: Pénalité pour les blocs de couleur 2x2
+		if y > 0 {
+			rules[1] += blockPenalty(m.row(y-1), m.row(y), size)
+		}
+	}
 
-	// Règle 3: Pénalité pour motifs spécifiques ressemblant aux finder patterns
-	score += evaluateRule3(matrix, size)
+	for x := 0; x < size; x++ {
+		m.readColumn(x, line)
+		rules[0] += runPenalty(line)
+		rules[2] += finderPenalty(line)
+	}
 
 	// Règle 4: Équilibre entre modules noirs et blancs
-	score += evaluateRule4(matrix, size)
+	rules[3] = balancePenalty(m.countDark(), size*size)
 
-	return score
+	return rules
 }
 
-// evaluateRule1 calcule la pénalité pour les séquences de modules de même couleur
-func evaluateRule1(matrix *image.RGBA, size int) int {
-	penalty := 0
+// penaltyScore additionne les pénalités des quatre règles
+func penaltyScore(rules [4]int) int {
+	return rules[0] + rules[1] + rules[2] + rules[3]
+}
 
-	// Vérifier les lignes horizontales
-	for y := 0; y < size; y++ {
-		count := 1
-		color := isBlack(matrix.At(0, y))
+// runPenalty applique la règle 1 à une ligne ou une colonne :
+// chaque suite de n >= 5 modules de même couleur coûte N1 + (n - 5)
+func runPenalty(line []bool) int {
+	penalty := 0
+	count := 1
 
-		for x := 1; x < size; x++ {
-			if isBlack(matrix.At(x, y)) == color {
-				count++
-			} else {
-				if count >= 5 {
-					penalty += 3 + (count - 5)
-				}
-				count = 1
-				color = isBlack(matrix.At(x, y))
-			}
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			count++
+			continue
 		}
-
-		// Vérifier la séquence finale de la ligne
 		if count >= 5 {
-			penalty += 3 + (count - 5)
+			penalty += penaltyN1 + (count - 5)
 		}
+		count = 1
 	}
 
-	// Vérifier les colonnes verticales
-	for x := 0; x < size; x++ {
-		count := 1
-		color := isBlack(matrix.At(x, 0))
+	return penalty
+}
 
-		for y := 1; y < size; y++ {
-			if isBlack(matrix.At(x, y)) == color {
-				count++
-			} else {
-				if count >= 5 {
-					penalty += 3 + (count - 5)
-				}
-				count = 1
-				color = isBlack(matrix.At(x, y))
-			}
-		}
+// blockPenalty applique la règle 2 à deux lignes adjacentes de size modules,
+// directement sur leurs mots de 64 bits : chaque bloc 2x2 de même couleur coûte N2,
+// les blocs se chevauchant comptent séparément
+func blockPenalty(upper, lower []uint64, size int) int {
+	count := 0
 
-		// Vérifier la séquence finale de la colonne
-		if count >= 5 {
-			penalty += 3 + (count - 5)
+	for w := range upper {
+		a, b := upper[w], lower[w]
+		// Décaler d'un module vers la gauche, en reportant le premier bit du mot suivant
+		var aNext, bNext uint64
+		if w+1 < len(upper) {
+			aNext, bNext = upper[w+1], lower[w+1]
 		}
-	}
+		a1 := a>>1 | aNext<<63
+		b1 := b>>1 | bNext<<63
 
-	return penalty
-}
-
-// evaluateRule2 calcule la pénalité pour les blocs 2x2 de même couleur
-func evaluateRule2(matrix *image.RGBA, size int) int {
-	penalty := 0
+		// Bit x à 1 si les modules x et x+1 des deux lignes sont identiques
+		same := ^(a ^ b) & ^(a ^ a1) & ^(b ^ b1)
 
-	for y := 0; y < size-1; y++ {
-		for x := 0; x < size-1; x++ {
-			color := isBlack(matrix.At(x, y))
-			if isBlack(matrix.At(x+1, y)) == color &&
-				isBlack(matrix.At(x, y+1)) == color &&
-				isBlack(matrix.At(x+1, y+1)) == color {
-				penalty += 3
-			}
+		// Seuls les blocs commençant avant la dernière colonne comptent
+		valid := size - 1 - w*64
+		if valid <= 0 {
+			break
+		}
+		if valid < 64 {
+			same &= 1<<valid - 1
 		}
+		count += bits.OnesCount64(same)
 	}
 
-	return penalty
+	return count * penaltyN2
 }
 
-// evaluateRule3 calcule la pénalité pour les motifs finder-like (1:1:3:1:1)
-func evaluateRule3(matrix *image.RGBA, size int) int {
+// finderPenalty applique la règle 3 à une ligne ou une colonne : chaque motif
+// sombre-clair-sombre-sombre-sombre-clair-sombre (1:1:3:1:1) précédé ou suivi de
+// 4 modules clairs coûte N3. Un motif bordé des deux côtés ne compte qu'une fois
+// et les modules hors du symbole sont clairs (zone calme), comme dans ZXing.
+func finderPenalty(line []bool) int {
 	penalty := 0
 
-	// Motif horizontal: noir-blanc-noir-noir-noir-blanc-noir
-	pattern1 := []bool{true, false, true, true, true, false, true}
-	// Motif vertical équivalent
-	pattern2 := []bool{true, false, true, true, true, false, true}
-
-	// Rechercher les motifs horizontaux
-	for y := 0; y < size; y++ {
-		for x := 0; x <= size-7; x++ {
-			match := true
-			for i := 0; i < 7; i++ {
-				if isBlack(matrix.At(x+i, y)) != pattern1[i] {
-					match = false
-					break
-				}
-			}
-			if match {
-				penalty += 40
-			}
+	for i := 0; i+7 <= len(line); i++ {
+		if !line[i] || line[i+1] || !line[i+2] || !line[i+3] ||
+			!line[i+4] || line[i+5] || !line[i+6] {
+			continue
 		}
-	}
-
-	// Rechercher les motifs verticaux
-	for x := 0; x < size; x++ {
-		for y := 0; y <= size-7; y++ {
-			match := true
-			for i := 0; i < 7; i++ {
-				if isBlack(matrix.At(x, y+i)) != pattern2[i] {
-					match = false
-					break
-				}
-			}
-			if match {
-				penalty += 40
-			}
+		if isLightRun(line, i-4, i) || isLightRun(line, i+7, i+11) {
+			penalty += penaltyN3
 		}
 	}
 
 	return penalty
 }
 
-// evaluateRule4 calcule la pénalité pour le déséquilibre noir/blanc
-func evaluateRule4(matrix *image.RGBA, size int) int {
-	blackCount := 0
-	totalCount := size * size
-
-	// Compter les modules noirs
-	for y := 0; y < size; y++ {
-		for x := 0; x < size; x++ {
-			if isBlack(matrix.At(x, y)) {
-				blackCount++
-			}
+// isLightRun indique si les modules [from, to) de la ligne sont tous clairs
+func isLightRun(line []bool, from, to int) bool {
+	from = max(from, 0)
+	to = min(to, len(line))
+	for i := from; i < to; i++ {
+		if line[i] {
+			return false
 		}
 	}
+	return true
+}
 
-	// Calculer le pourcentage de modules noirs
-	blackPercentage := (blackCount * 100) / totalCount
-
-	// Calculer la différence avec 50% par tranches de 5%
-	fivePercentDeviation := abs((blackPercentage - 50) / 5)
-
-	return fivePercentDeviation * 10
+// balancePenalty applique la règle 4 : N4 par tranche complète de 5 %
+// d'écart entre la proportion de modules sombres et 50 %
+func balancePenalty(darkCount, totalCount int) int {
+	if totalCount == 0 {
+		return 0
+	}
+	// |dark/total - 1/2| / 5 % = |20*dark - 10*total| / total, en arithmétique entière
+	k := abs(darkCount*20-totalCount*10) / totalCount
+	return k * penaltyN4
 }
 
 // isBlack vérifie si une couleur est noire
@@ -490,7 +496,7 @@ func PlaceData(matrix *image.RGBA, data string) {
 	upward := true
 
 	// Vérifier la longueur des données
-	fmt.Printf("Données à placer : %s (longueur: %d)\n", data, len(data))
+	logf("Données à placer : %s (longueur: %d)\n", data, len(data))
 
 	// Calculer le nombre total de modules disponibles pour les données
 	totalModules := 0
@@ -548,7 +554,7 @@ func PlaceData(matrix *image.RGBA, data string) {
 				for y := size - 1; y >= 0; y-- {
 					if isValidDataPosition(currentX, y, size) {
 						if dataIndex < len(data) && data[dataIndex] != '-' {
-							fmt.Printf("Module placé à (%d,%d): %c [Total: %d]\n",
+							logf("Module placé à (%d,%d): %c [Total: %d]\n",
 								currentX, y, data[dataIndex], dataIndex+1)
 							if data[dataIndex] == '1' {
 								matrix.Set(currentX, y, color.Black)
@@ -557,7 +563,7 @@ func PlaceData(matrix *image.RGBA, data string) {
 							}
 						} else if dataIndex < len(data) {
 							// Si c'est '-' ou autre (séparateur), on saute ce bit mais on compte
-							fmt.Printf("Module ignoré à (%d,%d): %c [séparateur]\n",
+							logf("Module ignoré à (%d,%d): %c [séparateur]\n",
 								currentX, y, data[dataIndex])
 						} else {
 							// Si on a fini de placer les données, utiliser le padding calculé
@@ -578,7 +584,7 @@ func PlaceData(matrix *image.RGBA, data string) {
 				for y := 0; y < size; y++ {
 					if isValidDataPosition(currentX, y, size) {
 						if dataIndex < len(data) && data[dataIndex] != '-' {
-							fmt.Printf("Module placé à (%d,%d): %c [Total: %d]\n",
+							logf("Module placé à (%d,%d): %c [Total: %d]\n",
 								currentX, y, data[dataIndex], dataIndex+1)
 							if data[dataIndex] == '1' {
 								matrix.Set(currentX, y, color.Black)
@@ -587,7 +593,7 @@ func PlaceData(matrix *image.RGBA, data string) {
 							}
 						} else if dataIndex < len(data) {
 							// Si c'est '-' ou autre (séparateur), on saute ce bit mais on compte
-							fmt.Printf("Module ignoré à (%d,%d): %c [séparateur]\n",
+							logf("Module ignoré à (%d,%d): %c [séparateur]\n",
 								currentX, y, data[dataIndex])
 						} else {
 							// Si on a fini de placer les données, utiliser le padding calculé
@@ -608,7 +614,7 @@ func PlaceData(matrix *image.RGBA, data string) {
 		}
 	}
 
-	fmt.Printf("Total des modules placés : %d\n", dataIndex)
+	logf("Total des modules placés : %d\n", dataIndex)
 }
 
 // isValidDataPosition vérifie si une position peut contenir des données
@@ -805,85 +811,30 @@ func ProcessDataWithWorkers(data string, numWorkers int) string {
 	return finalResult.String()
 }
 
-// SaveQRImage sauvegarde la matrice QR en image PNG
+// SaveQRImage sauvegarde la matrice QR sans zone calme, dans le format déduit
+// de l'extension du fichier (PNG par défaut)
 func SaveQRImage(matrix *image.RGBA, outputFile string, scale int) error {
-	bounds := matrix.Bounds()
-	width, height := bounds.Max.X, bounds.Max.Y
-
-	// Création d'une nouvelle image avec la taille mise à l'échelle
-	scaledImage := image.NewRGBA(image.Rect(0, 0, width*scale, height*scale))
-
-	// Mise à l'échelle de l'image
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			color := matrix.At(x, y)
-			for sy := 0; sy < scale; sy++ {
-				for sx := 0; sx < scale; sx++ {
-					scaledImage.Set(x*scale+sx, y*scale+sy, color)
-				}
-			}
-		}
-	}
-
-	// Création du fichier de sortie
-	file, err := os.Create(outputFile)
-	if err != nil {
-		return fmt.Errorf("erreur lors de la création du fichier : %v", err)
-	}
-	defer file.Close()
-
-	// Encodage en PNG
-	if err := png.Encode(file, scaledImage); err != nil {
-		return fmt.Errorf("erreur lors de l'encodage PNG : %v", err)
-	}
-
-	return nil
+	opts := NewDefaultRenderOptions()
+	opts.Scale = scale
+	opts.QuietZone = 0
+	return SaveQRImageFormat(matrix, outputFile, FormatFromFilename(outputFile), opts)
 }
 
-// SaveQRImageWithQuietZone sauvegarde la matrice QR en image PNG avec une zone calme (quiet zone)
+// SaveQRImageWithQuietZone sauvegarde la matrice QR avec une zone calme (quiet zone),
+// dans le format déduit de l'extension du fichier (PNG par défaut)
 func SaveQRImageWithQuietZone(matrix *image.RGBA, outputFile string, scale int, quietZone int) error {
-	bounds := matrix.Bounds()
-	width, height := bounds.Max.X, bounds.Max.Y
-
-	// Calculer les dimensions avec la zone calme
-	totalWidth := width + (quietZone * 2)
-	totalHeight := height + (quietZone * 2)
-
-	// Création d'une nouvelle image avec la taille mise à l'échelle incluant la zone calme
-	scaledImage := image.NewRGBA(image.Rect(0, 0, totalWidth*scale, totalHeight*scale))
-
-	// Remplir l'image entière en blanc pour la zone calme
-	for y := 0; y < totalHeight*scale; y++ {
-		for x := 0; x < totalWidth*scale; x++ {
-			scaledImage.Set(x, y, color.White)
-		}
-	}
-
-	// Mise à l'échelle de l'image QR au centre
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			c := matrix.At(x, y)
-			for sy := 0; sy < scale; sy++ {
-				for sx := 0; sx < scale; sx++ {
-					scaledImage.Set((x+quietZone)*scale+sx, (y+quietZone)*scale+sy, c)
-				}
-			}
-		}
-	}
-
-	// Création du fichier de sortie
-	file, err := os.Create(outputFile)
-	if err != nil {
-		return fmt.Errorf("erreur lors de la création du fichier : %v", err)
-	}
-	defer file.Close()
-
-	// Encodage en PNG
-	if err := png.Encode(file, scaledImage); err != nil {
-		return fmt.Errorf("erreur lors de l'encodage PNG : %v", err)
-	}
+	opts := NewDefaultRenderOptions()
+	opts.Scale = scale
+	opts.QuietZone = quietZone
+	return SaveQRImageFormat(matrix, outputFile, FormatFromFilename(outputFile), opts)
+}
 
-	return nil
+// WriteQRImageWithQuietZone encode la matrice QR en PNG noir sur blanc avec une zone calme dans w
+func WriteQRImageWithQuietZone(w io.Writer, matrix *image.RGBA, scale int, quietZone int) error {
+	opts := NewDefaultRenderOptions()
+	opts.Scale = scale
+	opts.QuietZone = quietZone
+	return RenderPNG(w, NewMatrixFromImage(matrix), opts)
 }
 
 // AddFormatInfo ajoute l'information de format au QR code
@@ -891,84 +842,41 @@ func AddFormatInfo(matrix *image.RGBA, ecLevel string, maskPattern int) {
 	formatInfoBits := FormatInfo[ecLevel][maskPattern]
 	size := matrix.Bounds().Max.X
 
-	// Placer l'information de format autour du motif de positionnement en haut à gauche
 	for i := 0; i < 15; i++ {
-		bit := formatInfoBits[i] == '1'
-		if i < 6 {
-			// Position horizontale
-			if bit {
-				matrix.Set(i, 8, color.Black)
-			} else {
-				matrix.Set(i, 8, color.White)
-			}
-		} else if i < 8 {
-			// Position horizontale (après le timing pattern)
-			if bit {
-				matrix.Set(i+1, 8, color.Black)
-			} else {
-				matrix.Set(i+1, 8, color.White)
-			}
-		} else {
-			// Position verticale
-			if bit {
-				matrix.Set(8, size-1-(14-i), color.Black)
-			} else {
-				matrix.Set(8, size-1-(14-i), color.White)
-			}
-		}
-
-		// Copier l'information de format sur le côté droit et en bas
-		if i < 7 {
-			if bit {
-				matrix.Set(size-7+i, 8, color.Black)
-			} else {
-				matrix.Set(size-7+i, 8, color.White)
-			}
-		} else {
-			if bit {
-				matrix.Set(8, 6-(i-7), color.Black)
-			} else {
-				matrix.Set(8, 6-(i-7), color.White)
-			}
+		c := color.White
+		if formatInfoBits[i] == '1' {
+			c = color.Black
 		}
+		x1, y1, x2, y2 := formatInfoCoords(i, size)
+		matrix.Set(x1, y1, c)
+		matrix.Set(x2, y2, c)
 	}
 }
 
-// Ajout d'une fonction pour calculer la capacité disponible
-func calculateAvailableCapacity(size int) int {
-	// Version calculée à partir de la taille
-	version := (size - 17) / 4
-
-	// Tableau de capacités officielles en bits pour chaque version (Mode byte, niveau de correction M)
-	// Ces valeurs sont tirées de la spécification ISO/IEC 18004
-	capacityTable := map[int]int{
-		1:  128,  // 16 octets
-		2:  224,  // 28 octets
-		3:  352,  // 44 octets
-		4:  512,  // 64 octets
-		5:  688,  // 86 octets
-		6:  864,  // 108 octets
-		7:  992,  // 124 octets
-		8:  1232, // 154 octets
-		9:  1456, // 182 octets
-		10: 1728, // 216 octets
-	}
-
-	// Utiliser la valeur tabulée si disponible
-	if capacity, ok := capacityTable[version]; ok {
-		return capacity
+// formatInfoCoords retourne les deux positions du bit i de l'information de format :
+// autour du motif de positionnement en haut à gauche, puis sa copie sur le côté
+// droit et en bas. Les positions hors de la matrice sont ignorées par les appelants.
+func formatInfoCoords(i, size int) (x1, y1, x2, y2 int) {
+	switch {
+	case i < 6:
+		// Position horizontale
+		x1, y1 = i, 8
+	case i < 8:
+		// Position horizontale (après le timing pattern)
+		x1, y1 = i+1, 8
+	default:
+		// Position verticale
+		x1, y1 = 8, size-1-(14-i)
+	}
+
+	// Copie de l'information de format
+	if i < 7 {
+		x2, y2 = size-7+i, 8
+	} else {
+		x2, y2 = 8, 6-(i-7)
 	}
 
-	// Fallback : calculer en comptant les positions valides
-	capacity := 0
-	for x := 0; x < size; x++ {
-		for y := 0; y < size; y++ {
-			if isValidDataPosition(x, y, size) {
-				capacity++
-			}
-		}
-	}
-	return capacity
+	return x1, y1, x2, y2
 }
 
 // Fonction existante utilisée pour la correction d'erreur
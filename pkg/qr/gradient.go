@@ -0,0 +1,199 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Types de dégradé des modules
+const (
+	GradientLinear = "linear"
+	GradientRadial = "radial"
+)
+
+// GradientTypes retourne la liste des types de dégradé disponibles
+func GradientTypes() []string {
+	return []string{GradientLinear, GradientRadial}
+}
+
+// Gradient décrit un dégradé qui remplace Foreground pour colorer les modules.
+// Il s'étend sur le symbole, zone calme exclue : le dégradé linéaire va d'un
+// bord à l'autre selon son angle, le dégradé radial du centre aux coins.
+type Gradient struct {
+	// GradientLinear ou GradientRadial (GradientLinear s'il est vide)
+	Type string
+
+	// Direction du dégradé linéaire en degrés, dans le sens horaire : 0 va de
+	// la gauche vers la droite, 90 du haut vers le bas
+	Angle float64
+
+	// Couleurs réparties régulièrement du début à la fin du dégradé (au moins deux)
+	Colors []color.Color
+}
+
+// gradientFill est un dégradé validé, exprimé en modules depuis le coin haut
+// gauche du symbole
+type gradientFill struct {
+	radial bool
+	colors []color.NRGBA
+
+	// Dégradé linéaire : début et fin du segment ; radial : centre et rayon
+	x1, y1, x2, y2 float64
+	radius         float64
+}
+
+// newGradientFill valide le dégradé et le place sur un symbole de size modules ;
+// il retourne nil sans dégradé
+func newGradientFill(g *Gradient, size int) (*gradientFill, error) {
+	if g == nil {
+		return nil, nil
+	}
+	kind := strings.ToLower(g.Type)
+	if kind == "" {
+		kind = GradientLinear
+	}
+	if !slices.Contains(GradientTypes(), kind) {
+		return nil, fmt.Errorf("type de dégradé inconnu : %q (attendu %s)", g.Type, strings.Join(GradientTypes(), ", "))
+	}
+	if len(g.Colors) < 2 {
+		return nil, fmt.Errorf("dégradé de %d couleur(s), il en faut au moins deux", len(g.Colors))
+	}
+
+	f := &gradientFill{radial: kind == GradientRadial}
+	for _, c := range g.Colors {
+		f.colors = append(f.colors, toNRGBA(c, color.Black))
+	}
+
+	center := float64(size) / 2
+	if f.radial {
+		f.x1, f.y1, f.radius = center, center, center*math.Sqrt2
+		return f, nil
+	}
+
+	// Le segment est assez long pour que les coins du symbole tombent sur ses extrémités
+	angle := g.Angle * math.Pi / 180
+	dx, dy := math.Cos(angle), math.Sin(angle)
+	half := center * (math.Abs(dx) + math.Abs(dy))
+	f.x1, f.y1 = center-dx*half, center-dy*half
+	f.x2, f.y2 = center+dx*half, center+dy*half
+	return f, nil
+}
+
+// at retourne la couleur du dégradé au point (x, y), en modules
+func (f *gradientFill) at(x, y float64) color.NRGBA {
+	var t float64
+	if f.radial {
+		t = math.Hypot(x-f.x1, y-f.y1) / f.radius
+	} else {
+		dx, dy := f.x2-f.x1, f.y2-f.y1
+		t = ((x-f.x1)*dx + (y-f.y1)*dy) / (dx*dx + dy*dy)
+	}
+	t = math.Max(0, math.Min(1, t))
+
+	pos := t * float64(len(f.colors)-1)
+	i := min(int(pos), len(f.colors)-2)
+	frac := pos - float64(i)
+	a, b := f.colors[i], f.colors[i+1]
+	mix := func(u, v uint8) uint8 {
+		return uint8(math.Round(float64(u)*(1-frac) + float64(v)*frac))
+	}
+	return color.NRGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: mix(a.A, b.A)}
+}
+
+// painted indique si les couleurs des modules varient d'un endroit à l'autre
+// du symbole, ce qu'une palette d'encres ne peut pas représenter
+func (opts RenderOptions) painted() bool {
+	return opts.Gradient != nil || len(opts.EyeColors) > 0
+}
+
+// eyeAt retourne le numéro du motif de repérage contenant le module (x, y), -1 sinon
+func eyeAt(x, y float64, size int) int {
+	for i, o := range eyeOrigins(size) {
+		if x >= float64(o.X) && x < float64(o.X+eyeSize) && y >= float64(o.Y) && y < float64(o.Y+eyeSize) {
+			return i
+		}
+	}
+	return -1
+}
+
+// paint colore l'image à palette pixel par pixel : les modules prennent la
+// couleur du dégradé, et chaque œil la couleur que lui donne EyeColors. Le
+// cadre et la pupille gardent EyeFrameColor et EyePupilColor s'ils sont
+// définis. La couverture de chaque pixel est conservée pour l'anticrénelage.
+func paint(img *image.Paletted, m *Matrix, opts RenderOptions) (*image.NRGBA, error) {
+	layout, err := newRasterLayout(m, opts)
+	if err != nil {
+		return nil, err
+	}
+	gradient, err := newGradientFill(opts.Gradient, m.size)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.EyeColors) > len(eyeOrigins(m.size)) {
+		return nil, fmt.Errorf("%d couleurs d'yeux, le symbole n'a que trois motifs de repérage", len(opts.EyeColors))
+	}
+
+	origin := float64(layout.offset + opts.QuietZone*layout.module)
+	module := float64(layout.module)
+	levels := shapeSamples * shapeSamples
+	bg := toNRGBA(img.Palette[0], color.White)
+
+	out := image.NewNRGBA(img.Bounds())
+	for py := 0; py < img.Rect.Dy(); py++ {
+		for px := 0; px < img.Rect.Dx(); px++ {
+			index := img.Pix[py*img.Stride+px]
+			ink, coverage := shadeOf(index)
+			if coverage == 0 {
+				out.SetNRGBA(px, py, bg)
+				continue
+			}
+
+			c := toNRGBA(img.Palette[shadeIndex(ink, uint8(levels))], color.Black)
+			x, y := (float64(px)+0.5-origin)/module, (float64(py)+0.5-origin)/module
+			ownColor := (ink == 1 && opts.EyeFrameColor != nil) || (ink == 2 && opts.EyePupilColor != nil)
+			if gradient != nil && !ownColor {
+				c = gradient.at(x, y)
+			}
+			if eye := eyeAt(x, y, m.size); eye >= 0 && eye < len(opts.EyeColors) && opts.EyeColors[eye] != nil {
+				c = toNRGBA(opts.EyeColors[eye], c)
+			}
+			out.SetNRGBA(px, py, blend(bg, c, int(coverage), levels))
+		}
+	}
+	return out, nil
+}
+
+// shadeOf retourne l'encre et la couverture de l'indice de palette, à
+// l'inverse de shadeIndex
+func shadeOf(index uint8) (ink int, coverage uint8) {
+	levels := shapeSamples * shapeSamples
+	switch {
+	case index == 0:
+		return 0, 0
+	case index == 1:
+		return 0, uint8(levels)
+	case int(index) <= levels:
+		return 0, index - 1
+	default:
+		ink = (int(index) - 1) / levels
+		return ink, uint8(int(index) - levels*ink)
+	}
+}
+
+// blend mélange le fond et l'encre selon la couverture, comme les teintes de shadePalette
+func blend(bg, ink color.NRGBA, coverage, levels int) color.NRGBA {
+	if coverage == levels {
+		return ink
+	}
+	r0, g0, b0, a0 := bg.RGBA()
+	r1, g1, b1, a1 := ink.RGBA()
+	mix := func(a, b uint32) uint16 {
+		return uint16((a*uint32(levels-coverage) + b*uint32(coverage)) / uint32(levels))
+	}
+	premultiplied := color.RGBA64{R: mix(r0, r1), G: mix(g0, g1), B: mix(b0, b1), A: mix(a0, a1)}
+	return color.NRGBAModel.Convert(premultiplied).(color.NRGBA)
+}
@@ -0,0 +1,156 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestGradientFillAt(t *testing.T) {
+	black, white := color.NRGBA{A: 0xFF}, color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	gray := color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF}
+	colors := []color.Color{black, white}
+
+	tests := []struct {
+		name     string
+		gradient Gradient
+		x, y     float64
+		want     color.NRGBA
+	}{
+		{"Linéaire, bord gauche", Gradient{Colors: colors}, 0, 5, black},
+		{"Linéaire, centre", Gradient{Colors: colors}, 10.5, 3, gray},
+		{"Linéaire, bord droit", Gradient{Colors: colors}, 21, 5, white},
+		{"Linéaire vertical, bas", Gradient{Angle: 90, Colors: colors}, 3, 21, white},
+		{"Diagonale, coin haut gauche", Gradient{Angle: 45, Colors: colors}, 0, 0, black},
+		{"Diagonale, coin bas droit", Gradient{Angle: 45, Colors: colors}, 21, 21, white},
+		{"Radial, centre", Gradient{Type: GradientRadial, Colors: colors}, 10.5, 10.5, black},
+		{"Radial, coin", Gradient{Type: "Radial", Colors: colors}, 0, 21, white},
+		{"Trois couleurs, milieu", Gradient{Colors: []color.Color{black, gray, white}}, 10.5, 0, gray},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newGradientFill(&tt.gradient, 21)
+			if err != nil {
+				t.Fatalf("newGradientFill() error = %v", err)
+			}
+			if got := f.at(tt.x, tt.y); got != tt.want {
+				t.Errorf("at(%g, %g) = %v, attendu %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGradientFillValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		gradient Gradient
+	}{
+		{"Type inconnu", Gradient{Type: "conic", Colors: []color.Color{color.Black, color.White}}},
+		{"Une seule couleur", Gradient{Colors: []color.Color{color.Black}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newGradientFill(&tt.gradient, 21); err == nil {
+				t.Error("newGradientFill() devrait échouer")
+			}
+		})
+	}
+}
+
+func TestShadeOf(t *testing.T) {
+	levels := shapeSamples * shapeSamples
+	for ink := 0; ink < 3; ink++ {
+		for coverage := 1; coverage <= levels; coverage++ {
+			gotInk, gotCoverage := shadeOf(shadeIndex(ink, uint8(coverage)))
+			if gotInk != ink || int(gotCoverage) != coverage {
+				t.Fatalf("shadeOf(shadeIndex(%d, %d)) = %d, %d", ink, coverage, gotInk, gotCoverage)
+			}
+		}
+	}
+}
+
+func TestRenderPNGGradient(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	red, blue := color.NRGBA{R: 0xFF, A: 0xFF}, color.NRGBA{B: 0xFF, A: 0xFF}
+	green := color.NRGBA{G: 0x80, A: 0xFF}
+	opts := RenderOptions{
+		QuietZone: 4,
+		Scale:     10,
+		Gradient:  &Gradient{Colors: []color.Color{red, blue}},
+		EyeColors: []color.Color{nil, green},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderPNG(&buf, m, opts); err != nil {
+		t.Fatalf("RenderPNG() error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("PNG illisible : %v", err)
+	}
+
+	// Centre d'un module, zone calme de 4 modules de 10 pixels
+	at := func(x, y int) color.NRGBA {
+		return color.NRGBAModel.Convert(img.At((x+4)*10+5, (y+4)*10+5)).(color.NRGBA)
+	}
+	if c := at(0, 0); c.R < 0xF0 || c.B > 0x10 {
+		t.Errorf("œil haut gauche %v, attendu le début du dégradé", c)
+	}
+	if c := at(m.Size()-1, 0); c != green {
+		t.Errorf("œil haut droit %v, attendu sa couleur propre %v", c, green)
+	}
+	if c := at(6, m.Size()-1); c.R < 0xB0 || c.B < 0x30 {
+		t.Errorf("œil bas gauche %v, attendu un mélange proche du début du dégradé", c)
+	}
+	if c := at(1, 1); c != (color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Errorf("évidement du cadre %v, attendu le fond", c)
+	}
+
+	opts.EyeColors = make([]color.Color, 4)
+	if err := RenderPNG(&buf, m, opts); err == nil {
+		t.Error("plus de trois couleurs d'yeux devraient être refusées")
+	}
+}
+
+func TestRenderSVGGradient(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	colors := []color.Color{color.NRGBA{R: 0xFF, A: 0xFF}, color.NRGBA{B: 0xFF, A: 0x80}}
+
+	tests := []struct {
+		name string
+		opts RenderOptions
+		want []string
+	}{
+		{"Linéaire", RenderOptions{IDPrefix: "qr1-", Gradient: &Gradient{Angle: 90, Colors: colors}}, []string{
+			`<linearGradient id="qr1-foreground" gradientUnits="userSpaceOnUse" x1="12.5" y1="0" x2="12.5" y2="25">`,
+			`<stop offset="1" stop-color="#0000ff" stop-opacity="0.502"/>`,
+			`<path fill="url(#qr1-foreground)" d=`,
+		}},
+		{"Radial, zone calme", RenderOptions{IDPrefix: "qr1-", QuietZone: 2, Gradient: &Gradient{Type: GradientRadial, Colors: colors}}, []string{
+			`<radialGradient id="qr1-foreground" gradientUnits="userSpaceOnUse" cx="14.5" cy="14.5" r="17.67767">`,
+		}},
+		{"Yeux de couleurs différentes", RenderOptions{IDPrefix: "qr1-", Gradient: &Gradient{Colors: colors}, EyeColors: []color.Color{color.NRGBA{G: 0xFF, A: 0xFF}}}, []string{
+			`<path fill="url(#qr1-foreground)" fill-rule="evenodd" d=`,
+			`<path fill="#00ff00" fill-rule="evenodd" d=`,
+			`<path fill="#00ff00" d=`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := RenderSVG(&buf, m, tt.opts); err != nil {
+				t.Fatalf("RenderSVG() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("document sans %q", want)
+				}
+			}
+		})
+	}
+}
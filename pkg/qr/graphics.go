@@ -0,0 +1,164 @@
+package qr
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// kittyChunkSize est la taille maximale d'un fragment base64 du protocole Kitty
+const kittyChunkSize = 4096
+
+// RenderSixel écrit la matrice dans w sous forme d'image Sixel, zone calme
+// comprise, chaque module mesurant Scale pixels. Un fond transparent laisse
+// apparaître celui du terminal.
+func RenderSixel(w io.Writer, m *Matrix, opts RenderOptions) error {
+	opts = opts.polarity()
+	scale := max(opts.Scale, 1)
+	total := m.size + 2*opts.QuietZone
+	pixels := total * scale
+	bw := bufio.NewWriter(w)
+
+	fg := sixelColor(opts.Foreground, color.Black)
+	bg := sixelColor(opts.Background, color.White)
+	transparent := opts.Background != nil && isTransparent(opts.Background)
+
+	// Introduction DCS : P2 = 1 laisse transparents les pixels non peints
+	if transparent {
+		fmt.Fprintf(bw, "\x1bP0;1;0q")
+	} else {
+		fmt.Fprintf(bw, "\x1bP0;0;0q")
+	}
+	fmt.Fprintf(bw, "\"1;1;%d;%d", pixels, pixels)
+	fmt.Fprintf(bw, "#0;2;%s#1;2;%s", bg, fg)
+
+	// dark indique si le pixel (x, y) appartient à un module sombre
+	dark := func(x, y int) bool {
+		return m.Get(x/scale-opts.QuietZone, y/scale-opts.QuietZone)
+	}
+
+	// Chaque bande couvre six lignes de pixels, une passe par couleur
+	line := make([]byte, pixels)
+	for top := 0; top < pixels; top += 6 {
+		for register := 0; register < 2; register++ {
+			if register == 0 && transparent {
+				continue
+			}
+			for x := 0; x < pixels; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && top+dy < pixels; dy++ {
+					if dark(x, top+dy) == (register == 1) {
+						bits |= 1 << dy
+					}
+				}
+				line[x] = '?' + bits
+			}
+			fmt.Fprintf(bw, "#%d", register)
+			writeSixelRuns(bw, line)
+			bw.WriteByte('$')
+		}
+		bw.WriteByte('-')
+	}
+
+	fmt.Fprintf(bw, "\x1b\\\n")
+	return bw.Flush()
+}
+
+// writeSixelRuns écrit une ligne de sixels en compressant les répétitions (!n)
+func writeSixelRuns(w *bufio.Writer, line []byte) {
+	for i := 0; i < len(line); {
+		j := i
+		for j < len(line) && line[j] == line[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, line[i])
+		} else {
+			w.Write(line[i:j])
+		}
+		i = j
+	}
+}
+
+// sixelColor retourne une couleur au format Sixel RVB en pourcentages
+func sixelColor(c color.Color, fallback color.Color) string {
+	if c == nil {
+		c = fallback
+	}
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	percent := func(v uint8) int {
+		return (int(v)*100 + 127) / 255
+	}
+	return fmt.Sprintf("%d;%d;%d", percent(nrgba.R), percent(nrgba.G), percent(nrgba.B))
+}
+
+// RenderKitty écrit la matrice dans w en PNG transmis par le protocole graphique
+// de Kitty, découpé en fragments base64 de 4096 octets au plus
+func RenderKitty(w io.Writer, m *Matrix, opts RenderOptions) error {
+	var img bytes.Buffer
+	if err := RenderPNG(&img, m, opts); err != nil {
+		return err
+	}
+	payload := base64.StdEncoding.EncodeToString(img.Bytes())
+
+	bw := bufio.NewWriter(w)
+	for first := true; first || payload != ""; first = false {
+		chunk := payload[:min(kittyChunkSize, len(payload))]
+		payload = payload[len(chunk):]
+
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		// Les clés de contrôle ne figurent que dans le premier fragment
+		if first {
+			fmt.Fprintf(bw, "\x1b_Gf=100,a=T,m=%d;%s\x1b\\", more, chunk)
+		} else {
+			fmt.Fprintf(bw, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	bw.WriteByte('\n')
+
+	return bw.Flush()
+}
+
+// Terminaux reconnus par leurs variables TERM et TERM_PROGRAM
+var (
+	kittyTerms    = []string{"xterm-kitty", "xterm-ghostty"}
+	kittyPrograms = []string{"WezTerm", "ghostty"}
+	sixelTerms    = []string{"foot", "foot-extra", "mlterm", "yaft-256color", "contour"}
+	sixelPrograms = []string{"iTerm.app", "mintty", "WezTerm", "contour"}
+)
+
+// SupportsTerminalFormat indique si le terminal décrit par les variables
+// d'environnement (lues avec getenv) sait afficher le format demandé. Les
+// demi-blocs et l'ASCII sont toujours pris en charge.
+func SupportsTerminalFormat(format string, getenv func(string) string) bool {
+	term := getenv("TERM")
+	program := getenv("TERM_PROGRAM")
+
+	switch format {
+	case FormatTerminal:
+		return true
+	case FormatKitty:
+		return getenv("KITTY_WINDOW_ID") != "" || containsFold(kittyTerms, term) || containsFold(kittyPrograms, program)
+	case FormatSixel:
+		return strings.Contains(term, "sixel") || containsFold(sixelTerms, term) || containsFold(sixelPrograms, program)
+	default:
+		return false
+	}
+}
+
+// containsFold indique si values contient s, sans tenir compte de la casse
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/color"
+	"image/png"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	sixelRaster = regexp.MustCompile(`^"1;1;(\d+);(\d+)`)
+	kittyChunk  = regexp.MustCompile("\x1b_G([^;]*);([^\x1b]*)\x1b\\\\")
+)
+
+// decodeSixel décode les données d'une image Sixel en grille de registres de
+// couleur, -1 pour un pixel non peint
+func decodeSixel(t *testing.T, data string) [][]int {
+	t.Helper()
+
+	match := sixelRaster.FindStringSubmatch(data)
+	if match == nil {
+		t.Fatalf("attributs raster manquants : %.20q", data)
+	}
+	width, _ := strconv.Atoi(match[1])
+	height, _ := strconv.Atoi(match[2])
+	pixels := make([][]int, height)
+	for y := range pixels {
+		pixels[y] = make([]int, width)
+		for x := range pixels[y] {
+			pixels[y][x] = -1
+		}
+	}
+
+	data = data[len(match[0]):]
+	register, x, top := 0, 0, 0
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '#':
+			// Sélection ou définition de registre : #n ou #n;2;r;g;b
+			j := i + 1
+			for j < len(data) && (data[j] >= '0' && data[j] <= '9' || data[j] == ';') {
+				j++
+			}
+			register, _ = strconv.Atoi(strings.Split(data[i+1:j], ";")[0])
+			i = j
+		case c == '$':
+			x = 0
+			i++
+		case c == '-':
+			x, top = 0, top+6
+			i++
+		case c == '!' || (c >= '?' && c <= '~'):
+			count := 1
+			if c == '!' {
+				j := i + 1
+				for data[j] >= '0' && data[j] <= '9' {
+					j++
+				}
+				count, _ = strconv.Atoi(data[i+1 : j])
+				i = j
+			}
+			bits := data[i] - '?'
+			for n := 0; n < count; n++ {
+				for dy := 0; dy < 6; dy++ {
+					if bits&(1<<dy) != 0 && top+dy < height {
+						pixels[top+dy][x] = register
+					}
+				}
+				x++
+			}
+			i++
+		default:
+			t.Fatalf("caractère Sixel inattendu %q", c)
+		}
+	}
+
+	return pixels
+}
+
+func TestRenderSixel(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows(cornerRows))
+
+	tests := []struct {
+		name       string
+		background color.Color
+		header     string
+		light      int
+	}{
+		{"Fond opaque", color.White, "\x1bP0;0;0q", 0},
+		{"Fond transparent", color.Transparent, "\x1bP0;1;0q", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewDefaultRenderOptions()
+			opts.QuietZone = 1
+			opts.Scale = 2
+			opts.Background = tt.background
+
+			var buf bytes.Buffer
+			if err := RenderSixel(&buf, m, opts); err != nil {
+				t.Fatalf("RenderSixel() error = %v", err)
+			}
+			out := buf.String()
+			if !strings.HasPrefix(out, tt.header) || !strings.HasSuffix(out, "\x1b\\\n") {
+				t.Fatalf("séquence DCS mal délimitée : %q", out)
+			}
+
+			pixels := decodeSixel(t, strings.TrimSuffix(strings.TrimPrefix(out, tt.header), "\x1b\\\n"))
+			if len(pixels) != 14 || len(pixels[0]) != 14 {
+				t.Fatalf("image de %dx%d pixels, attendu 14x14", len(pixels[0]), len(pixels))
+			}
+			for y, row := range pixels {
+				for x, register := range row {
+					want := tt.light
+					if m.Get(x/2-1, y/2-1) {
+						want = 1
+					}
+					if register != want {
+						t.Errorf("pixel (%d,%d) : registre %d, attendu %d", x, y, register, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRenderKitty(t *testing.T) {
+	// Un symbole dense, pour que le PNG dépasse un fragment
+	m := NewMatrixFromImage(benchmarkSymbol(25))
+
+	opts := NewDefaultRenderOptions()
+	opts.Scale = 8
+
+	var buf bytes.Buffer
+	if err := RenderKitty(&buf, m, opts); err != nil {
+		t.Fatalf("RenderKitty() error = %v", err)
+	}
+
+	chunks := kittyChunk.FindAllStringSubmatch(buf.String(), -1)
+	if len(chunks) < 2 {
+		t.Fatalf("%d fragments, attendu une image découpée en plusieurs fragments", len(chunks))
+	}
+
+	var payload strings.Builder
+	for i, chunk := range chunks {
+		want := "m=1"
+		if i == 0 {
+			want = "f=100,a=T,m=1"
+		}
+		if i == len(chunks)-1 {
+			want = strings.TrimSuffix(want, "1") + "0"
+		}
+		if chunk[1] != want {
+			t.Errorf("fragment %d : contrôle %q, attendu %q", i, chunk[1], want)
+		}
+		if len(chunk[2]) > kittyChunkSize {
+			t.Errorf("fragment %d : %d octets, maximum %d", i, len(chunk[2]), kittyChunkSize)
+		}
+		payload.WriteString(chunk[2])
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload.String())
+	if err != nil {
+		t.Fatalf("base64 invalide : %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("PNG illisible : %v", err)
+	}
+	if size := img.Bounds().Dx(); size != (m.Size()+8)*8 {
+		t.Errorf("image de %d pixels, attendu %d", size, (m.Size()+8)*8)
+	}
+}
+
+func TestSupportsTerminalFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		format string
+		want   bool
+	}{
+		{"Demi-blocs partout", nil, FormatTerminal, true},
+		{"Kitty par TERM", map[string]string{"TERM": "xterm-kitty"}, FormatKitty, true},
+		{"Kitty par fenêtre", map[string]string{"KITTY_WINDOW_ID": "1"}, FormatKitty, true},
+		{"WezTerm", map[string]string{"TERM_PROGRAM": "WezTerm"}, FormatSixel, true},
+		{"Sixel par TERM", map[string]string{"TERM": "foot"}, FormatSixel, true},
+		{"Sixel déclaré", map[string]string{"TERM": "xterm-sixel"}, FormatSixel, true},
+		{"Sixel par iTerm", map[string]string{"TERM_PROGRAM": "iTerm.app"}, FormatSixel, true},
+		{"Pas de Kitty dans iTerm", map[string]string{"TERM_PROGRAM": "iTerm.app"}, FormatKitty, false},
+		{"xterm simple", map[string]string{"TERM": "xterm-256color"}, FormatSixel, false},
+		{"Format inconnu", map[string]string{"TERM": "xterm-kitty"}, "png", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			if got := SupportsTerminalFormat(tt.format, getenv); got != tt.want {
+				t.Errorf("SupportsTerminalFormat(%q) = %v, attendu %v", tt.format, got, tt.want)
+			}
+		})
+	}
+}
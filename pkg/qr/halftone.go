@@ -0,0 +1,247 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"maps"
+	"slices"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// HalftoneCell est le nombre de sous-pixels par côté de module en mode tramé
+const HalftoneCell = 3
+
+// halftoneMargin est la part de la capacité de correction que l'image peut
+// consommer ; le reste est laissé aux défauts d'impression et de prise de vue
+const halftoneMargin = 0.5
+
+// halftoneWeights pondère les sous-pixels d'une cellule comme un lecteur qui
+// échantillonne le centre du module avec un flou : le centre compte pour un
+// quart, les voisins directs pour la moitié, les coins pour le reste
+var halftoneWeights = [HalftoneCell * HalftoneCell]int{1, 2, 1, 2, 4, 2, 1, 2, 1}
+
+// Halftone décrit le mode tramé : une photo est tramée par diffusion d'erreur
+// sur une grille de HalftoneCell×HalftoneCell sous-pixels par module. Le
+// sous-pixel central d'un module de données porte toujours sa valeur, les autres
+// suivent l'image ; les motifs de fonction (repérage, synchronisation,
+// alignement, format et version) restent pleins. Quand l'image ferait lire un
+// module à l'envers par un lecteur qui floute la cellule (halftoneWeights), ses
+// voisins directs du centre sont eux aussi forcés à sa valeur, sauf dans autant
+// de mots de code que la moitié de la capacité de correction en tolère : un
+// niveau de correction élevé garde donc une image plus fidèle.
+type Halftone struct {
+	// Photo recadrée au carré, au centre, et étirée sur le symbole, zone calme exclue
+	Image image.Image
+}
+
+// halftoneMatrix trame l'image sur la matrice et retourne la matrice des
+// sous-pixels, HalftoneCell fois plus grande
+func halftoneMatrix(m *Matrix, h *Halftone) (*Matrix, error) {
+	if h == nil || h.Image == nil || h.Image.Bounds().Empty() {
+		return nil, fmt.Errorf("image tramée absente ou vide")
+	}
+	level, _, ok := readFormatInfo(m)
+	if !ok {
+		return nil, fmt.Errorf("information de format illisible, niveau de correction inconnu")
+	}
+	size := m.size * HalftoneCell
+	light := halftoneLuminance(h.Image, size)
+
+	cells := NewMatrix(size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			col, row := x/HalftoneCell, y/HalftoneCell
+			center := x%HalftoneCell == HalftoneCell/2 && y%HalftoneCell == HalftoneCell/2
+
+			// Floyd-Steinberg ; les sous-pixels imposés diffusent aussi leur
+			// erreur, pour que la teinte moyenne de l'image soit conservée
+			v := light[y*size+x]
+			dark := v < 0.5
+			if center || isFunctionPattern(col, row, m.size) {
+				dark = m.Get(col, row)
+			}
+			cells.Set(x, y, dark)
+
+			e := v
+			if !dark {
+				e = v - 1
+			}
+			spread := func(dx, dy int, weight float64) {
+				if x+dx >= 0 && x+dx < size && y+dy < size {
+					light[(y+dy)*size+x+dx] += e * weight
+				}
+			}
+			spread(1, 0, 7.0/16)
+			spread(-1, 1, 3.0/16)
+			spread(0, 1, 5.0/16)
+			spread(1, 1, 1.0/16)
+		}
+	}
+	halftoneProtect(m, cells, level)
+	return cells, nil
+}
+
+// halftoneProtect renforce les modules lus à l'envers en forçant à leur valeur,
+// un par un, jusqu'aux quatre sous-pixels voisins du centre ; l'image y est donc
+// remplacée par le module. Les mots de code les plus touchés de chaque bloc,
+// dans la limite tolérée par halftoneMargin, gardent l'image intacte.
+func halftoneProtect(m, cells *Matrix, level string) {
+	misread := halftoneMisread(m, cells)
+	codeword := make(map[image.Point]int)
+	counts := make(map[int]int)
+	for i, p := range dataOrder(m.size) {
+		codeword[p] = i / 8
+		if misread.Get(p.X, p.Y) {
+			counts[i/8]++
+		}
+	}
+
+	owner, budget := codewordBlocks(m.size, level)
+	touched := slices.Collect(maps.Keys(counts))
+	slices.SortFunc(touched, func(a, b int) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return a - b
+	})
+	tolerated := make(map[int]bool)
+	perBlock := make(map[int]int)
+	for _, c := range touched {
+		// Les bits de reste n'appartiennent à aucun bloc et peuvent être faussés
+		if c >= len(owner) {
+			tolerated[c] = owner != nil
+			continue
+		}
+		if perBlock[owner[c]] < int(float64(budget)*halftoneMargin) {
+			perBlock[owner[c]]++
+			tolerated[c] = true
+		}
+	}
+
+	for row := 0; row < m.size; row++ {
+		for col := 0; col < m.size; col++ {
+			c, data := codeword[image.Pt(col, row)]
+			if !misread.Get(col, row) || (data && tolerated[c]) {
+				continue
+			}
+			// Les voisins directs du centre pèsent assez pour rétablir la valeur
+			want := m.Get(col, row)
+			for _, i := range []int{1, 3, 5, 7} {
+				if halftoneRead(cells, col, row, want) == want {
+					break
+				}
+				cells.Set(col*HalftoneCell+i%HalftoneCell, row*HalftoneCell+i/HalftoneCell, want)
+			}
+		}
+	}
+}
+
+// halftoneLuminance recadre l'image au carré, la met à l'échelle size×size sur
+// fond blanc et retourne la luminance de chaque pixel, de 0 (noir) à 1 (blanc)
+func halftoneLuminance(img image.Image, size int) []float64 {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(b.Min).Add(image.Pt((b.Dx()-side)/2, (b.Dy()-side)/2))
+
+	scaled := image.NewGray(image.Rect(0, 0, size, size))
+	draw.Draw(scaled, scaled.Rect, image.White, image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(scaled, scaled.Rect, img, crop, xdraw.Over, nil)
+
+	light := make([]float64, size*size)
+	for i, v := range scaled.Pix {
+		light[i] = float64(v) / 0xFF
+	}
+	return light
+}
+
+// halftoneRead retourne la valeur qu'un lecteur échantillonnant la cellule du
+// module (col, row) avec halftoneWeights y lit ; à égalité, le sous-pixel
+// central, qui porte la valeur want, l'emporte
+func halftoneRead(cells *Matrix, col, row int, want bool) bool {
+	dark, total := 0, 0
+	for i, w := range halftoneWeights {
+		total += w
+		if cells.Get(col*HalftoneCell+i%HalftoneCell, row*HalftoneCell+i/HalftoneCell) {
+			dark += w
+		}
+	}
+	if 2*dark == total {
+		return want
+	}
+	return 2*dark > total
+}
+
+// halftoneMisread retourne les modules que le lecteur de halftoneRead lirait à l'envers
+func halftoneMisread(m, cells *Matrix) *Matrix {
+	misread := NewMatrix(m.size)
+	for row := 0; row < m.size; row++ {
+		for col := 0; col < m.size; col++ {
+			want := m.Get(col, row)
+			misread.Set(col, row, halftoneRead(cells, col, row, want) != want)
+		}
+	}
+	return misread
+}
+
+// checkHalftone vérifie que les modules lus à l'envers dans les sous-pixels ne
+// touchent pas plus de mots de code que le niveau de correction n'en restaure
+func checkHalftone(m, cells *Matrix, level string) error {
+	if lost, budget := codewordLoss(halftoneMisread(m, cells), level); lost > budget {
+		return fmt.Errorf("image tramée illisible : elle fausse %d mots de code, le niveau %s n'en restaure que %d",
+			lost, level, budget)
+	}
+	return nil
+}
+
+// CheckHalftone trame l'image sur le symbole et vérifie que le résultat reste
+// lisible par un lecteur qui échantillonne le centre de chaque module
+func CheckHalftone(s *Symbol, h *Halftone) error {
+	m := NewMatrixFromImage(s.Matrix)
+	cells, err := halftoneMatrix(m, h)
+	if err != nil {
+		return err
+	}
+	return checkHalftone(m, cells, s.ErrorCorrectionLevel)
+}
+
+// prepareHalftone remplace la matrice par ses sous-pixels tramés, après avoir
+// vérifié qu'ils restent lisibles, et adapte les options à cette grille plus
+// fine : zone calme, taille des modules et cadre sont multipliés ou divisés par
+// HalftoneCell pour garder les dimensions de l'image. L'échelle est arrondie au
+// multiple de HalftoneCell le plus proche.
+func prepareHalftone(m *Matrix, opts RenderOptions) (*Matrix, RenderOptions, error) {
+	if opts.Halftone == nil {
+		return m, opts, nil
+	}
+	if opts.Logo != nil || opts.eyesStyled() ||
+		(opts.ModuleShape != "" && opts.ModuleShape != ShapeSquare) ||
+		(opts.FunctionShape != "" && opts.FunctionShape != ShapeSquare) {
+		return nil, opts, fmt.Errorf("le mode tramé ne se combine pas avec un logo, des yeux personnalisés ou des formes de modules")
+	}
+	if opts.Inverted {
+		// Les points de la photo changeraient aussi de couleur : l'image serait en négatif
+		return nil, opts, fmt.Errorf("le mode tramé ne se combine pas avec la réflectance inversée")
+	}
+	cells, err := halftoneMatrix(m, opts.Halftone)
+	if err != nil {
+		return nil, opts, err
+	}
+	level, _, _ := readFormatInfo(m)
+	if err := checkHalftone(m, cells, level); err != nil {
+		return nil, opts, err
+	}
+
+	opts.QuietZone *= HalftoneCell
+	opts.Scale = max((opts.Scale+HalftoneCell/2)/HalftoneCell, 1)
+	opts.ModuleMM /= HalftoneCell
+	if opts.Frame != nil {
+		frame := *opts.Frame
+		border, radius, size := frame.dimensions()
+		frame.Border, frame.Radius, frame.TextSize = border*HalftoneCell, radius*HalftoneCell, size*HalftoneCell
+		frame.Padding *= HalftoneCell
+		opts.Frame = &frame
+	}
+	return cells, opts, nil
+}
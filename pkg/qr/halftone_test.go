@@ -0,0 +1,241 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+)
+
+// tentBlur floute l'image en niveaux de gris avec un noyau triangulaire de
+// radius pixels, comme l'optique d'un lecteur peu net
+func tentBlur(img image.Image, radius int) *image.Gray {
+	b := img.Bounds()
+	gray := image.NewGray(b)
+	draw.Draw(gray, b, img, b.Min, draw.Src)
+	pass := func(src *image.Gray, dx, dy int) *image.Gray {
+		dst := image.NewGray(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				sum, total := 0, 0
+				for d := 1 - radius; d < radius; d++ {
+					// Les bords de l'image répètent le pixel courant
+					p := image.Pt(x+d*dx, y+d*dy)
+					if !p.In(b) {
+						p = image.Pt(x, y)
+					}
+					w := radius - max(d, -d)
+					sum += w * int(src.GrayAt(p.X, p.Y).Y)
+					total += w
+				}
+				dst.SetGray(x, y, color.Gray{Y: uint8(sum / total)})
+			}
+		}
+		return dst
+	}
+	return pass(pass(gray, 1, 0), 0, 1)
+}
+
+// radialPhoto retourne une image de w×h pixels, sombre au centre et claire aux bords
+func radialPhoto(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(math.Min(0xFF, math.Hypot(float64(x-w/2), float64(y-h/2))*2.5))
+			img.SetNRGBA(x, y, color.NRGBA{R: v, G: v, B: v, A: 0xFF})
+		}
+	}
+	return img
+}
+
+func TestReadFormatInfo(t *testing.T) {
+	for _, level := range []string{"L", "M", "Q", "H"} {
+		for mask := 0; mask < 8; mask++ {
+			m := NewMatrix(25)
+			setFormatInfo(m, level, mask)
+			// Trois erreurs sur la première copie, corrigibles
+			for i := 0; i < 3; i++ {
+				x, y, _, _ := formatInfoCoords(i, m.size)
+				m.Set(x, y, !m.Get(x, y))
+			}
+			gotLevel, gotMask, ok := readFormatInfo(m)
+			if !ok || gotLevel != level || gotMask != mask {
+				t.Errorf("readFormatInfo(%s, %d) = %s, %d, %v", level, mask, gotLevel, gotMask, ok)
+			}
+		}
+	}
+
+	if _, _, ok := readFormatInfo(NewMatrix(25)); ok {
+		t.Error("une matrice vide ne devrait pas avoir d'information de format lisible")
+	}
+}
+
+func TestHalftoneMatrix(t *testing.T) {
+	tests := []struct {
+		name  string
+		level string
+		photo image.Image
+	}{
+		{"Photo, niveau L", "L", radialPhoto(200, 160)},
+		{"Photo, niveau H", "H", radialPhoto(160, 200)},
+		{"Image noire", "M", solidLogo(10, 10, color.Black)},
+		{"Image transparente", "Q", solidLogo(10, 10, color.Transparent)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbol, err := GenerateSymbol(0, "https://example.com/qrfactory", tt.level)
+			if err != nil {
+				t.Fatalf("GenerateSymbol() error = %v", err)
+			}
+			m := NewMatrixFromImage(symbol.Matrix)
+			cells, err := halftoneMatrix(m, &Halftone{Image: tt.photo})
+			if err != nil {
+				t.Fatalf("halftoneMatrix() error = %v", err)
+			}
+			if cells.Size() != m.size*HalftoneCell {
+				t.Fatalf("taille %d, attendu %d", cells.Size(), m.size*HalftoneCell)
+			}
+
+			for y := 0; y < cells.Size(); y++ {
+				for x := 0; x < cells.Size(); x++ {
+					col, row := x/HalftoneCell, y/HalftoneCell
+					center := x%HalftoneCell == 1 && y%HalftoneCell == 1
+					if (center || isFunctionPattern(col, row, m.size)) && cells.Get(x, y) != m.Get(col, row) {
+						t.Fatalf("sous-pixel (%d, %d) différent du module (%d, %d)", x, y, col, row)
+					}
+				}
+			}
+
+			// L'image ne consomme que la part tolérée de la capacité de correction
+			lost, budget := codewordLoss(halftoneMisread(m, cells), tt.level)
+			if lost > int(float64(budget)*halftoneMargin) {
+				t.Errorf("%d mots de code faussés, au plus %d tolérés", lost, int(float64(budget)*halftoneMargin))
+			}
+			if err := CheckHalftone(symbol, &Halftone{Image: tt.photo}); err != nil {
+				t.Errorf("CheckHalftone() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestHalftoneMatrixErrors(t *testing.T) {
+	symbol, err := GenerateSymbol(0, "https://example.com/qrfactory", "M")
+	if err != nil {
+		t.Fatalf("GenerateSymbol() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		m        *Matrix
+		halftone *Halftone
+	}{
+		{"Sans image", NewMatrixFromImage(symbol.Matrix), &Halftone{}},
+		{"Image vide", NewMatrixFromImage(symbol.Matrix), &Halftone{Image: image.NewNRGBA(image.Rect(0, 0, 0, 0))}},
+		{"Sans information de format", NewMatrixFromImage(benchmarkSymbol(2)), &Halftone{Image: radialPhoto(10, 10)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := halftoneMatrix(tt.m, tt.halftone); err == nil {
+				t.Error("halftoneMatrix() devrait échouer")
+			}
+		})
+	}
+}
+
+func TestRenderHalftone(t *testing.T) {
+	symbol, err := GenerateSymbol(2, "https://example.com/qrfactory", "H")
+	if err != nil {
+		t.Fatalf("GenerateSymbol() error = %v", err)
+	}
+	m := NewMatrixFromImage(symbol.Matrix)
+	opts := RenderOptions{QuietZone: 4, Scale: 9, Halftone: &Halftone{Image: radialPhoto(100, 100)}}
+
+	var buf bytes.Buffer
+	if err := RenderPNG(&buf, m, opts); err != nil {
+		t.Fatalf("RenderPNG() error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("PNG illisible : %v", err)
+	}
+	// Les dimensions restent celles du symbole à l'échelle demandée
+	if got, want := img.Bounds().Dx(), (m.size+8)*9; got != want {
+		t.Errorf("image de %d pixels, attendu %d", got, want)
+	}
+
+	buf.Reset()
+	if err := RenderSVG(&buf, m, opts); err != nil {
+		t.Fatalf("RenderSVG() error = %v", err)
+	}
+	side := (m.size + 8) * HalftoneCell
+	if want := fmt.Sprintf(`viewBox="0 0 %d %d" width="%d" height="%d"`, side, side, side*3, side*3); !strings.Contains(buf.String(), want) {
+		t.Errorf("document sans %q", want)
+	}
+
+	opts.ModuleShape = ShapeCircle
+	if err := RenderPNG(&bytes.Buffer{}, m, opts); err == nil {
+		t.Error("le mode tramé avec des modules ronds devrait être refusé")
+	}
+}
+
+func TestHalftoneReadMatrix(t *testing.T) {
+	const quietZone, scale = 4, 9
+	tests := []struct {
+		name    string
+		version int
+		level   string
+	}{
+		{"Version 2, niveau L", 2, "L"},
+		{"Version 2, niveau H", 2, "H"},
+		{"Version 5, niveau M", 5, "M"},
+		{"Version 8, niveau Q", 8, "Q"},
+		{"Version 8, niveau H", 8, "H"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbol, err := GenerateSymbol(tt.version, "https://example.com/qrfactory", tt.level)
+			if err != nil {
+				t.Fatalf("GenerateSymbol() error = %v", err)
+			}
+			m := NewMatrixFromImage(symbol.Matrix)
+			opts := RenderOptions{QuietZone: quietZone, Scale: scale, Halftone: &Halftone{Image: radialPhoto(100, 100)}}
+			var buf bytes.Buffer
+			if err := RenderPNG(&buf, m, opts); err != nil {
+				t.Fatalf("RenderPNG() error = %v", err)
+			}
+			img, err := png.Decode(&buf)
+			if err != nil {
+				t.Fatalf("PNG illisible : %v", err)
+			}
+
+			// Un lecteur net échantillonne le sous-pixel central et retrouve chaque module
+			got, inverted, err := ReadMatrix(img)
+			if err != nil || inverted {
+				t.Fatalf("ReadMatrix() = %v, inversé %v", err, inverted)
+			}
+			assertSameModules(t, got, m)
+
+			// Un léger flou mélange le centre et ses voisins : les modules lus à
+			// l'envers restent dans la capacité de correction
+			blurred := tentBlur(img, scale/HalftoneCell+1)
+			misread := NewMatrix(m.size)
+			for row := 0; row < m.size; row++ {
+				for col := 0; col < m.size; col++ {
+					x, y := (quietZone+col)*scale+scale/2, (quietZone+row)*scale+scale/2
+					misread.Set(col, row, (blurred.GrayAt(x, y).Y < 0x80) != m.Get(col, row))
+				}
+			}
+			if lost, budget := codewordLoss(misread, tt.level); lost > budget {
+				t.Errorf("%d mots de code faussés par le flou, le niveau %s n'en restaure que %d", lost, tt.level, budget)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"slices"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Formes de la plaque et du détourage du logo
+const (
+	LogoSquare  = "square"
+	LogoRounded = "rounded"
+	LogoCircle  = "circle"
+)
+
+// DefaultLogoSize est le côté du logo, en fraction du côté du symbole, si le
+// logo n'en précise pas
+const DefaultLogoSize = 0.2
+
+// logoCornerRatio est le rayon des coins de la forme arrondie, en fraction du côté
+const logoCornerRatio = 0.2
+
+// LogoShapes retourne la liste des formes de logo disponibles
+func LogoShapes() []string {
+	return []string{LogoSquare, LogoRounded, LogoCircle}
+}
+
+// Logo décrit une image posée au centre du symbole. Les modules qu'elle masque
+// sont perdus pour le lecteur et doivent être restaurés par la correction
+// d'erreur : voir CheckLogo et GenerateSymbolWithLogo.
+type Logo struct {
+	// Image du logo, réduite pour tenir dans son carré en gardant ses proportions
+	Image image.Image
+
+	// Côté du carré du logo en fraction du côté du symbole, zone calme exclue
+	// (DefaultLogoSize s'il est nul)
+	Size float64
+
+	// Marge entre le logo et le bord de sa plaque, en modules
+	Padding float64
+
+	// Effacer les modules sous le logo et sa marge, qui prennent la couleur du
+	// fond ; sinon le logo est dessiné par-dessus les modules
+	Plate bool
+
+	// Forme de la plaque et du détourage du logo, parmi LogoShapes ; LogoSquare
+	// si elle est vide
+	Shape string
+}
+
+// logoLayout décrit la place du logo dans le symbole, en modules depuis le
+// coin haut gauche du symbole
+type logoLayout struct {
+	logo  *Logo
+	image moduleGeometry
+	plate moduleGeometry
+}
+
+// newLogoLayout valide le logo et le centre dans un symbole de size modules
+func newLogoLayout(l *Logo, size int) (*logoLayout, error) {
+	if l.Image == nil || l.Image.Bounds().Empty() {
+		return nil, fmt.Errorf("logo sans image")
+	}
+	relative := l.Size
+	if relative == 0 {
+		relative = DefaultLogoSize
+	}
+	if relative < 0 || relative >= 1 {
+		return nil, fmt.Errorf("taille de logo invalide : %g (attendu une fraction du symbole entre 0 et 1)", relative)
+	}
+	if l.Padding < 0 {
+		return nil, fmt.Errorf("marge de logo invalide : %g modules", l.Padding)
+	}
+	shape := strings.ToLower(l.Shape)
+	if shape == "" {
+		shape = LogoSquare
+	}
+	if !slices.Contains(LogoShapes(), shape) {
+		return nil, fmt.Errorf("forme de logo inconnue : %q (attendu %s)", l.Shape, strings.Join(LogoShapes(), ", "))
+	}
+
+	center := float64(size) / 2
+	side := relative * float64(size)
+	return &logoLayout{
+		logo:  l,
+		image: logoGeometry(shape, center, side),
+		plate: logoGeometry(shape, center, side+2*l.Padding),
+	}, nil
+}
+
+// logoGeometry retourne un carré de côté side centré en (center, center)
+func logoGeometry(shape string, center, side float64) moduleGeometry {
+	g := moduleGeometry{x0: center - side/2, y0: center - side/2, x1: center + side/2, y1: center + side/2}
+	switch shape {
+	case LogoRounded:
+		r := side * logoCornerRatio
+		g.radii = [4]float64{r, r, r, r}
+	case LogoCircle:
+		r := side / 2
+		g.radii = [4]float64{r, r, r, r}
+	}
+	return g
+}
+
+// masked retourne la zone dont les modules sont perdus : la plaque, ou le
+// carré du logo lorsqu'il est dessiné sur les modules
+func (l *logoLayout) masked() moduleGeometry {
+	if l.logo.Plate {
+		return l.plate
+	}
+	// Sans plaque, la transparence du logo n'est pas analysée : tout son carré compte
+	g := l.image
+	g.radii = [4]float64{}
+	return g
+}
+
+// cover retourne les modules touchés par la zone masquée. Une erreur est
+// retournée si elle atteint les motifs de repérage, de synchronisation, de
+// format ou de version, sans lesquels le symbole ne peut pas être localisé.
+func (l *logoLayout) cover(size int) (*Matrix, error) {
+	g := l.masked()
+	covered := NewMatrix(size)
+	first, last := max(int(math.Floor(g.x0)), 0), min(int(math.Ceil(g.x1)), size)
+	step := 1 / float64(shapeSamples)
+	for y := first; y < last; y++ {
+		for x := first; x < last; x++ {
+			hit := false
+			for s := 0; s < shapeSamples*shapeSamples && !hit; s++ {
+				hit = g.contains(float64(x)+(float64(s%shapeSamples)+0.5)*step, float64(y)+(float64(s/shapeSamples)+0.5)*step)
+			}
+			if !hit {
+				continue
+			}
+			if logoReserved(x, y, size) {
+				return nil, fmt.Errorf("le logo couvre le module (%d, %d) des motifs de repérage, de synchronisation ou de format", x, y)
+			}
+			covered.Set(x, y, true)
+		}
+	}
+	return covered, nil
+}
+
+// logoReserved indique si le module (x, y) appartient aux motifs que le logo
+// ne doit jamais masquer : repérage et séparateurs, format, synchronisation et
+// version. Les motifs d'alignement, redondants, peuvent l'être.
+func logoReserved(x, y, size int) bool {
+	if (x < 9 && y < 9) || (x >= size-8 && y < 9) || (x < 9 && y >= size-8) {
+		return true
+	}
+	if x == 6 || y == 6 {
+		return true
+	}
+	if (size-17)/4 >= 7 {
+		return (x >= size-11 && x <= size-9 && y < 6) || (y >= size-11 && y <= size-9 && x < 6)
+	}
+	return false
+}
+
+// dataOrder retourne les positions des modules de données dans l'ordre où
+// PlaceData les remplit : le bit i du flux appartient au mot de code i/8
+func dataOrder(size int) []image.Point {
+	var order []image.Point
+	upward := true
+	for x := size - 1; x >= 0; x -= 2 {
+		for dx := 0; dx <= 1 && x-dx >= 0; dx++ {
+			col := x - dx
+			if col == 6 {
+				continue
+			}
+			for i := 0; i < size; i++ {
+				y := i
+				if upward {
+					y = size - 1 - i
+				}
+				if isValidDataPosition(col, y, size) {
+					order = append(order, image.Point{X: col, Y: y})
+				}
+			}
+			upward = !upward
+		}
+	}
+	return order
+}
+
+// codewordBlocks retourne le bloc Reed-Solomon de chaque mot de code, dans
+// l'ordre de placement d'un symbole de size modules, et le nombre de mots
+// faussés que chaque bloc corrige au niveau donné : la moitié de ses mots de
+// correction, moins ceux réservés contre les fausses lectures. Les blocs courts
+// précèdent les blocs longs ; les mots de données puis de correction sont
+// entrelacés bloc par bloc, comme AddErrorCorrectionEC les écrit. owner est nil
+// si la taille ou le niveau sont invalides.
+func codewordBlocks(size int, level string) (owner []int, budget int) {
+	version := (size - 17) / 4
+	if version < 1 || version > 40 || size != 4*version+17 {
+		return nil, 0
+	}
+	count, ec := ECBlockCount[level][version], ECCodewordsPerBlock[level][version]
+	if count == 0 {
+		return nil, 0
+	}
+	data := dataCodewords(version, level)
+	short, long := data/count, data%count
+
+	for i := 0; i <= short; i++ {
+		for b := 0; b < count; b++ {
+			// Seuls les derniers blocs ont un mot de données de plus
+			if i < short || b >= count-long {
+				owner = append(owner, b)
+			}
+		}
+	}
+	for i := 0; i < ec; i++ {
+		for b := 0; b < count; b++ {
+			owner = append(owner, b)
+		}
+	}
+	return owner, (ec - misdecodeProtection[version][level]) / 2
+}
+
+// codewordLoss retourne le plus grand nombre de mots de code d'un même bloc
+// touchés par les modules faussés (masqués par un logo, mal lus dans une image
+// tramée) et le nombre de mots que le niveau de correction restaure par bloc.
+// Un seul bloc au-delà de sa capacité rend le symbole illisible.
+func codewordLoss(covered *Matrix, level string) (lost, budget int) {
+	owner, budget := codewordBlocks(covered.size, level)
+	touched := make(map[int]bool)
+	perBlock := make(map[int]int)
+	for i, p := range dataOrder(covered.size) {
+		c := i / 8
+		if !covered.Get(p.X, p.Y) || touched[c] {
+			continue
+		}
+		touched[c] = true
+		if owner == nil {
+			// Symbole inconnu : aucune perte n'est tolérée
+			lost++
+		} else if c < len(owner) {
+			// Les bits de reste, après le dernier mot complet, n'appartiennent à aucun bloc
+			perBlock[owner[c]]++
+			lost = max(lost, perBlock[owner[c]])
+		}
+	}
+	return lost, budget
+}
+
+// symbolCoverage retourne les modules du symbole masqués par le logo
+func symbolCoverage(s *Symbol, l *Logo) (*Matrix, error) {
+	size := s.Matrix.Bounds().Dx()
+	layout, err := newLogoLayout(l, size)
+	if err != nil {
+		return nil, err
+	}
+	return layout.cover(size)
+}
+
+// CheckLogo vérifie que le logo, centré sur le symbole, laisse intacts les
+// motifs de repérage, de synchronisation et de format, et que les mots de code
+// qu'il masque restent dans la capacité de correction du niveau du symbole
+func CheckLogo(s *Symbol, l *Logo) error {
+	covered, err := symbolCoverage(s, l)
+	if err != nil {
+		return err
+	}
+	if lost, budget := codewordLoss(covered, s.ErrorCorrectionLevel); lost > budget {
+		return fmt.Errorf("logo trop grand : il masque %d mots de code d'un même bloc, le niveau %s n'en restaure que %d par bloc",
+			lost, s.ErrorCorrectionLevel, budget)
+	}
+	return nil
+}
+
+// GenerateSymbolWithLogo génère le symbole en relevant si nécessaire le niveau
+// de correction (L, M, Q puis H) jusqu'à ce que le logo reste lisible. Une
+// erreur est retournée si le logo touche les motifs de fonction protégés ou si
+// même le niveau H ne suffit pas.
+func GenerateSymbolWithLogo(version int, data string, errorCorrectionLevel string, l *Logo) (*Symbol, error) {
+	levels := []string{"L", "M", "Q", "H"}
+	start := slices.Index(levels, errorCorrectionLevel)
+	if start < 0 {
+		// Même repli que GenerateSymbol
+		start = slices.Index(levels, "M")
+	}
+
+	var lastErr error
+	for _, level := range levels[start:] {
+		symbol, err := GenerateSymbol(version, data, level)
+		if err != nil {
+			return nil, err
+		}
+		// Un motif protégé touché ne dépend pas du niveau : inutile d'insister
+		if _, err := symbolCoverage(symbol, l); err != nil {
+			return nil, err
+		}
+		if lastErr = CheckLogo(symbol, l); lastErr == nil {
+			if level != levels[start] {
+				logf("Niveau de correction relevé de %s à %s pour le logo\n", levels[start], level)
+			}
+			return symbol, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// prepareLogo valide le logo des options et retourne la matrice à dessiner,
+// privée des modules sous la plaque, avec la disposition du logo (nil sans logo).
+// Comme CheckLogo, il refuse un logo masquant plus de mots de code que le niveau
+// de correction, lu dans l'information de format, n'en restaure.
+func prepareLogo(m *Matrix, opts RenderOptions) (*Matrix, *logoLayout, error) {
+	if opts.Logo == nil {
+		return m, nil, nil
+	}
+	layout, err := newLogoLayout(opts.Logo, m.size)
+	if err != nil {
+		return nil, nil, err
+	}
+	covered, err := layout.cover(m.size)
+	if err != nil {
+		return nil, nil, err
+	}
+	level, _, ok := readFormatInfo(m)
+	if !ok {
+		return nil, nil, fmt.Errorf("information de format illisible, niveau de correction inconnu pour le logo")
+	}
+	if lost, budget := codewordLoss(covered, level); lost > budget {
+		return nil, nil, fmt.Errorf("logo trop grand : il masque %d mots de code d'un même bloc, le niveau %s n'en restaure que %d par bloc",
+			lost, level, budget)
+	}
+	if !opts.Logo.Plate {
+		return m, layout, nil
+	}
+
+	out := m.Clone()
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if covered.Get(x, y) {
+				out.Set(x, y, false)
+			}
+		}
+	}
+	return out, layout, nil
+}
+
+// fit retourne le rectangle, en modules, où l'image est dessinée : centrée
+// dans le carré du logo en gardant ses proportions
+func (l *logoLayout) fit() (x, y, w, h float64) {
+	b := l.logo.Image.Bounds()
+	side := l.image.x1 - l.image.x0
+	w, h = side, side
+	if b.Dx() > b.Dy() {
+		h = side * float64(b.Dy()) / float64(b.Dx())
+	} else {
+		w = side * float64(b.Dx()) / float64(b.Dy())
+	}
+	return l.image.x0 + (side-w)/2, l.image.y0 + (side-h)/2, w, h
+}
+
+// draw pose le logo sur l'image du symbole, détouré par sa forme et anticrénelé.
+// L'image est copiée en RVBA pour accueillir les couleurs du logo.
+func (l *logoLayout) draw(img image.Image, m *Matrix, opts RenderOptions) (*image.NRGBA, error) {
+	layout, err := newRasterLayout(m, opts)
+	if err != nil {
+		return nil, err
+	}
+	origin := float64(layout.offset + opts.QuietZone*layout.module)
+	module := float64(layout.module)
+
+	out := image.NewNRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	x, y, w, h := l.fit()
+	dst := image.Rect(
+		int(math.Round(origin+x*module)), int(math.Round(origin+y*module)),
+		int(math.Round(origin+(x+w)*module)), int(math.Round(origin+(y+h)*module)),
+	)
+	if dst.Empty() {
+		return out, nil
+	}
+	scaled := image.NewNRGBA(dst)
+	xdraw.CatmullRom.Scale(scaled, dst, l.logo.Image, l.logo.Image.Bounds(), xdraw.Src, nil)
+
+	// Masque de détourage, sous-échantillonné comme les formes des modules
+	mask := image.NewAlpha(dst)
+	levels := shapeSamples * shapeSamples
+	for py := dst.Min.Y; py < dst.Max.Y; py++ {
+		for px := dst.Min.X; px < dst.Max.X; px++ {
+			n := 0
+			for s := 0; s < levels; s++ {
+				sx := (float64(px) + (float64(s%shapeSamples)+0.5)/shapeSamples - origin) / module
+				sy := (float64(py) + (float64(s/shapeSamples)+0.5)/shapeSamples - origin) / module
+				if l.image.contains(sx, sy) {
+					n++
+				}
+			}
+			mask.Pix[mask.PixOffset(px, py)] = uint8(n * 0xFF / levels)
+		}
+	}
+	draw.DrawMask(out, dst, scaled, dst.Min, mask, dst.Min, draw.Over)
+	return out, nil
+}
@@ -0,0 +1,437 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// solidLogo retourne une image unie de w×h pixels
+func solidLogo(w, h int, c color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestLogoCover(t *testing.T) {
+	logo := solidLogo(4, 4, color.Black)
+
+	tests := []struct {
+		name    string
+		logo    Logo
+		wantErr bool
+	}{
+		{"Taille par défaut", Logo{Image: logo}, false},
+		{"Plaque ronde", Logo{Image: logo, Size: 0.25, Padding: 1, Plate: true, Shape: "Circle"}, false},
+		{"Motif de synchronisation atteint", Logo{Image: logo, Size: 0.5}, true},
+		{"Marge jusqu'aux motifs de repérage", Logo{Image: logo, Size: 0.2, Padding: 4, Plate: true}, true},
+		{"Sans image", Logo{}, true},
+		{"Taille négative", Logo{Image: logo, Size: -0.1}, true},
+		{"Marge négative", Logo{Image: logo, Padding: -1}, true},
+		{"Forme inconnue", Logo{Image: logo, Shape: "star"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := newLogoLayout(&tt.logo, 25)
+			if err == nil {
+				_, err = layout.cover(25)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("cover() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDataOrder(t *testing.T) {
+	for _, size := range []int{21, 25, 45} {
+		order := dataOrder(size)
+		seen := make(map[image.Point]bool)
+		for _, p := range order {
+			if seen[p] || !isValidDataPosition(p.X, p.Y, size) {
+				t.Fatalf("taille %d : position (%d, %d) invalide ou répétée", size, p.X, p.Y)
+			}
+			seen[p] = true
+		}
+		if got, want := len(order), countDataPositions(size); got != want {
+			t.Errorf("taille %d : %d positions, attendu %d", size, got, want)
+		}
+	}
+}
+
+func TestCodewordBlocks(t *testing.T) {
+	tests := []struct {
+		version    int
+		level      string
+		wantTotal  int
+		wantData   int
+		wantBudget int
+	}{
+		{1, "L", 26, 19, 2},
+		{1, "M", 26, 16, 4},
+		{1, "Q", 26, 13, 6},
+		{1, "H", 26, 9, 8},
+		{2, "L", 44, 34, 4},
+		{3, "L", 70, 55, 7},
+		{5, "Q", 134, 62, 9},
+		{7, "M", 196, 124, 9},
+		{40, "L", 3706, 2956, 15},
+		{40, "H", 3706, 1276, 15},
+	}
+
+	for _, tt := range tests {
+		size := 4*tt.version + 17
+		owner, budget := codewordBlocks(size, tt.level)
+		if len(owner) != tt.wantTotal || budget != tt.wantBudget {
+			t.Errorf("version %d-%s : %d mots, %d corrigibles par bloc, attendu %d et %d",
+				tt.version, tt.level, len(owner), budget, tt.wantTotal, tt.wantBudget)
+			continue
+		}
+		ec := ECBlockCount[tt.level][tt.version] * ECCodewordsPerBlock[tt.level][tt.version]
+		if data := len(owner) - ec; data != tt.wantData {
+			t.Errorf("version %d-%s : %d mots de données, attendu %d", tt.version, tt.level, data, tt.wantData)
+		}
+		// Chaque bloc reçoit ses mots de correction et au plus un mot de données de plus qu'un autre
+		sizes := make(map[int]int)
+		for _, b := range owner {
+			sizes[b]++
+		}
+		smallest, largest := len(owner), 0
+		for _, n := range sizes {
+			smallest, largest = min(smallest, n), max(largest, n)
+		}
+		if len(sizes) != ECBlockCount[tt.level][tt.version] || largest-smallest > 1 {
+			t.Errorf("version %d-%s : blocs de %d à %d mots, %d blocs", tt.version, tt.level, smallest, largest, len(sizes))
+		}
+	}
+
+	if owner, _ := codewordBlocks(22, "L"); owner != nil {
+		t.Error("une taille invalide ne devrait pas avoir de blocs")
+	}
+	if owner, _ := codewordBlocks(21, "X"); owner != nil {
+		t.Error("un niveau invalide ne devrait pas avoir de blocs")
+	}
+}
+
+func TestCodewordLoss(t *testing.T) {
+	// Version 5-Q : 4 blocs corrigeant chacun 9 mots, entrelacés mot par mot
+	const size = 37
+	owner, _ := codewordBlocks(size, "Q")
+	order := dataOrder(size)
+	damage := func(codewords []int) *Matrix {
+		m := NewMatrix(size)
+		for _, c := range codewords {
+			p := order[8*c]
+			m.Set(p.X, p.Y, true)
+		}
+		return m
+	}
+	inBlock := func(block, n int) []int {
+		var codewords []int
+		for c, b := range owner {
+			if b == block && len(codewords) < n {
+				codewords = append(codewords, c)
+			}
+		}
+		return codewords
+	}
+
+	tests := []struct {
+		name      string
+		codewords []int
+		level     string
+		wantLost  int
+		wantErr   bool
+	}{
+		{"Aucun dégât", nil, "Q", 0, false},
+		{"Dégâts répartis", []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31}, "Q", 8, false},
+		{"Bloc saturé", inBlock(2, 9), "Q", 9, false},
+		{"Un seul bloc au-delà", inBlock(2, 10), "Q", 10, true},
+		{"Niveau inconnu", []int{0, 1}, "X", 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lost, budget := codewordLoss(damage(tt.codewords), tt.level)
+			if lost != tt.wantLost || (lost > budget) != tt.wantErr {
+				t.Errorf("codewordLoss() = %d perdus pour %d corrigibles, attendu %d perdus, illisible %v",
+					lost, budget, tt.wantLost, tt.wantErr)
+			}
+		})
+	}
+}
+
+// countDataPositions compte les positions de données valides
+func countDataPositions(size int) int {
+	n := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if isValidDataPosition(x, y, size) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// decodeSymbol décode une matrice lue comme un lecteur : information de format,
+// masque retiré, mots de code relus dans l'ordre de placement, blocs
+// désentrelacés et corrigés, puis segment octet. Retourne les données et le plus
+// grand nombre de mots corrigés dans un même bloc.
+func decodeSymbol(t *testing.T, m *Matrix) (data string, corrected int) {
+	t.Helper()
+	level, mask, ok := readFormatInfo(m)
+	if !ok {
+		t.Fatal("information de format illisible")
+	}
+	unmasked := m.Clone()
+	unmasked.xor(getMaskPlanes(m.size)[mask])
+
+	version := (m.size - 17) / 4
+	codewords := make([]byte, totalCodewords(version))
+	for i, p := range dataOrder(m.size) {
+		if i/8 < len(codewords) && unmasked.Get(p.X, p.Y) {
+			codewords[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+
+	count, ecWords := ECBlockCount[level][version], ECCodewordsPerBlock[level][version]
+	short, long := dataCodewords(version, level)/count, dataCodewords(version, level)%count
+	blocks := make([][]byte, count)
+	next := 0
+	for i := 0; i <= short; i++ {
+		for b := range blocks {
+			if i < short || b >= count-long {
+				blocks[b] = append(blocks[b], codewords[next])
+				next++
+			}
+		}
+	}
+	for i := 0; i < ecWords; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[next])
+			next++
+		}
+	}
+
+	var stream []byte
+	for b, block := range blocks {
+		n, ok := rsCorrect(block, ecWords)
+		if !ok {
+			t.Fatalf("bloc %d de la version %d-%s impossible à corriger", b, version, level)
+		}
+		corrected = max(corrected, n)
+		stream = append(stream, block[:len(block)-ecWords]...)
+	}
+
+	bit := func(i int) int { return int(stream[i/8]>>(7-i%8)) & 1 }
+	read := func(from, n int) int {
+		v := 0
+		for i := from; i < from+n; i++ {
+			v = v<<1 | bit(i)
+		}
+		return v
+	}
+	if mode := read(0, 4); mode != 0b0100 {
+		t.Fatalf("mode %04b, attendu le mode octet", mode)
+	}
+	lengthBits := 8
+	if version >= 10 {
+		lengthBits = 16
+	}
+	length := read(4, lengthBits)
+	buf := make([]byte, length)
+	for i := range buf {
+		buf[i] = byte(read(4+lengthBits+8*i, 8))
+	}
+	return string(buf), corrected
+}
+
+func TestGenerateSymbolWithLogo(t *testing.T) {
+	short := "https://example.com/qrfactory"
+	medium := "https://example.com/" + strings.Repeat("a", 30)
+	long := "https://example.com/" + strings.Repeat("a", 100)
+	logo := solidLogo(4, 4, color.Black)
+
+	tests := []struct {
+		name      string
+		data      string
+		level     string
+		logo      Logo
+		wantLevel string
+		wantErr   bool
+	}{
+		{"Petit logo, niveau conservé", short, "H", Logo{Image: logo, Size: 0.1}, "H", false},
+		{"Niveau relevé", short, "L", Logo{Image: logo, Size: 0.2, Padding: 1, Plate: true}, "M", false},
+		{"Niveau relevé de deux crans", medium, "L", Logo{Image: logo, Size: 0.15, Padding: 1, Plate: true}, "Q", false},
+		{"Niveau invalide, repli sur M puis relevé", medium, "X", Logo{Image: logo, Size: 0.15, Padding: 1, Plate: true}, "Q", false},
+		{"Logo trop grand même au niveau H", long, "H", Logo{Image: logo, Size: 0.5}, "", true},
+		{"Motifs de repérage atteints", short, "H", Logo{Image: logo, Size: 0.6, Padding: 1, Plate: true}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbol, err := GenerateSymbolWithLogo(0, tt.data, tt.level, &tt.logo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateSymbolWithLogo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if symbol.ErrorCorrectionLevel != tt.wantLevel {
+				t.Errorf("niveau %s, attendu %s", symbol.ErrorCorrectionLevel, tt.wantLevel)
+			}
+			if err := CheckLogo(symbol, &tt.logo); err != nil {
+				t.Errorf("CheckLogo() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestLogoSymbolDecodes(t *testing.T) {
+	data := "https://example.com/qrfactory?utm_source=logo"
+	tests := []struct {
+		name    string
+		version int
+		level   string
+		logo    Logo
+	}{
+		{"Version 7, plaque", 7, "M", Logo{Image: solidLogo(20, 10, color.NRGBA{R: 0xFF, A: 0xFF}), Size: 0.2, Padding: 1, Plate: true}},
+		{"Version 7, logo noir sans plaque", 7, "Q", Logo{Image: solidLogo(10, 10, color.Black), Size: 0.2}},
+		{"Version 10, blocs de tailles inégales", 10, "M", Logo{Image: solidLogo(10, 10, color.Black), Size: 0.2, Padding: 1, Plate: true, Shape: LogoCircle}},
+		{"Version 15, niveau H", 15, "H", Logo{Image: solidLogo(10, 10, color.White), Size: 0.25, Padding: 1, Plate: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbol, err := GenerateSymbolWithLogo(tt.version, data, tt.level, &tt.logo)
+			if err != nil {
+				t.Fatalf("GenerateSymbolWithLogo() error = %v", err)
+			}
+			var buf bytes.Buffer
+			opts := RenderOptions{QuietZone: 4, Scale: 4, Logo: &tt.logo}
+			if err := RenderPNG(&buf, NewMatrixFromImage(symbol.Matrix), opts); err != nil {
+				t.Fatalf("RenderPNG() error = %v", err)
+			}
+			img, err := png.Decode(&buf)
+			if err != nil {
+				t.Fatalf("PNG illisible : %v", err)
+			}
+			m, _, err := ReadMatrix(img)
+			if err != nil {
+				t.Fatalf("ReadMatrix() error = %v", err)
+			}
+
+			got, corrected := decodeSymbol(t, m)
+			if got != data {
+				t.Errorf("données %q, attendu %q", got, data)
+			}
+			// Le logo fausse des mots de code, sans dépasser ce que CheckLogo a compté
+			covered, _ := symbolCoverage(symbol, &tt.logo)
+			lost, budget := codewordLoss(covered, symbol.ErrorCorrectionLevel)
+			if corrected == 0 || corrected > lost || lost > budget {
+				t.Errorf("%d mots corrigés dans un bloc, %d masqués, %d corrigibles", corrected, lost, budget)
+			}
+		})
+	}
+}
+
+func TestRenderPNGLogo(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	setFormatInfo(m, "H", 0)
+	red := color.NRGBA{R: 0xFF, A: 0xFF}
+	opts := RenderOptions{QuietZone: 4, Scale: 10, Logo: &Logo{Image: solidLogo(20, 10, red), Size: 0.2, Padding: 1, Plate: true}}
+
+	var buf bytes.Buffer
+	if err := RenderPNG(&buf, m, opts); err != nil {
+		t.Fatalf("RenderPNG() error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("PNG illisible : %v", err)
+	}
+
+	// Symbole de 25 modules : logo de 5 modules sur 2,5, centré en 12,5 modules
+	// après une zone calme de 4 modules
+	center := 4*10 + 125
+	tests := []struct {
+		name string
+		x, y int
+		want color.NRGBA
+	}{
+		{"Centre du logo", center, center, red},
+		{"Bande libre au-dessus du logo", center, center - 20, color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		{"Marge de la plaque", center - 30, center, color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := color.NRGBAModel.Convert(img.At(tt.x, tt.y)); got != tt.want {
+				t.Errorf("pixel (%d, %d) = %v, attendu %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+
+	for _, format := range []string{FormatJPEG, FormatGIF, FormatBMP, FormatTIFF} {
+		if err := Render(&bytes.Buffer{}, format, m, opts); err != nil {
+			t.Errorf("Render(%s) error = %v", format, err)
+		}
+	}
+}
+
+func TestRenderLogoLevel(t *testing.T) {
+	logo := &Logo{Image: solidLogo(4, 4, color.Black), Size: 0.25, Padding: 1}
+	withFormat := func(level string) *Matrix {
+		m := NewMatrixFromImage(benchmarkSymbol(3))
+		setFormatInfo(m, level, 0)
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		m       *Matrix
+		wantErr bool
+	}{
+		{"Niveau H suffisant", withFormat("H"), false},
+		{"Niveau L insuffisant", withFormat("L"), true},
+		{"Information de format illisible", NewMatrixFromImage(benchmarkSymbol(3)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := RenderOptions{QuietZone: 4, Scale: 2, Logo: logo}
+			if err := RenderPNG(&bytes.Buffer{}, tt.m, opts); (err != nil) != tt.wantErr {
+				t.Errorf("RenderPNG() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := RenderSVG(&bytes.Buffer{}, tt.m, opts); (err != nil) != tt.wantErr {
+				t.Errorf("RenderSVG() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderSVGLogo(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	setFormatInfo(m, "H", 0)
+	opts := RenderOptions{QuietZone: 4, IDPrefix: "qr-", Logo: &Logo{Image: solidLogo(4, 4, color.Black), Plate: true, Shape: LogoCircle}}
+
+	var buf bytes.Buffer
+	if err := RenderSVG(&buf, m, opts); err != nil {
+		t.Fatalf("RenderSVG() error = %v", err)
+	}
+	doc := buf.String()
+	for _, want := range []string{`xmlns:xlink=`, `<clipPath id="qr-logo">`, `clip-path="url(#qr-logo)"`, `xlink:href="data:image/png;base64,`} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document sans %q", want)
+		}
+	}
+
+	opts.Logo.Size = 0.9
+	if err := RenderSVG(&buf, m, opts); err == nil {
+		t.Error("un logo couvrant les motifs de repérage devrait être refusé")
+	}
+}
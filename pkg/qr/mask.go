@@ -3,59 +3,229 @@ package qr
 import (
 	"image"
 	"image/color"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
 )
 
+// maskPlanes contient, pour une taille de symbole, les 8 plans XOR des masques
+// restreints aux modules de données : appliquer un masque revient à un XOR mot à mot
+type maskPlanes [8]*Matrix
+
+var (
+	// Plans de masque déjà calculés, par taille de symbole
+	maskPlanesCache = make(map[int]*maskPlanes)
+
+	// Mutex pour protéger l'accès au cache des plans de masque
+	maskPlanesMutex sync.RWMutex
+
+	// Tampons de travail réutilisés d'un appel à l'autre
+	maskScratchPool = sync.Pool{New: func() any { return &maskScratch{} }}
+)
+
+// maskScratch regroupe les tampons nécessaires à l'évaluation d'un candidat
+type maskScratch struct {
+	candidate Matrix
+	line      []bool
+}
+
+// acquireMaskScratch récupère des tampons de travail dimensionnés pour size modules
+func acquireMaskScratch(size int) *maskScratch {
+	s := maskScratchPool.Get().(*maskScratch)
+	s.candidate.reset(size)
+	if cap(s.line) < size {
+		s.line = make([]bool, size)
+	}
+	return s
+}
+
+// releaseMaskScratch rend les tampons de travail au pool
+func releaseMaskScratch(s *maskScratch) {
+	maskScratchPool.Put(s)
+}
+
+// getMaskPlanes retourne les plans de masque pour la taille donnée, calculés une seule fois
+func getMaskPlanes(size int) *maskPlanes {
+	maskPlanesMutex.RLock()
+	planes, ok := maskPlanesCache[size]
+	maskPlanesMutex.RUnlock()
+	if ok {
+		return planes
+	}
+
+	planes = &maskPlanes{}
+	for mask := range planes {
+		plane := NewMatrix(size)
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				// Ne pas masquer les motifs de fonction
+				if !isFunctionPattern(x, y, size) && maskBit(mask, x, y) {
+					plane.Set(x, y, true)
+				}
+			}
+		}
+		planes[mask] = plane
+	}
+
+	maskPlanesMutex.Lock()
+	defer maskPlanesMutex.Unlock()
+	if cached, ok := maskPlanesCache[size]; ok {
+		return cached
+	}
+	maskPlanesCache[size] = planes
+	return planes
+}
+
+// maskBit indique si le module (x, y) doit être inversé par le motif de masque
+// Définition officielle des masques selon le standard ISO/IEC 18004:2015
+func maskBit(maskPattern, x, y int) bool {
+	switch maskPattern {
+	case 0: // (x+y) mod 2 == 0
+		return (x+y)%2 == 0
+	case 1: // y mod 2 == 0
+		return y%2 == 0
+	case 2: // x mod 3 == 0
+		return x%3 == 0
+	case 3: // (x+y) mod 3 == 0
+		return (x+y)%3 == 0
+	case 4: // (floor(y/2) + floor(x/3)) mod 2 == 0
+		return ((y/2)+(x/3))%2 == 0
+	case 5: // (x*y) mod 2 + (x*y) mod 3 == 0
+		return ((x*y)%2 + (x*y)%3) == 0
+	case 6: // ((x*y) mod 2 + (x*y) mod 3) mod 2 == 0
+		return ((x*y)%2+(x*y)%3)%2 == 0
+	case 7: // ((x+y) mod 2 + (x*y) mod 3) mod 2 == 0
+		return ((x+y)%2+(x*y)%3)%2 == 0
+	}
+	return false
+}
+
+// setFormatInfo place l'information de format dans une matrice compacte,
+// aux mêmes positions que AddFormatInfo
+func setFormatInfo(m *Matrix, ecLevel string, maskPattern int) {
+	formatInfoBits := FormatInfo[ecLevel][maskPattern]
+	for i := 0; i < 15; i++ {
+		bit := formatInfoBits[i] == '1'
+		x1, y1, x2, y2 := formatInfoCoords(i, m.size)
+		m.Set(x1, y1, bit)
+		m.Set(x2, y2, bit)
+	}
+}
+
+// readFormatInfo lit l'information de format de la matrice et retourne le niveau
+// de correction et le masque de la combinaison la plus proche de l'une des deux
+// copies ; ok est faux si elle diffère de plus de trois bits, au-delà de ce que
+// le code BCH corrige
+func readFormatInfo(m *Matrix) (ecLevel string, maskPattern int, ok bool) {
+	best := 4
+	for level, masks := range FormatInfo {
+		for mask, bits := range masks {
+			var distance [2]int
+			for i := 0; i < 15; i++ {
+				x1, y1, x2, y2 := formatInfoCoords(i, m.size)
+				bit := bits[i] == '1'
+				if m.Get(x1, y1) != bit {
+					distance[0]++
+				}
+				if m.Get(x2, y2) != bit {
+					distance[1]++
+				}
+			}
+			if d := min(distance[0], distance[1]); d < best {
+				best, ecLevel, maskPattern = d, level, mask
+			}
+		}
+	}
+	return ecLevel, maskPattern, best < 4
+}
+
+// evaluateMasks calcule le score des 8 candidats, informations de format placées,
+// avec au plus workers goroutines. Chaque goroutine réutilise ses propres tampons.
+func evaluateMasks(base *Matrix, ecLevel string, workers int) [8]int {
+	planes := getMaskPlanes(base.size)
+	var scores [8]int
+	var next atomic.Int32
+	var wg sync.WaitGroup
+
+	workers = max(1, min(workers, len(planes)))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scratch := acquireMaskScratch(base.size)
+			defer releaseMaskScratch(scratch)
+
+			for {
+				mask := int(next.Add(1)) - 1
+				if mask >= len(planes) {
+					return
+				}
+				candidate := &scratch.candidate
+				candidate.copyFrom(base)
+				candidate.xor(planes[mask])
+				setFormatInfo(candidate, ecLevel, mask)
+				scores[mask] = penaltyScore(penaltyRules(candidate, scratch))
+			}
+		}()
+	}
+	wg.Wait()
+
+	return scores
+}
+
+// selectBestMask applique les 8 masques à la matrice, place les informations de
+// format de chaque candidat puis retourne le symbole de plus faible pénalité,
+// avec son masque et son score
+func selectBestMask(matrix *image.RGBA, ecLevel string) (*image.RGBA, int, int) {
+	base := NewMatrixFromImage(matrix)
+	scores := evaluateMasks(base, ecLevel, runtime.GOMAXPROCS(0))
+
+	bestScore := math.MaxInt32
+	bestMask := 0
+
+	logf("Évaluation des masques:\n")
+	for mask, score := range scores {
+		logf("  Masque %d: score %d\n", mask, score)
+
+		if score < bestScore {
+			bestScore = score
+			bestMask = mask
+		}
+	}
+
+	logf("Meilleur masque sélectionné: %d (score: %d)\n", bestMask, bestScore)
+
+	// Reconstruire le candidat retenu plutôt que de conserver les 8 symboles
+	base.xor(getMaskPlanes(base.size)[bestMask])
+	setFormatInfo(base, ecLevel, bestMask)
+
+	return base.Image(), bestMask, bestScore
+}
+
 // ApplyMask applique un masque à la matrice QR et retourne la matrice masquée
 func ApplyMask(matrix *image.RGBA, maskPattern int) *image.RGBA {
 	size := matrix.Bounds().Max.X
 	maskedMatrix := image.NewRGBA(matrix.Bounds())
 
 	// Copie d'abord la matrice originale
-	for y := 0; y < size; y++ {
-		for x := 0; x < size; x++ {
-			maskedMatrix.Set(x, y, matrix.At(x, y))
-		}
-	}
+	copy(maskedMatrix.Pix, matrix.Pix)
 
 	// Applique le masque uniquement aux données
+	plane := getMaskPlanes(size)[maskPattern]
 	for y := 0; y < size; y++ {
 		for x := 0; x < size; x++ {
-			// Ne pas masquer les motifs de fonction
-			if isFunctionPattern(x, y, size) {
+			if !plane.Get(x, y) {
 				continue
 			}
 
-			// Détermine si le pixel doit être inversé selon le motif de masque
-			// Définition officielle des masques selon le standard ISO/IEC 18004:2015
-			shouldInvert := false
-			switch maskPattern {
-			case 0: // (x+y) mod 2 == 0
-				shouldInvert = (x+y)%2 == 0
-			case 1: // y mod 2 == 0
-				shouldInvert = y%2 == 0
-			case 2: // x mod 3 == 0
-				shouldInvert = x%3 == 0
-			case 3: // (x+y) mod 3 == 0
-				shouldInvert = (x+y)%3 == 0
-			case 4: // (floor(y/2) + floor(x/3)) mod 2 == 0
-				shouldInvert = ((y/2)+(x/3))%2 == 0
-			case 5: // (x*y) mod 2 + (x*y) mod 3 == 0
-				shouldInvert = ((x*y)%2 + (x*y)%3) == 0
-			case 6: // ((x*y) mod 2 + (x*y) mod 3) mod 2 == 0
-				shouldInvert = ((x*y)%2+(x*y)%3)%2 == 0
-			case 7: // ((x+y) mod 2 + (x*y) mod 3) mod 2 == 0
-				shouldInvert = ((x+y)%2+(x*y)%3)%2 == 0
-			}
-
-			if shouldInvert {
-				// Inverser la couleur
-				// Vérifier la couleur actuelle
-				r, _, _, a := maskedMatrix.At(x, y).RGBA()
-				if r > 0x7FFF { // Si c'est blanc (valeurs normalisées sont sur 16 bits)
-					maskedMatrix.Set(x, y, color.RGBA{0, 0, 0, uint8(a >> 8)})
-				} else { // Si c'est noir
-					maskedMatrix.Set(x, y, color.RGBA{255, 255, 255, uint8(a >> 8)})
-				}
+			// Inverser la couleur
+			// Vérifier la couleur actuelle
+			r, _, _, a := maskedMatrix.At(x, y).RGBA()
+			if r > 0x7FFF { // Si c'est blanc (valeurs normalisées sur 16 bits)
+				maskedMatrix.Set(x, y, color.RGBA{0, 0, 0, uint8(a >> 8)})
+			} else { // Si c'est noir
+				maskedMatrix.Set(x, y, color.RGBA{255, 255, 255, uint8(a >> 8)})
 			}
 		}
 	}
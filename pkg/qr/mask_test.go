@@ -0,0 +1,407 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// referenceSymbol est un symbole 21x21 (motifs de repérage, timing et données
+// pseudo-aléatoires) dont les pénalités ont été calculées indépendamment avec
+// les conventions de ZXing : règle 1 = 182, règle 2 = 150, règle 3 = 760, règle 4 = 0
+var referenceSymbol = []string{
+	"111111100000001111111",
+	"100000100111001000001",
+	"101110101110001011101",
+	"101110100001101011101",
+	"101110101110101011101",
+	"100000100100001000001",
+	"111111101010101111111",
+	"000000000011000000000",
+	"110101110010011101110",
+	"000011000011001101100",
+	"111010101111011101111",
+	"110011001110010010000",
+	"010100110001000000110",
+	"000000000001000100111",
+	"111111101111011011111",
+	"100000100100001111011",
+	"101110100000100010011",
+	"101110101101100010101",
+	"101110101000011110001",
+	"100000101100001111001",
+	"111111100100100010001",
+}
+
+// matrixFromRows construit une matrice RGBA à partir de lignes de '0' et '1'
+func matrixFromRows(rows []string) *image.RGBA {
+	size := len(rows)
+	matrix := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y, row := range rows {
+		for x := 0; x < size; x++ {
+			if row[x] == '1' {
+				matrix.Set(x, y, color.Black)
+			} else {
+				matrix.Set(x, y, color.White)
+			}
+		}
+	}
+	return matrix
+}
+
+// uniformMatrix construit une matrice entièrement sombre ou entièrement claire
+func uniformMatrix(size int, dark bool) *image.RGBA {
+	matrix := image.NewRGBA(image.Rect(0, 0, size, size))
+	c := color.Color(color.White)
+	if dark {
+		c = color.Black
+	}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			matrix.Set(x, y, c)
+		}
+	}
+	return matrix
+}
+
+// lineOf convertit une chaîne de '0' et '1' en ligne de modules
+func lineOf(s string) []bool {
+	line := make([]bool, len(s))
+	for i := range s {
+		line[i] = s[i] == '1'
+	}
+	return line
+}
+
+func TestEvaluateMaskReferenceVectors(t *testing.T) {
+	checkerboard := image.NewRGBA(image.Rect(0, 0, 21, 21))
+	for y := 0; y < 21; y++ {
+		for x := 0; x < 21; x++ {
+			if (x+y)%2 == 0 {
+				checkerboard.Set(x, y, color.Black)
+			} else {
+				checkerboard.Set(x, y, color.White)
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		matrix *image.RGBA
+		rules  [4]int
+	}{
+		{
+			name:   "symbole de référence",
+			matrix: matrixFromRows(referenceSymbol),
+			rules:  [4]int{182, 150, 760, 0},
+		},
+		{
+			// 42 suites de 21 modules (3 + 16), 400 blocs 2x2, 100 % de modules sombres
+			name:   "symbole entièrement sombre",
+			matrix: uniformMatrix(21, true),
+			rules:  [4]int{798, 1200, 0, 100},
+		},
+		{
+			name:   "symbole entièrement clair",
+			matrix: uniformMatrix(21, false),
+			rules:  [4]int{798, 1200, 0, 100},
+		},
+		{
+			// 221 modules sombres sur 441 : écart inférieur à 5 %
+			name:   "damier",
+			matrix: checkerboard,
+			rules:  [4]int{0, 0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMatrixFromImage(tt.matrix)
+			scratch := acquireMaskScratch(m.Size())
+			defer releaseMaskScratch(scratch)
+
+			got := penaltyRules(m, scratch)
+			if got != tt.rules {
+				t.Errorf("pénalités par règle = %v, attendu %v", got, tt.rules)
+			}
+
+			want := tt.rules[0] + tt.rules[1] + tt.rules[2] + tt.rules[3]
+			if score := EvaluateMask(tt.matrix); score != want {
+				t.Errorf("EvaluateMask() = %d, attendu %d", score, want)
+			}
+		})
+	}
+}
+
+func TestRunPenalty(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{"1010101010", 0},
+		{"1111", 0},
+		{"11111", 3},
+		{"0000000", 5},
+		{"111110000011", 6},
+		{"10000000001", 7},
+	}
+
+	for _, tt := range tests {
+		if got := runPenalty(lineOf(tt.line)); got != tt.want {
+			t.Errorf("runPenalty(%s) = %d, attendu %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestBlockPenalty(t *testing.T) {
+	tests := []struct {
+		upper, lower string
+		want         int
+	}{
+		{"1111", "1111", 9},
+		{"0000", "0000", 9},
+		{"1010", "0101", 0},
+		{"1100", "1100", 6},
+		{"1101", "1000", 0},
+		// Blocs à cheval sur deux mots de 64 bits
+		{strings.Repeat("1", 70), strings.Repeat("1", 70), 69 * 3},
+		{strings.Repeat("01", 40), strings.Repeat("01", 40), 0},
+	}
+
+	for _, tt := range tests {
+		m := NewMatrix(len(tt.upper))
+		for x := range tt.upper {
+			m.Set(x, 0, tt.upper[x] == '1')
+			m.Set(x, 1, tt.lower[x] == '1')
+		}
+		if got := blockPenalty(m.row(0), m.row(1), m.Size()); got != tt.want {
+			t.Errorf("blockPenalty(%s, %s) = %d, attendu %d", tt.upper, tt.lower, got, tt.want)
+		}
+	}
+}
+
+func TestFinderPenalty(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want int
+	}{
+		{"motif suivi de 4 modules clairs", "101110100001", 40},
+		{"motif précédé de 4 modules clairs", "100001011101", 40},
+		{"motif bordé des deux côtés", "000010111010000", 40},
+		{"bordure de 3 modules clairs seulement", "1000101110100011", 0},
+		{"motif au bord du symbole", "1011101", 40},
+		{"deux motifs accolés aux bords", "10111011011101", 80},
+		{"ancienne heuristique sans bordure claire", "11010111010111", 0},
+		{"rapport différent de 1:1:3:1:1", "0000101101010000", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := finderPenalty(lineOf(tt.line)); got != tt.want {
+				t.Errorf("finderPenalty(%s) = %d, attendu %d", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBalancePenalty(t *testing.T) {
+	tests := []struct {
+		dark, total int
+		want        int
+	}{
+		{50, 100, 0},
+		{54, 100, 0},
+		{46, 100, 0},
+		{55, 100, 10},
+		{44, 100, 10},
+		{61, 100, 20},
+		{0, 100, 100},
+		{100, 100, 100},
+		{227, 441, 0}, // 51,5 %
+		{199, 441, 0}, // 45,1 %, tronqué à tort en 45 % par l'ancien calcul
+	}
+
+	for _, tt := range tests {
+		if got := balancePenalty(tt.dark, tt.total); got != tt.want {
+			t.Errorf("balancePenalty(%d, %d) = %d, attendu %d", tt.dark, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestSelectBestMaskScoresFinalSymbol(t *testing.T) {
+	matrix := matrixFromRows(referenceSymbol)
+
+	best, mask, score := selectBestMask(matrix, "Q")
+
+	// Chaque candidat doit être évalué avec ses informations de format placées
+	for m := 0; m < 8; m++ {
+		candidate := ApplyMask(matrix, m)
+		AddFormatInfo(candidate, "Q", m)
+		if s := EvaluateMask(candidate); s < score || (s == score && m < mask) {
+			t.Errorf("masque %d (score %d) meilleur que le masque retenu %d (score %d)", m, s, mask, score)
+		}
+	}
+
+	want := ApplyMask(matrix, mask)
+	AddFormatInfo(want, "Q", mask)
+	if string(best.Pix) != string(want.Pix) {
+		t.Errorf("le symbole retenu ne porte pas les informations de format du masque %d", mask)
+	}
+}
+
+func TestMatrixImageRoundTrip(t *testing.T) {
+	img := matrixFromRows(referenceSymbol)
+	m := NewMatrixFromImage(img)
+
+	if m.Size() != 21 {
+		t.Fatalf("Size() = %d, attendu 21", m.Size())
+	}
+	for y, row := range referenceSymbol {
+		for x := range row {
+			if m.Get(x, y) != (row[x] == '1') {
+				t.Fatalf("module (%d,%d) incorrect", x, y)
+			}
+		}
+	}
+	if m.Get(-1, 0) || m.Get(21, 0) {
+		t.Error("les modules hors de la matrice doivent être clairs")
+	}
+	if string(m.Image().Pix) != string(img.Pix) {
+		t.Error("Image() ne restitue pas la matrice d'origine")
+	}
+}
+
+func TestEvaluateMasksMatchesImagePath(t *testing.T) {
+	for _, version := range []int{1, 7, 40} {
+		img := benchmarkSymbol(version)
+		base := NewMatrixFromImage(img)
+
+		serial := evaluateMasks(base, "M", 1)
+		parallel := evaluateMasks(base, "M", 8)
+		if serial != parallel {
+			t.Errorf("version %d : scores série %v != scores parallèles %v", version, serial, parallel)
+		}
+
+		for mask := 0; mask < 8; mask++ {
+			candidate := ApplyMask(img, mask)
+			AddFormatInfo(candidate, "M", mask)
+			if want := EvaluateMask(candidate); serial[mask] != want {
+				t.Errorf("version %d, masque %d : score %d, attendu %d", version, mask, serial[mask], want)
+			}
+		}
+	}
+}
+
+// benchmarkSymbol construit un symbole de la version donnée avec ses motifs de
+// fonction et des modules de données pseudo-aléatoires reproductibles
+func benchmarkSymbol(version int) *image.RGBA {
+	size := version*4 + 17
+	matrix := uniformMatrix(size, false)
+	AddFinderPatterns(matrix)
+	AddSeparators(matrix)
+	AddTimingPatterns(matrix)
+
+	rng := rand.New(rand.NewSource(int64(version)))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if !isFunctionPattern(x, y, size) && rng.Intn(2) == 0 {
+				matrix.Set(x, y, color.Black)
+			}
+		}
+	}
+	return matrix
+}
+
+// legacySelectMask reproduit l'ancienne sélection : copie et évaluation pixel
+// par pixel de l'image RGBA via At/Set, masque après masque. Conservée comme
+// référence pour mesurer le gain des plans de masque compacts.
+func legacySelectMask(matrix *image.RGBA, ecLevel string) int {
+	size := matrix.Bounds().Max.X
+	bestScore, bestMask := -1, 0
+
+	for mask := 0; mask < 8; mask++ {
+		masked := image.NewRGBA(matrix.Bounds())
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				c := matrix.At(x, y)
+				if !isFunctionPattern(x, y, size) && maskBit(mask, x, y) {
+					if isBlack(c) {
+						c = color.White
+					} else {
+						c = color.Black
+					}
+				}
+				masked.Set(x, y, c)
+			}
+		}
+		AddFormatInfo(masked, ecLevel, mask)
+
+		score := 0
+		upper, lower := make([]bool, size), make([]bool, size)
+		dark := 0
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				lower[x] = isBlack(masked.At(x, y))
+				if lower[x] {
+					dark++
+				}
+			}
+			score += runPenalty(lower) + finderPenalty(lower)
+			for x := 0; y > 0 && x < size-1; x++ {
+				c := lower[x]
+				if lower[x+1] == c && upper[x] == c && upper[x+1] == c {
+					score += penaltyN2
+				}
+			}
+			upper, lower = lower, upper
+		}
+		for x := 0; x < size; x++ {
+			for y := 0; y < size; y++ {
+				lower[y] = isBlack(masked.At(x, y))
+			}
+			score += runPenalty(lower) + finderPenalty(lower)
+		}
+		score += balancePenalty(dark, size*size)
+
+		if bestScore < 0 || score < bestScore {
+			bestScore, bestMask = score, mask
+		}
+	}
+
+	return bestMask
+}
+
+// BenchmarkMaskSelection compare l'ancienne sélection pixel par pixel à
+// l'évaluation sur matrice compacte, en série puis en parallèle :
+//
+//	go test ./pkg/qr -run '^$' -bench MaskSelection -benchmem
+func BenchmarkMaskSelection(b *testing.B) {
+	for _, version := range []int{10, 25, 40} {
+		img := benchmarkSymbol(version)
+		base := NewMatrixFromImage(img)
+		getMaskPlanes(base.Size()) // Plans calculés une fois pour toutes
+
+		b.Run(fmt.Sprintf("v%d/image", version), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				legacySelectMask(img, "M")
+			}
+		})
+		b.Run(fmt.Sprintf("v%d/compact-serie", version), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				evaluateMasks(base, "M", 1)
+			}
+		})
+		b.Run(fmt.Sprintf("v%d/compact-parallele", version), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				evaluateMasks(base, "M", 8)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package qr
+
+import (
+	"image"
+	"math/bits"
+)
+
+// Matrix est une matrice de modules compacte : un bit par module (1 = sombre).
+// Chaque ligne occupe stride mots de 64 bits, le module x d'une ligne étant le
+// bit x%64 du mot x/64. Les bits au-delà de la taille restent toujours à zéro.
+type Matrix struct {
+	size   int
+	stride int
+	bits   []uint64
+}
+
+// NewMatrix crée une matrice carrée de size modules, entièrement claire
+func NewMatrix(size int) *Matrix {
+	m := &Matrix{}
+	m.reset(size)
+	return m
+}
+
+// NewMatrixFromImage convertit une matrice image (un pixel par module) en matrice compacte
+func NewMatrixFromImage(img *image.RGBA) *Matrix {
+	bounds := img.Bounds()
+	m := NewMatrix(bounds.Dx())
+
+	for y := 0; y < m.size; y++ {
+		row := m.row(y)
+		offset := img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+		for x := 0; x < m.size; x++ {
+			// Même seuil que isBlack, sur la composante rouge 8 bits
+			if img.Pix[offset+x*4] < 0x80 {
+				row[x>>6] |= 1 << (x & 63)
+			}
+		}
+	}
+
+	return m
+}
+
+// reset redimensionne la matrice en réutilisant son tampon si possible, puis l'efface
+func (m *Matrix) reset(size int) {
+	m.size = size
+	m.stride = (size + 63) / 64
+	n := m.stride * size
+	if cap(m.bits) < n {
+		m.bits = make([]uint64, n)
+		return
+	}
+	m.bits = m.bits[:n]
+	clear(m.bits)
+}
+
+// Size retourne la taille de la matrice en modules
+func (m *Matrix) Size() int {
+	return m.size
+}
+
+// Get indique si le module (x, y) est sombre ; les modules hors de la matrice sont clairs
+func (m *Matrix) Get(x, y int) bool {
+	if x < 0 || y < 0 || x >= m.size || y >= m.size {
+		return false
+	}
+	return m.bits[y*m.stride+x>>6]&(1<<(x&63)) != 0
+}
+
+// Set définit la couleur du module (x, y) ; les positions hors de la matrice sont ignorées
+func (m *Matrix) Set(x, y int, dark bool) {
+	if x < 0 || y < 0 || x >= m.size || y >= m.size {
+		return
+	}
+	if dark {
+		m.bits[y*m.stride+x>>6] |= 1 << (x & 63)
+	} else {
+		m.bits[y*m.stride+x>>6] &^= 1 << (x & 63)
+	}
+}
+
+// Clone retourne une copie indépendante de la matrice
+func (m *Matrix) Clone() *Matrix {
+	c := &Matrix{size: m.size, stride: m.stride, bits: make([]uint64, len(m.bits))}
+	copy(c.bits, m.bits)
+	return c
+}
+
+// Image convertit la matrice en image RGBA noir et blanc, un pixel par module
+func (m *Matrix) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, m.size, m.size))
+
+	for y := 0; y < m.size; y++ {
+		pix := img.Pix[y*img.Stride : y*img.Stride+m.size*4]
+		for x := 0; x < m.size; x++ {
+			v := uint8(0xFF) // blanc
+			if m.Get(x, y) {
+				v = 0 // noir
+			}
+			pix[x*4], pix[x*4+1], pix[x*4+2], pix[x*4+3] = v, v, v, 0xFF
+		}
+	}
+
+	return img
+}
+
+// row retourne les mots de la ligne y
+func (m *Matrix) row(y int) []uint64 {
+	return m.bits[y*m.stride : (y+1)*m.stride]
+}
+
+// copyFrom copie src dans m, qui doit avoir la même taille
+func (m *Matrix) copyFrom(src *Matrix) {
+	copy(m.bits, src.bits)
+}
+
+// xor inverse les modules de m présents dans plane, de même taille
+func (m *Matrix) xor(plane *Matrix) {
+	for i, w := range plane.bits {
+		m.bits[i] ^= w
+	}
+}
+
+// readRow copie la ligne y dans line (true = module sombre)
+func (m *Matrix) readRow(y int, line []bool) {
+	row := m.row(y)
+	for x := range line {
+		line[x] = row[x>>6]&(1<<(x&63)) != 0
+	}
+}
+
+// readColumn copie la colonne x dans line (true = module sombre)
+func (m *Matrix) readColumn(x int, line []bool) {
+	word, bit := x>>6, uint64(1)<<(x&63)
+	for y := range line {
+		line[y] = m.bits[y*m.stride+word]&bit != 0
+	}
+}
+
+// countDark retourne le nombre de modules sombres
+func (m *Matrix) countDark() int {
+	count := 0
+	for _, w := range m.bits {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
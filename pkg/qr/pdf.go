@@ -0,0 +1,196 @@
+package qr
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+)
+
+// SaveQRImagePDF sauvegarde la matrice QR en document PDF vectoriel
+func SaveQRImagePDF(matrix *image.RGBA, outputFile string, opts RenderOptions) error {
+	return saveRendered(outputFile, RendererFunc(RenderPDF), matrix, opts)
+}
+
+// RenderPDF écrit la matrice dans w sous forme d'un PDF d'une page aux dimensions
+// exactes du symbole, zone calme comprise. Tous les modules sombres forment un
+// unique chemin rempli, en CMJN ou en ton direct (espace Separation).
+func RenderPDF(w io.Writer, m *Matrix, opts RenderOptions) error {
+	layout, err := newPrintLayout(m, opts)
+	if err != nil {
+		return err
+	}
+
+	content, err := pdfContent(m, opts, layout)
+	if err != nil {
+		return err
+	}
+
+	pw := &pdfWriter{w: bufio.NewWriter(w)}
+	pw.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	resources := ""
+	if opts.SpotColor != "" {
+		resources = " /Resources << /ColorSpace << /CS0 6 0 R >> >>"
+	}
+
+	pw.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	pw.object(2, "<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	pw.object(3, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s]%s /Contents 4 0 R >>",
+		formatNumber(layout.size), formatNumber(layout.size), resources))
+	pw.stream(4, "/Filter /FlateDecode", content)
+
+	info := "<< /Producer (QRFactory)"
+	if opts.Title != "" {
+		info += " /Title " + pdfString(opts.Title)
+	}
+	if opts.Description != "" {
+		info += " /Subject " + pdfString(opts.Description)
+	}
+	pw.object(5, info+" >>")
+
+	if opts.SpotColor != "" {
+		// Teinte t de la couleur d'accompagnement : t × la couleur de substitution en CMJN
+		pw.object(6, fmt.Sprintf("[/Separation %s /DeviceCMYK << /FunctionType 2 /Domain [0 1] /C0 [0 0 0 0] /C1 [%s] /N 1 >>]",
+			pdfName(opts.SpotColor), cmykComponents(layout.foreground)))
+	}
+
+	pw.trailer("/Root 1 0 R /Info 5 0 R")
+	return pw.flush()
+}
+
+// pdfContent construit le flux de contenu compressé de la page
+func pdfContent(m *Matrix, opts RenderOptions, layout printLayout) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	bw := bufio.NewWriter(zw)
+
+	// Repère en modules, origine en haut à gauche comme dans la matrice
+	fmt.Fprintf(bw, "q\n%s 0 0 %s 0 %s cm\n",
+		formatNumber(layout.module), formatNumber(-layout.module), formatNumber(layout.size))
+
+	if bg := layout.background; bg != nil {
+		fmt.Fprintf(bw, "%s k\n0 0 %d %d re f\n", cmykComponents(*bg), layout.modules, layout.modules)
+	}
+
+	if opts.SpotColor != "" {
+		fmt.Fprintf(bw, "/CS0 cs 1 scn\n")
+	} else {
+		fmt.Fprintf(bw, "%s k\n", cmykComponents(layout.foreground))
+	}
+
+	// Un seul chemin rempli selon la règle nonzero
+	offset := opts.QuietZone
+	if opts.Outline {
+		for _, loop := range outlineLoops(m) {
+			fmt.Fprintf(bw, "%d %d m\n", loop[0].X+offset, loop[0].Y+offset)
+			for _, p := range loop[1:] {
+				fmt.Fprintf(bw, "%d %d l\n", p.X+offset, p.Y+offset)
+			}
+			fmt.Fprintf(bw, "h\n")
+		}
+	} else {
+		for _, r := range darkRuns(m) {
+			fmt.Fprintf(bw, "%d %d %d 1 re\n", r.x+offset, r.y+offset, r.length)
+		}
+	}
+	fmt.Fprintf(bw, "f\nQ\n")
+
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pdfWriter écrit les objets d'un PDF en relevant leurs positions pour la table xref
+type pdfWriter struct {
+	w       *bufio.Writer
+	offset  int
+	offsets []int
+}
+
+// write ajoute du texte brut au document
+func (pw *pdfWriter) write(s string) {
+	n, _ := pw.w.WriteString(s)
+	pw.offset += n
+}
+
+// object écrit l'objet numéro id, numérotés à partir de 1 et dans l'ordre
+func (pw *pdfWriter) object(id int, body string) {
+	pw.offsets = append(pw.offsets, pw.offset)
+	pw.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", id, body))
+}
+
+// stream écrit un objet flux avec ses entrées de dictionnaire supplémentaires
+func (pw *pdfWriter) stream(id int, dict string, data []byte) {
+	pw.offsets = append(pw.offsets, pw.offset)
+	pw.write(fmt.Sprintf("%d 0 obj\n<< /Length %d %s >>\nstream\n", id, len(data), dict))
+	n, _ := pw.w.Write(data)
+	pw.offset += n
+	pw.write("\nendstream\nendobj\n")
+}
+
+// trailer écrit la table de références croisées et la fin du document
+func (pw *pdfWriter) trailer(dict string) {
+	xref := pw.offset
+	pw.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets)+1))
+	for _, off := range pw.offsets {
+		pw.write(fmt.Sprintf("%010d 00000 n \n", off))
+	}
+	pw.write(fmt.Sprintf("trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets)+1, dict, xref))
+}
+
+// flush vide le tampon vers la destination
+func (pw *pdfWriter) flush() error {
+	return pw.w.Flush()
+}
+
+// pdfName écrit un nom PDF, les caractères hors de l'ASCII imprimable et les
+// délimiteurs étant codés en #xx
+func pdfName(s string) string {
+	var b strings.Builder
+	b.WriteByte('/')
+	for _, c := range []byte(s) {
+		if c <= ' ' || c >= 0x7F || strings.IndexByte("#()<>[]{}/%", c) >= 0 {
+			fmt.Fprintf(&b, "#%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// pdfString écrit une chaîne PDF littérale ; hors ASCII, le texte est codé en UTF-16BE
+func pdfString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+
+	if !ascii {
+		var b strings.Builder
+		b.WriteString("<FEFF")
+		for _, r := range s {
+			if r > 0xFFFF {
+				r -= 0x10000
+				fmt.Fprintf(&b, "%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			} else {
+				fmt.Fprintf(&b, "%04X", r)
+			}
+		}
+		b.WriteByte('>')
+		return b.String()
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", `\r`, "\n", `\n`)
+	return "(" + replacer.Replace(s) + ")"
+}
@@ -0,0 +1,77 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// SaveQRImagePNG sauvegarde la matrice QR en image PNG avec les couleurs et la zone calme des options
+func SaveQRImagePNG(matrix *image.RGBA, outputFile string, opts RenderOptions) error {
+	return saveRendered(outputFile, RendererFunc(RenderPNG), matrix, opts)
+}
+
+// RenderPNG encode la matrice en PNG dans w, chaque module mesurant Scale pixels
+// ou la taille déduite de SizePx, SizeMM ou ModuleMM. La zone calme prend la
+// couleur du fond ; les couleurs translucides sont conservées dans la palette.
+// L'image n'ayant que deux couleurs, elle est encodée en PNG indexé sur 1 bit
+// par pixel, sauf si un logo impose les couleurs vraies. Pour une taille
+// physique, la résolution est inscrite dans un bloc pHYs.
+func RenderPNG(w io.Writer, m *Matrix, opts RenderOptions) error {
+	return RasterRenderer(encodePNG).Render(w, m, opts)
+}
+
+// encodePNG encode l'image en PNG (indexé pour une image à palette), avec sa
+// résolution pour une taille physique
+func encodePNG(w io.Writer, img image.Image, opts RenderOptions) error {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: opts.PNGCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return fmt.Errorf("erreur lors de l'encodage PNG : %v", err)
+	}
+
+	data := buf.Bytes()
+	if opts.physical() {
+		data = withPNGResolution(data, opts.dpi())
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture PNG : %v", err)
+	}
+	return nil
+}
+
+// pngHeaderSize couvre la signature PNG et le bloc IHDR, toujours en tête
+const pngHeaderSize = 8 + 12 + 13
+
+// withPNGResolution insère après l'en-tête un bloc pHYs donnant la résolution
+// en pixels par mètre
+func withPNGResolution(data []byte, dpi float64) []byte {
+	ppm := uint32(math.Round(dpi / mmPerInch * 1000))
+
+	chunk := make([]byte, 0, 21)
+	chunk = binary.BigEndian.AppendUint32(chunk, 9)
+	chunk = append(chunk, "pHYs"...)
+	chunk = binary.BigEndian.AppendUint32(chunk, ppm)
+	chunk = binary.BigEndian.AppendUint32(chunk, ppm)
+	chunk = append(chunk, 1) // Unité : le mètre
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	out := make([]byte, 0, len(data)+len(chunk))
+	out = append(out, data[:pngHeaderSize]...)
+	out = append(out, chunk...)
+	return append(out, data[pngHeaderSize:]...)
+}
+
+// toNRGBA convertit une couleur en NRGBA ; fallback est utilisée si elle n'est pas définie
+func toNRGBA(c color.Color, fallback color.Color) color.NRGBA {
+	if c == nil {
+		c = fallback
+	}
+	return color.NRGBAModel.Convert(c).(color.NRGBA)
+}
@@ -0,0 +1,225 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestRenderPNGColors(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows(cornerRows))
+
+	tests := []struct {
+		name       string
+		foreground color.Color
+		background color.Color
+	}{
+		{"Noir sur blanc par défaut", nil, nil},
+		{"Couleurs opaques", color.NRGBA{0x1a, 0x2b, 0x3c, 0xff}, color.NRGBA{0xff, 0xf0, 0xc0, 0xff}},
+		{"Fond transparent", color.NRGBA{0x80, 0x00, 0x00, 0xff}, color.Transparent},
+		{"Modules translucides", color.NRGBA{0x00, 0x00, 0x80, 0x80}, color.White},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := RenderOptions{QuietZone: 2, Scale: 3, Foreground: tt.foreground, Background: tt.background}
+
+			var buf bytes.Buffer
+			if err := RenderPNG(&buf, m, opts); err != nil {
+				t.Fatalf("RenderPNG() error = %v", err)
+			}
+			img, err := png.Decode(&buf)
+			if err != nil {
+				t.Fatalf("PNG illisible : %v", err)
+			}
+			if size := img.Bounds().Dx(); size != (5+4)*3 {
+				t.Fatalf("image de %d pixels, attendu %d", size, (5+4)*3)
+			}
+
+			fg := toNRGBA(tt.foreground, color.Black)
+			bg := toNRGBA(tt.background, color.White)
+			for y := 0; y < img.Bounds().Dy(); y++ {
+				for x := 0; x < img.Bounds().Dx(); x++ {
+					want := bg
+					if m.Get(x/3-2, y/3-2) {
+						want = fg
+					}
+					if got := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA); got != want && want.A != 0 {
+						t.Fatalf("pixel (%d,%d) = %v, attendu %v", x, y, got, want)
+					} else if want.A == 0 && got.A != 0 {
+						t.Fatalf("pixel (%d,%d) = %v, attendu transparent", x, y, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPNGPaletted(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(5))
+
+	tests := []struct {
+		name        string
+		compression png.CompressionLevel
+	}{
+		{"Compression par défaut", png.DefaultCompression},
+		{"Sans compression", png.NoCompression},
+		{"Compression rapide", png.BestSpeed},
+		{"Compression maximale", png.BestCompression},
+	}
+
+	sizes := make(map[png.CompressionLevel]int)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewDefaultRenderOptions()
+			opts.Scale = 7
+			opts.PNGCompression = tt.compression
+
+			var buf bytes.Buffer
+			if err := RenderPNG(&buf, m, opts); err != nil {
+				t.Fatalf("RenderPNG() error = %v", err)
+			}
+			sizes[tt.compression] = buf.Len()
+
+			// En-tête IHDR : profondeur de 1 bit, type de couleur 3 (palette)
+			data := buf.Bytes()
+			if depth, colorType := data[24], data[25]; depth != 1 || colorType != 3 {
+				t.Errorf("PNG de profondeur %d et de type %d, attendu 1 bit indexé", depth, colorType)
+			}
+
+			img, err := png.Decode(&buf)
+			if err != nil {
+				t.Fatalf("PNG illisible : %v", err)
+			}
+			assertRasterMatches(t, img, m, opts)
+		})
+	}
+
+	if sizes[png.BestCompression] >= sizes[png.NoCompression] {
+		t.Errorf("compression maximale (%d octets) pas plus compacte que sans compression (%d octets)",
+			sizes[png.BestCompression], sizes[png.NoCompression])
+	}
+}
+
+// assertRasterMatches vérifie chaque pixel de l'image par rapport à la matrice
+func assertRasterMatches(t *testing.T, img image.Image, m *Matrix, opts RenderOptions) {
+	t.Helper()
+	scale := max(opts.Scale, 1)
+	if size := img.Bounds().Dx(); size != (m.Size()+2*opts.QuietZone)*scale {
+		t.Fatalf("image de %d pixels, attendu %d", size, (m.Size()+2*opts.QuietZone)*scale)
+	}
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			r, _, _, _ := img.At(x, y).RGBA()
+			if dark := m.Get(x/scale-opts.QuietZone, y/scale-opts.QuietZone); dark != (r == 0) {
+				t.Fatalf("pixel (%d,%d) sombre = %v, attendu %v", x, y, r == 0, dark)
+			}
+		}
+	}
+}
+
+// legacyScaledPNG reproduit l'ancien rendu : copie pixel par pixel avec Set dans
+// une image RGBA, puis encodage PNG en couleurs vraies. Conservé comme référence
+// pour mesurer le gain du rendu indexé.
+func legacyScaledPNG(w *bytes.Buffer, matrix *image.RGBA, scale, quietZone int) error {
+	size := matrix.Bounds().Max.X
+	total := (size + 2*quietZone) * scale
+	scaledImage := image.NewRGBA(image.Rect(0, 0, total, total))
+	for y := 0; y < total; y++ {
+		for x := 0; x < total; x++ {
+			mx, my := x/scale-quietZone, y/scale-quietZone
+			c := color.Color(color.White)
+			if mx >= 0 && my >= 0 && mx < size && my < size {
+				c = matrix.At(mx, my)
+			}
+			scaledImage.Set(x, y, c)
+		}
+	}
+	return png.Encode(w, scaledImage)
+}
+
+// BenchmarkRenderPNG compare l'ancien rendu RGBA au rendu indexé à l'échelle 30 :
+//
+//	go test ./pkg/qr -run '^$' -bench RenderPNG -benchmem
+func BenchmarkRenderPNG(b *testing.B) {
+	const scale = 30
+
+	for _, version := range []int{5, 25, 40} {
+		img := benchmarkSymbol(version)
+		m := NewMatrixFromImage(img)
+		opts := NewDefaultRenderOptions()
+		opts.Scale = scale
+
+		var buf bytes.Buffer
+		b.Run(fmt.Sprintf("v%d/rgba", version), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				if err := legacyScaledPNG(&buf, img, scale, opts.QuietZone); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(buf.Len()), "octets")
+		})
+		b.Run(fmt.Sprintf("v%d/palette", version), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				if err := RenderPNG(&buf, m, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(buf.Len()), "octets")
+		})
+	}
+}
+
+func TestRenderPNGResolution(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(1))
+
+	tests := []struct {
+		name    string
+		opts    RenderOptions
+		wantPPM uint32
+	}{
+		{"Sans taille physique", RenderOptions{QuietZone: 4, Scale: 4}, 0},
+		{"Taille en pixels seule", RenderOptions{QuietZone: 4, SizePx: 300}, 0},
+		{"Millimètres à 300 DPI", RenderOptions{QuietZone: 4, SizeMM: 25, DPI: 300}, 11811},
+		{"Millimètres sans résolution", RenderOptions{QuietZone: 4, SizeMM: 25}, 11811},
+		{"Pixels à 72 DPI", RenderOptions{QuietZone: 4, SizePx: 300, DPI: 72}, 2835},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := RenderPNG(&buf, m, tt.opts); err != nil {
+				t.Fatalf("RenderPNG() error = %v", err)
+			}
+			data := buf.Bytes()
+
+			// Le décodeur vérifie la somme de contrôle de chaque bloc
+			if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+				t.Fatalf("PNG illisible : %v", err)
+			}
+
+			i := bytes.Index(data, []byte("pHYs"))
+			if tt.wantPPM == 0 {
+				if i >= 0 {
+					t.Error("bloc pHYs inattendu sans taille physique")
+				}
+				return
+			}
+			if i < 0 {
+				t.Fatal("bloc pHYs absent")
+			}
+			x, y, unit := binary.BigEndian.Uint32(data[i+4:]), binary.BigEndian.Uint32(data[i+8:]), data[i+12]
+			if x != tt.wantPPM || y != tt.wantPPM || unit != 1 {
+				t.Errorf("pHYs = %d×%d unité %d, attendu %d pixels par mètre", x, y, unit, tt.wantPPM)
+			}
+		})
+	}
+}
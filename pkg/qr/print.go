@@ -0,0 +1,94 @@
+package qr
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Points PostScript par millimètre
+const pointsPerMM = 72 / 25.4
+
+// printLayout décrit la mise en page d'un symbole pour l'impression
+type printLayout struct {
+	// Nombre de modules par côté, zone calme comprise
+	modules int
+
+	// Taille d'un module et du symbole complet, en points
+	module float64
+	size   float64
+
+	// Couleurs en quadrichromie ; background est nil s'il est transparent
+	foreground color.CMYK
+	background *color.CMYK
+}
+
+// newPrintLayout calcule les dimensions physiques et les couleurs CMJN du rendu
+func newPrintLayout(m *Matrix, opts RenderOptions) (printLayout, error) {
+	if opts.SizeMM < 0 || opts.ModuleMM < 0 || opts.QuietZone < 0 {
+		return printLayout{}, fmt.Errorf("taille d'impression invalide : %g mm, module de %g mm, zone calme de %d modules", opts.SizeMM, opts.ModuleMM, opts.QuietZone)
+	}
+	if opts.Inverted {
+		// Les modules clairs deviennent le chemin rempli : ils ne peuvent être
+		// ni transparents ni associés à l'encre du ton direct des modules
+		if opts.SpotColor != "" || (opts.Background != nil && isTransparent(opts.Background)) {
+			return printLayout{}, fmt.Errorf("la réflectance inversée ne se combine pas avec un ton direct ou un fond transparent")
+		}
+		opts = opts.polarity()
+	}
+
+	layout := printLayout{modules: m.size + 2*opts.QuietZone}
+	switch {
+	case opts.SizeMM > 0:
+		layout.module = opts.SizeMM * pointsPerMM / float64(layout.modules)
+	case opts.ModuleMM > 0:
+		layout.module = opts.ModuleMM * pointsPerMM
+	case opts.Scale > 0:
+		layout.module = float64(opts.Scale)
+	default:
+		layout.module = pointsPerMM
+	}
+	layout.size = layout.module * float64(layout.modules)
+
+	// La conversion CMJN de Go reporte le gris sur le noir seul : un noir RVB
+	// devient 100 % K et non un noir enrichi
+	layout.foreground = toCMYK(opts.Foreground, color.Black)
+	if bg := opts.Background; bg == nil || !isTransparent(bg) {
+		c := toCMYK(bg, color.White)
+		layout.background = &c
+	}
+
+	return layout, nil
+}
+
+// toCMYK convertit une couleur en CMJN ; fallback est utilisée si elle n'est pas définie
+func toCMYK(c color.Color, fallback color.Color) color.CMYK {
+	if c == nil {
+		c = fallback
+	}
+	return color.CMYKModel.Convert(c).(color.CMYK)
+}
+
+// isTransparent indique si une couleur est totalement transparente
+func isTransparent(c color.Color) bool {
+	_, _, _, a := c.RGBA()
+	return a == 0
+}
+
+// cmykComponents retourne les composantes CMJN sous forme de quatre nombres entre 0 et 1
+func cmykComponents(c color.CMYK) string {
+	return formatNumber(float64(c.C)/255) + " " + formatNumber(float64(c.M)/255) + " " +
+		formatNumber(float64(c.Y)/255) + " " + formatNumber(float64(c.K)/255)
+}
+
+// formatNumber écrit un nombre avec au plus six décimales, sans zéros inutiles
+func formatNumber(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 6, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
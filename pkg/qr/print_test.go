@@ -0,0 +1,256 @@
+package qr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	pdfStartXref = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	pdfStream    = regexp.MustCompile(`(?s)4 0 obj\n<< /Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+)
+
+// lastFillPolygons interprète les opérateurs de chemin d'un flux PDF ou PostScript
+// et retourne les polygones du dernier remplissage. Les coordonnées restent dans
+// le repère en modules établi par le rendu.
+func lastFillPolygons(t *testing.T, content string, ops map[string]string) [][]image.Point {
+	t.Helper()
+
+	var polygons, filled [][]image.Point
+	var current []image.Point
+	var stack []int
+	pop := func(n int) []int {
+		if len(stack) < n {
+			t.Fatalf("pile trop courte pour un opérateur : %v", stack)
+		}
+		values := stack[len(stack)-n:]
+		stack = stack[:len(stack)-n]
+		return values
+	}
+
+	for _, token := range strings.Fields(content) {
+		if v, err := strconv.Atoi(token); err == nil {
+			stack = append(stack, v)
+			continue
+		}
+
+		switch ops[token] {
+		case "rect":
+			v := pop(4)
+			x, y, w, h := v[0], v[1], v[2], v[3]
+			polygons = append(polygons, []image.Point{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}})
+		case "rectLen":
+			// Procédure EPS : longueur x y
+			v := pop(3)
+			w, x, y := v[0], v[1], v[2]
+			polygons = append(polygons, []image.Point{{x, y}, {x + w, y}, {x + w, y + 1}, {x, y + 1}})
+		case "move":
+			v := pop(2)
+			current = []image.Point{{v[0], v[1]}}
+		case "line":
+			v := pop(2)
+			current = append(current, image.Point{v[0], v[1]})
+		case "close":
+			polygons = append(polygons, current)
+			current = nil
+		case "fill":
+			filled, polygons = polygons, nil
+		default:
+			stack = stack[:0]
+		}
+	}
+
+	return filled
+}
+
+// pdfContentStream vérifie la table xref du document et retourne son flux de contenu décompressé
+func pdfContentStream(t *testing.T, doc []byte) string {
+	t.Helper()
+
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) {
+		t.Fatalf("en-tête PDF manquant")
+	}
+
+	match := pdfStartXref.FindSubmatch(doc)
+	if match == nil {
+		t.Fatalf("startxref manquant")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	lines := strings.Split(string(doc[xref:]), "\n")
+	if lines[0] != "xref" {
+		t.Fatalf("startxref pointe sur %q, attendu xref", lines[0])
+	}
+	count, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for id := 1; id < count; id++ {
+		offset, _ := strconv.Atoi(strings.Fields(lines[2+id])[0])
+		if want := fmt.Sprintf("%d 0 obj\n", id); !bytes.HasPrefix(doc[offset:], []byte(want)) {
+			t.Errorf("l'entrée xref %d pointe sur %q", id, doc[offset:min(offset+12, len(doc))])
+		}
+	}
+
+	stream := pdfStream.FindSubmatchIndex(doc)
+	if stream == nil {
+		t.Fatalf("flux de contenu introuvable")
+	}
+	length, _ := strconv.Atoi(string(doc[stream[2]:stream[3]]))
+	zr, err := zlib.NewReader(bytes.NewReader(doc[stream[1] : stream[1]+length]))
+	if err != nil {
+		t.Fatalf("flux de contenu illisible : %v", err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("flux de contenu illisible : %v", err)
+	}
+	return string(content)
+}
+
+var (
+	pdfPathOps = map[string]string{"re": "rect", "m": "move", "l": "line", "h": "close", "f": "fill"}
+	epsPathOps = map[string]string{"r": "rectLen", "m": "move", "l": "line", "closepath": "close", "fill": "fill"}
+)
+
+func TestRenderPrintPathMatchesMatrix(t *testing.T) {
+	symbol := NewMatrixFromImage(matrixFromRows(referenceSymbol))
+	corners := NewMatrixFromImage(matrixFromRows(cornerRows))
+
+	for _, m := range []*Matrix{symbol, corners} {
+		for _, outline := range []bool{false, true} {
+			opts := NewDefaultRenderOptions()
+			opts.Outline = outline
+
+			t.Run(fmt.Sprintf("PDF %d modules contours=%v", m.Size(), outline), func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := RenderPDF(&buf, m, opts); err != nil {
+					t.Fatalf("RenderPDF() error = %v", err)
+				}
+				polygons := lastFillPolygons(t, pdfContentStream(t, buf.Bytes()), pdfPathOps)
+				assertSameModules(t, rasterizePolygons(t, polygons, m.Size(), opts.QuietZone), m)
+			})
+
+			t.Run(fmt.Sprintf("EPS %d modules contours=%v", m.Size(), outline), func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := RenderEPS(&buf, m, opts); err != nil {
+					t.Fatalf("RenderEPS() error = %v", err)
+				}
+				polygons := lastFillPolygons(t, buf.String(), epsPathOps)
+				assertSameModules(t, rasterizePolygons(t, polygons, m.Size(), opts.QuietZone), m)
+			})
+		}
+	}
+}
+
+func TestRenderPDFDocument(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows(referenceSymbol))
+
+	opts := NewDefaultRenderOptions()
+	opts.SizeMM = 25.4
+	opts.Title = "Étiquette (lot 42)"
+	opts.SpotColor = "PANTONE Black C"
+
+	var buf bytes.Buffer
+	if err := RenderPDF(&buf, m, opts); err != nil {
+		t.Fatalf("RenderPDF() error = %v", err)
+	}
+	doc := buf.String()
+	content := pdfContentStream(t, buf.Bytes())
+
+	for _, want := range []string{
+		"/MediaBox [0 0 72 72]",
+		"/ColorSpace << /CS0 6 0 R >>",
+		"[/Separation /PANTONE#20Black#20C /DeviceCMYK << /FunctionType 2 /Domain [0 1] /C0 [0 0 0 0] /C1 [0 0 0 1] /N 1 >>]",
+		"/Title <FEFF00C9",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("le document ne contient pas %s", want)
+		}
+	}
+	for _, want := range []string{"2.482759 0 0 -2.482759 0 72 cm", "0 0 0 0 k\n0 0 29 29 re f", "/CS0 cs 1 scn"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("le flux de contenu ne contient pas %q", want)
+		}
+	}
+}
+
+func TestRenderEPSDocument(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows(referenceSymbol))
+
+	opts := NewDefaultRenderOptions()
+	opts.ModuleMM = 0.5
+	opts.QuietZone = 2
+	opts.Background = color.Transparent
+
+	var buf bytes.Buffer
+	if err := RenderEPS(&buf, m, opts); err != nil {
+		t.Fatalf("RenderEPS() error = %v", err)
+	}
+	doc := buf.String()
+
+	// 25 modules de 0,5 mm : 12,5 mm, soit 35,43 points
+	for _, want := range []string{
+		"%!PS-Adobe-3.0 EPSF-3.0\n",
+		"%%BoundingBox: 0 0 36 36\n",
+		"%%HiResBoundingBox: 0 0 35.433071 35.433071\n",
+		"0 0 0 1 setcmykcolor\n",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("le document ne contient pas %q", want)
+		}
+	}
+	if strings.Contains(doc, "rectfill") {
+		t.Errorf("un fond transparent ne doit pas être peint")
+	}
+
+	opts.SpotColor = "Vernis (sélectif)"
+	buf.Reset()
+	if err := RenderEPS(&buf, m, opts); err != nil {
+		t.Fatalf("RenderEPS() error = %v", err)
+	}
+	if want := `[/Separation (Vernis \(sélectif\)) /DeviceCMYK`; !strings.Contains(buf.String(), want) {
+		t.Errorf("le document ne contient pas %q", want)
+	}
+}
+
+func TestNewPrintLayout(t *testing.T) {
+	m := NewMatrix(21)
+
+	tests := []struct {
+		name       string
+		opts       RenderOptions
+		wantModule float64
+		wantK      uint8
+		wantErr    bool
+	}{
+		{"Taille totale", RenderOptions{QuietZone: 4, SizeMM: 29, Foreground: color.Black}, pointsPerMM, 255, false},
+		{"Taille de module", RenderOptions{QuietZone: 4, ModuleMM: 0.33, Scale: 10}, 0.33 * pointsPerMM, 255, false},
+		{"Échelle en points", RenderOptions{Scale: 3}, 3, 255, false},
+		{"Gris RVB sur le noir seul", RenderOptions{Scale: 1, Foreground: color.Gray{Y: 0x80}}, 1, 127, false},
+		{"Taille négative", RenderOptions{SizeMM: -1}, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := newPrintLayout(m, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newPrintLayout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if math.Abs(layout.module-tt.wantModule) > 1e-9 {
+				t.Errorf("module = %f pt, attendu %f", layout.module, tt.wantModule)
+			}
+			if c := layout.foreground; c.C != 0 || c.M != 0 || c.Y != 0 || c.K != tt.wantK {
+				t.Errorf("couleur = %+v, attendu K seul à %d", c, tt.wantK)
+			}
+		})
+	}
+}
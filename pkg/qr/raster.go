@@ -0,0 +1,157 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// DefaultDPI est la résolution supposée pour une taille physique sans DPI explicite
+const DefaultDPI = 300
+
+// mmPerInch est le nombre de millimètres dans un pouce
+const mmPerInch = 25.4
+
+// rasterLayout décrit le placement du symbole dans une image matricielle
+type rasterLayout struct {
+	// Taille d'un module en pixels
+	module int
+
+	// Côté de l'image en pixels
+	size int
+
+	// Marge ajoutée avant la zone calme pour centrer le symbole, en pixels
+	offset int
+}
+
+// newRasterLayout calcule la taille entière des modules. Avec SizePx, ou SizeMM
+// et la résolution, le côté de l'image est imposé : le plus grand module entier
+// qui y tient est retenu et le reste est réparti autour du symbole. ModuleMM
+// fixe la taille d'un module à la résolution donnée ; sinon un module mesure
+// Scale pixels.
+func newRasterLayout(m *Matrix, opts RenderOptions) (rasterLayout, error) {
+	if opts.SizePx < 0 || opts.SizeMM < 0 || opts.ModuleMM < 0 || opts.DPI < 0 || opts.QuietZone < 0 {
+		return rasterLayout{}, fmt.Errorf("dimensions invalides : %d pixels, %g mm, module de %g mm, %g DPI, zone calme de %d modules",
+			opts.SizePx, opts.SizeMM, opts.ModuleMM, opts.DPI, opts.QuietZone)
+	}
+
+	modules := m.size + 2*opts.QuietZone
+	size := opts.SizePx
+	if size == 0 && opts.SizeMM > 0 {
+		size = int(math.Round(opts.SizeMM / mmPerInch * opts.dpi()))
+	}
+
+	if size > 0 {
+		module := size / modules
+		if module < 1 {
+			return rasterLayout{}, fmt.Errorf("image de %d pixels trop petite pour %d modules, zone calme comprise", size, modules)
+		}
+		return rasterLayout{module: module, size: size, offset: (size - module*modules) / 2}, nil
+	}
+
+	module := max(opts.Scale, 1)
+	if opts.ModuleMM > 0 {
+		module = max(int(math.Round(opts.ModuleMM/mmPerInch*opts.dpi())), 1)
+	}
+	return rasterLayout{module: module, size: module * modules}, nil
+}
+
+// dpi retourne la résolution des options, DefaultDPI si elle n'est pas précisée
+func (opts RenderOptions) dpi() float64 {
+	if opts.DPI > 0 {
+		return opts.DPI
+	}
+	return DefaultDPI
+}
+
+// physical indique si les options décrivent une taille physique, auquel cas la
+// résolution doit être inscrite dans l'image
+func (opts RenderOptions) physical() bool {
+	return opts.DPI > 0 || (opts.SizePx == 0 && (opts.SizeMM > 0 || opts.ModuleMM > 0))
+}
+
+// rasterize dessine la matrice mise à l'échelle, zone calme comprise, dans une
+// image à palette (0 pour le fond, 1 pour les modules). Chaque ligne de modules
+// est tracée une seule fois puis recopiée sur les lignes de pixels suivantes.
+// Les formes autres que le carré et les yeux personnalisés sont anticrénelés
+// avec des teintes intermédiaires ajoutées à la palette.
+func rasterize(m *Matrix, opts RenderOptions) (*image.Paletted, error) {
+	layout, err := newRasterLayout(m, opts)
+	if err != nil {
+		return nil, err
+	}
+	eyes, err := newEyeStyle(opts)
+	if err != nil {
+		return nil, err
+	}
+	modules := m
+	if eyes != nil {
+		modules = withoutEyes(m)
+	}
+	s, err := newShaper(modules, opts)
+	if err != nil {
+		return nil, err
+	}
+	bg := toNRGBA(opts.Background, color.White)
+	fg := toNRGBA(opts.Foreground, color.Black)
+
+	rect := image.Rect(0, 0, layout.size, layout.size)
+	origin := layout.offset + opts.QuietZone*layout.module
+	if eyes != nil {
+		img := image.NewPaletted(rect, shadePalette(bg, fg, eyes.frameColor, eyes.pupilColor))
+		drawShapes(img, s, origin, layout.module)
+		drawEyes(img, eyes, m.size, origin, layout.module)
+		return img, nil
+	}
+	if !s.square() {
+		img := image.NewPaletted(rect, shadePalette(bg, fg))
+		drawShapes(img, s, origin, layout.module)
+		return img, nil
+	}
+
+	img := image.NewPaletted(rect, color.Palette{bg, fg})
+	for row := 0; row < m.size; row++ {
+		top := origin + row*layout.module
+		line := img.Pix[top*img.Stride : top*img.Stride+layout.size]
+		for col := 0; col < m.size; col++ {
+			if m.Get(col, row) {
+				left := origin + col*layout.module
+				for i := left; i < left+layout.module; i++ {
+					line[i] = 1
+				}
+			}
+		}
+		for dy := 1; dy < layout.module; dy++ {
+			copy(img.Pix[(top+dy)*img.Stride:], line)
+		}
+	}
+	return img, nil
+}
+
+// drawShapes dessine chaque module sombre avec sa forme ; la couverture de
+// chaque géométrie n'est calculée qu'une fois
+func drawShapes(img *image.Paletted, s *shaper, origin, module int) {
+	masks := make(map[moduleGeometry][]uint8)
+	for row := 0; row < s.m.size; row++ {
+		for col := 0; col < s.m.size; col++ {
+			if !s.m.Get(col, row) {
+				continue
+			}
+			g := s.geometry(col, row)
+			mask, ok := masks[g]
+			if !ok {
+				mask = g.coverage(module)
+				masks[g] = mask
+			}
+
+			left, top := origin+col*module, origin+row*module
+			for dy := 0; dy < module; dy++ {
+				line := img.Pix[(top+dy)*img.Stride+left:]
+				for dx, coverage := range mask[dy*module : (dy+1)*module] {
+					line[dx] = shadeIndex(0, coverage)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package qr
+
+import (
+	"testing"
+)
+
+func TestNewRasterLayout(t *testing.T) {
+	// Symbole de 21 modules, 29 avec la zone calme de 4 modules
+	m := NewMatrixFromImage(benchmarkSymbol(1))
+
+	tests := []struct {
+		name    string
+		opts    RenderOptions
+		want    rasterLayout
+		wantErr bool
+	}{
+		{"Échelle seule", RenderOptions{QuietZone: 4, Scale: 10}, rasterLayout{module: 10, size: 290}, false},
+		{"Échelle nulle", RenderOptions{QuietZone: 4}, rasterLayout{module: 1, size: 29}, false},
+		{"Taille exacte en pixels", RenderOptions{QuietZone: 4, Scale: 10, SizePx: 290}, rasterLayout{module: 10, size: 290}, false},
+		{"Marge répartie autour du symbole", RenderOptions{QuietZone: 4, Scale: 10, SizePx: 600}, rasterLayout{module: 20, size: 600, offset: 10}, false},
+		{"Marge impaire", RenderOptions{QuietZone: 4, SizePx: 61}, rasterLayout{module: 2, size: 61, offset: 1}, false},
+		{"25 mm à 300 DPI", RenderOptions{QuietZone: 4, SizeMM: 25, DPI: 300}, rasterLayout{module: 10, size: 295, offset: 2}, false},
+		{"Résolution par défaut", RenderOptions{QuietZone: 4, SizeMM: 25}, rasterLayout{module: 10, size: 295, offset: 2}, false},
+		{"Pixels prioritaires sur les millimètres", RenderOptions{QuietZone: 4, SizePx: 58, SizeMM: 25}, rasterLayout{module: 2, size: 58}, false},
+		{"Module de 0,5 mm à 600 DPI", RenderOptions{QuietZone: 4, ModuleMM: 0.5, DPI: 600}, rasterLayout{module: 12, size: 348}, false},
+		{"Image trop petite", RenderOptions{QuietZone: 4, SizePx: 20}, rasterLayout{}, true},
+		{"Taille négative", RenderOptions{QuietZone: 4, SizePx: -1}, rasterLayout{}, true},
+		{"Résolution négative", RenderOptions{QuietZone: 4, SizeMM: 25, DPI: -300}, rasterLayout{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newRasterLayout(m, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRasterLayout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("newRasterLayout() = %+v, attendu %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRasterizeCentersSymbol(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(1))
+	opts := RenderOptions{QuietZone: 4, SizePx: 61}
+
+	img, err := rasterize(m, opts)
+	if err != nil {
+		t.Fatalf("rasterize() error = %v", err)
+	}
+	if img.Bounds().Dx() != 61 || img.Bounds().Dy() != 61 {
+		t.Fatalf("image de %v, attendu 61×61", img.Bounds().Size())
+	}
+
+	// Modules de 2 pixels, décalés de 1 pixel plus la zone calme
+	for y := 0; y < 61; y++ {
+		for x := 0; x < 61; x++ {
+			dark := x >= 1 && y >= 1 && m.Get((x-1)/2-4, (y-1)/2-4)
+			if got := img.ColorIndexAt(x, y) == 1; got != dark {
+				t.Fatalf("pixel (%d,%d) sombre = %v, attendu %v", x, y, got, dark)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// readMinContrast est l'écart de luminance minimal, sur 255, entre les
+// modules et le fond d'une image lue
+const readMinContrast = 0x20
+
+// ReadMatrix lit les modules d'un symbole rendu : une image droite, zone calme
+// comprise, sans cadre ni rotation, comme celles des moteurs de rendu
+// matriciels. La polarité est déduite de la zone calme, si bien que les
+// symboles en réflectance inversée (modules clairs sur fond sombre) sont lus
+// comme les autres ; inverted l'indique. Chaque module est échantillonné en son
+// centre, puis l'information de format doit être lisible. Les pixels
+// translucides sont posés sur du blanc.
+func ReadMatrix(img image.Image) (m *Matrix, inverted bool, err error) {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, false, fmt.Errorf("image vide")
+	}
+	gray := image.NewGray(b)
+	draw.Draw(gray, b, image.White, image.Point{}, draw.Src)
+	draw.Draw(gray, b, img, b.Min, draw.Over)
+
+	lo, hi := 0xFF, 0
+	for _, v := range gray.Pix {
+		lo, hi = min(lo, int(v)), max(hi, int(v))
+	}
+	if hi-lo < readMinContrast {
+		return nil, false, fmt.Errorf("contraste insuffisant entre les modules et le fond")
+	}
+	threshold := uint8((lo + hi) / 2)
+
+	// Le coin de l'image appartient à la zone calme, de la couleur des modules clairs
+	inverted = gray.GrayAt(b.Min.X, b.Min.Y).Y < threshold
+	dark := func(x, y int) bool {
+		return (gray.GrayAt(x, y).Y < threshold) != inverted
+	}
+
+	// Le symbole est le plus petit rectangle contenant tous les modules sombres
+	symbol := image.Rectangle{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if dark(x, y) {
+				symbol = symbol.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if symbol.Empty() || symbol.Min == b.Min {
+		return nil, inverted, fmt.Errorf("aucun symbole entouré d'une zone calme")
+	}
+
+	// Le bord haut du motif de repérage en haut à gauche mesure 7 modules
+	run := 0
+	for x := symbol.Min.X; x < symbol.Max.X && dark(x, symbol.Min.Y); x++ {
+		run++
+	}
+	size := int(math.Round(float64(symbol.Dx()) * 7 / float64(run)))
+	if size < 21 || size > 177 || (size-17)%4 != 0 {
+		return nil, inverted, fmt.Errorf("symbole de %d×%d pixels illisible : %d modules par côté estimés",
+			symbol.Dx(), symbol.Dy(), size)
+	}
+
+	m = NewMatrix(size)
+	for row := 0; row < size; row++ {
+		y := symbol.Min.Y + (2*row+1)*symbol.Dy()/(2*size)
+		for col := 0; col < size; col++ {
+			m.Set(col, row, dark(symbol.Min.X+(2*col+1)*symbol.Dx()/(2*size), y))
+		}
+	}
+	if _, _, ok := readFormatInfo(m); !ok {
+		return nil, inverted, fmt.Errorf("information de format illisible")
+	}
+	return m, inverted, nil
+}
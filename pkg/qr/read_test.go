@@ -0,0 +1,140 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadMatrix(t *testing.T) {
+	SetLogOutput(io.Discard)
+	defer SetLogOutput(os.Stdout)
+	symbol, err := GenerateSymbol(2, "https://example.com/qrfactory", "M")
+	if err != nil {
+		t.Fatalf("GenerateSymbol() error = %v", err)
+	}
+	m := NewMatrixFromImage(symbol.Matrix)
+
+	navy := color.NRGBA{B: 0x80, A: 0xFF}
+	yellow := color.NRGBA{R: 0xFF, G: 0xD0, A: 0xFF}
+	tests := []struct {
+		name         string
+		opts         RenderOptions
+		wantInverted bool
+	}{
+		{"Noir sur blanc", RenderOptions{QuietZone: 4, Scale: 4}, false},
+		{"Réflectance inversée", RenderOptions{QuietZone: 4, Scale: 4, Inverted: true}, true},
+		{"Inversé, taille imposée", RenderOptions{QuietZone: 2, SizePx: 250, Inverted: true}, true},
+		{"Inversé en couleurs", RenderOptions{QuietZone: 4, Scale: 3, Foreground: navy, Background: yellow, Inverted: true}, true},
+		// Les modules transparents laissent voir le blanc sous l'image
+		{"Inversé sur fond transparent", RenderOptions{QuietZone: 4, Scale: 2, Background: color.Transparent, Inverted: true}, true},
+		{"Couleurs inversées sans l'option", RenderOptions{QuietZone: 4, Scale: 2, Foreground: color.White, Background: color.Black}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := RenderImage(m, tt.opts)
+			if err != nil {
+				t.Fatalf("RenderImage() error = %v", err)
+			}
+			got, inverted, err := ReadMatrix(img)
+			if err != nil {
+				t.Fatalf("ReadMatrix() error = %v", err)
+			}
+			if inverted != tt.wantInverted {
+				t.Errorf("inversé = %v, attendu %v", inverted, tt.wantInverted)
+			}
+			if got.size != m.size || !bytes.Equal(got.Image().Pix, m.Image().Pix) {
+				t.Error("les modules lus diffèrent du symbole rendu")
+			}
+		})
+	}
+
+	t.Run("Modules fractionnaires", func(t *testing.T) {
+		dst := image.NewNRGBA(image.Rect(0, 0, 150, 150))
+		if err := Draw(dst, dst.Rect, m, RenderOptions{QuietZone: 4, Inverted: true, AntiAlias: true}); err != nil {
+			t.Fatalf("Draw() error = %v", err)
+		}
+		got, inverted, err := ReadMatrix(dst)
+		if err != nil {
+			t.Fatalf("ReadMatrix() error = %v", err)
+		}
+		if !inverted || !bytes.Equal(got.Image().Pix, m.Image().Pix) {
+			t.Error("symbole anticrénelé en réflectance inversée mal lu")
+		}
+	})
+
+	errors := []struct {
+		name string
+		img  image.Image
+	}{
+		{"Image unie", image.NewGray(image.Rect(0, 0, 10, 10))},
+		{"Image vide", image.NewGray(image.Rectangle{})},
+		{"Sans zone calme", mustRender(t, m, RenderOptions{Scale: 2, Inverted: true})},
+		{"Symbole quelconque", NewMatrixFromImage(benchmarkSymbol(1)).Image()},
+	}
+	for _, tt := range errors {
+		if _, _, err := ReadMatrix(tt.img); err == nil {
+			t.Errorf("%s : une erreur était attendue", tt.name)
+		}
+	}
+}
+
+func TestRenderInverted(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows([]string{
+		"101",
+		"110",
+		"011",
+	}))
+	opts := RenderOptions{QuietZone: 1, Scale: 1, Inverted: true}
+
+	img := mustRender(t, m, opts)
+	for _, p := range []struct {
+		x, y int
+		want color.Gray
+	}{
+		{0, 0, color.Gray{}},
+		{1, 1, color.Gray{Y: 0xFF}},
+		{2, 1, color.Gray{}},
+	} {
+		if got := color.GrayModel.Convert(img.At(p.x, p.y)); got != p.want {
+			t.Errorf("pixel (%d, %d) = %v, attendu %v", p.x, p.y, got, p.want)
+		}
+	}
+
+	var svg bytes.Buffer
+	if err := RenderSVG(&svg, m, opts); err != nil {
+		t.Fatalf("RenderSVG() error = %v", err)
+	}
+	if !strings.Contains(svg.String(), `<rect width="5" height="5" fill="#000000"/>`) {
+		t.Errorf("le fond SVG devrait être noir :\n%s", svg.String())
+	}
+
+	for _, tt := range []struct {
+		name string
+		opts RenderOptions
+		r    RendererFunc
+	}{
+		{"Ton direct", RenderOptions{Inverted: true, SpotColor: "PANTONE 286 C"}, RenderPDF},
+		{"Fond transparent imprimé", RenderOptions{Inverted: true, Background: color.Transparent}, RenderEPS},
+		{"Trame", RenderOptions{Inverted: true, Halftone: &Halftone{Image: image.NewGray(image.Rect(0, 0, 4, 4))}}, RenderPNG},
+	} {
+		if err := tt.r(io.Discard, m, tt.opts); err == nil {
+			t.Errorf("%s : la réflectance inversée devrait être refusée", tt.name)
+		}
+	}
+}
+
+// mustRender rend la matrice avec RenderImage et arrête le test en cas d'erreur
+func mustRender(t *testing.T, m *Matrix, opts RenderOptions) image.Image {
+	t.Helper()
+	img, err := RenderImage(m, opts)
+	if err != nil {
+		t.Fatalf("RenderImage() error = %v", err)
+	}
+	return img
+}
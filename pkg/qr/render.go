@@ -0,0 +1,344 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Formats de sortie pris en charge
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatGIF  = "gif"
+	FormatBMP  = "bmp"
+	FormatTIFF = "tiff"
+	FormatSVG  = "svg"
+	FormatPDF  = "pdf"
+	FormatEPS  = "eps"
+
+	// Affichage dans le terminal, sans fichier associé
+	FormatTerminal = "terminal"
+	FormatSixel    = "sixel"
+	FormatKitty    = "kitty"
+)
+
+// RenderOptions regroupe les paramètres communs aux moteurs de rendu
+type RenderOptions struct {
+	// Largeur de la zone calme en modules
+	QuietZone int
+
+	// Taille d'un module en pixels (0 pour laisser le SVG sans dimensions fixes)
+	Scale int
+
+	// Couleur des modules sombres
+	Foreground color.Color
+
+	// Couleur du fond et de la zone calme
+	Background color.Color
+
+	// Réflectance inversée : les modules sombres prennent la couleur du fond
+	// et les modules clairs, zone calme comprise, celle des modules. Avec les
+	// couleurs par défaut, le symbole est blanc sur noir, pour les interfaces
+	// sombres ; un fond transparent laisse alors voir la page sous les modules.
+	Inverted bool
+
+	// Titre et description accessibles, pour les formats qui les acceptent (SVG)
+	Title       string
+	Description string
+
+	// Préfixe des identifiants du document, dégradé et détourage du logo, pour
+	// incorporer plusieurs symboles dans une même page (SVG). S'il est vide, il
+	// est dérivé d'une empreinte de la matrice et de ces définitions.
+	IDPrefix string
+
+	// Tracer les contours des zones sombres plutôt que des segments horizontaux (SVG, PDF, EPS)
+	Outline bool
+
+	// Taille physique du symbole, zone calme comprise, en millimètres. Si elle est
+	// nulle, ModuleMM fixe la taille d'un module ; si les deux sont nulles, un
+	// module mesure Scale points (PDF, EPS) ou Scale pixels (images).
+	SizeMM   float64
+	ModuleMM float64
+
+	// Côté de l'image en pixels, zone calme comprise (images) ; prioritaire sur
+	// SizeMM. Le symbole est centré avec la plus grande taille de module entière.
+	SizePx int
+
+	// Résolution en points par pouce utilisée pour convertir les millimètres en
+	// pixels et inscrite dans l'image (DefaultDPI si elle est nulle)
+	DPI float64
+
+	// Nom de la couleur d'accompagnement (ton direct) des modules sombres (PDF, EPS).
+	// Foreground, convertie en CMJN, sert alors de couleur de substitution.
+	SpotColor string
+
+	// Dessiner les modules clairs plutôt que les sombres, pour les terminaux à
+	// fond sombre dont le texte est clair (terminal)
+	TerminalInverted bool
+
+	// Sortie ASCII (## par module sombre) sans caractères de bloc, pour les journaux (terminal)
+	TerminalASCII bool
+
+	// Peindre les demi-blocs avec Foreground et Background en couleurs ANSI 24 bits,
+	// indépendamment du thème du terminal (terminal)
+	TerminalColor bool
+
+	// Niveau de compression zlib (PNG) ; la valeur nulle correspond à png.DefaultCompression
+	PNGCompression png.CompressionLevel
+
+	// Qualité JPEG de 1 à 100 (DefaultJPEGQuality si elle est nulle)
+	JPEGQuality int
+
+	// Forme des modules de données et des modules des motifs de fonction
+	// (repérage, synchronisation, alignement, format), ShapeSquare si elles sont
+	// vides (images, SVG)
+	ModuleShape   string
+	FunctionShape string
+
+	// Rayon des coins de la forme arrondie, en fraction de module de 0 à 0,5
+	// (DefaultModuleRadius s'il est nul)
+	ModuleRadius float64
+
+	// Forme du cadre (7×7) et de la pupille (3×3) des motifs de repérage, parmi
+	// EyeShapes ; EyeSquare si elles sont vides (images, SVG)
+	EyeFrameShape string
+	EyePupilShape string
+
+	// Couleurs du cadre et de la pupille des motifs de repérage (Foreground si
+	// elles ne sont pas définies)
+	EyeFrameColor color.Color
+	EyePupilColor color.Color
+
+	// Couleur de chacun des motifs de repérage (haut gauche, haut droit, bas
+	// gauche), cadre et pupille compris ; prioritaire sur EyeFrameColor et
+	// EyePupilColor, une entrée nil laissant l'œil inchangé (images, SVG)
+	EyeColors []color.Color
+
+	// Dégradé remplaçant Foreground pour les modules et, sauf couleurs propres,
+	// les yeux (images, SVG) ; nil pour des modules unis
+	Gradient *Gradient
+
+	// Logo posé au centre du symbole (images, SVG) ; nil pour aucun. Le rendu
+	// échoue si le niveau de correction du symbole ne restaure pas les mots de
+	// code masqués : GenerateSymbolWithLogo relève ce niveau au besoin
+	Logo *Logo
+
+	// Cadre et légende autour du symbole (images, SVG) ; nil pour aucun
+	Frame *Frame
+
+	// Photo tramée dans les modules (images, SVG) ; nil pour des modules pleins
+	Halftone *Halftone
+
+	// Draw : modules de taille fractionnaire, lissés, qui remplissent tout le
+	// rectangle au lieu de la plus grande taille entière en pixels
+	AntiAlias bool
+}
+
+// NewDefaultRenderOptions crée des options de rendu avec des valeurs par défaut
+func NewDefaultRenderOptions() RenderOptions {
+	return RenderOptions{
+		QuietZone:  4,
+		Scale:      10,
+		Foreground: color.Black,
+		Background: color.White,
+	}
+}
+
+// polarity retourne les options aux couleurs effectives : en réflectance
+// inversée, les couleurs des modules et du fond sont échangées
+func (opts RenderOptions) polarity() RenderOptions {
+	if !opts.Inverted {
+		return opts
+	}
+	fg, bg := opts.Foreground, opts.Background
+	if fg == nil {
+		fg = color.Black
+	}
+	if bg == nil {
+		bg = color.White
+	}
+	opts.Foreground, opts.Background = bg, fg
+	opts.Inverted = false
+	return opts
+}
+
+// FormatFromFilename déduit le format de sortie de l'extension du fichier (PNG par défaut)
+func FormatFromFilename(path string) string {
+	if format, ok := ExtensionFormat(path); ok {
+		return format
+	}
+	return FormatPNG
+}
+
+// ExtensionFormat retourne le format désigné par l'extension du fichier, et
+// false si l'extension n'est associée à aucun format
+func ExtensionFormat(path string) (string, bool) {
+	renderersMutex.RLock()
+	defer renderersMutex.RUnlock()
+
+	format, ok := extensions[strings.ToLower(filepath.Ext(path))]
+	return format, ok
+}
+
+// Renderer écrit une matrice dans un format de sortie donné
+type Renderer interface {
+	Render(w io.Writer, m *Matrix, opts RenderOptions) error
+}
+
+// RendererFunc adapte une fonction de rendu à l'interface Renderer
+type RendererFunc func(w io.Writer, m *Matrix, opts RenderOptions) error
+
+// Render implémente l'interface Renderer
+func (f RendererFunc) Render(w io.Writer, m *Matrix, opts RenderOptions) error {
+	return f(w, m, opts)
+}
+
+// Registre des moteurs de rendu par format, avec leur type MIME et les
+// extensions de fichier qui les désignent
+var (
+	renderers = map[string]Renderer{
+		FormatPNG:      RendererFunc(RenderPNG),
+		FormatJPEG:     RasterRenderer(encodeJPEG),
+		FormatGIF:      RasterRenderer(encodeGIF),
+		FormatBMP:      RasterRenderer(encodeBMP),
+		FormatTIFF:     RasterRenderer(encodeTIFF),
+		FormatSVG:      RendererFunc(RenderSVG),
+		FormatPDF:      RendererFunc(RenderPDF),
+		FormatEPS:      RendererFunc(RenderEPS),
+		FormatTerminal: RendererFunc(RenderTerminal),
+		FormatSixel:    RendererFunc(RenderSixel),
+		FormatKitty:    RendererFunc(RenderKitty),
+	}
+	mimeTypes = map[string]string{
+		FormatPNG:  "image/png",
+		FormatJPEG: "image/jpeg",
+		FormatGIF:  "image/gif",
+		FormatBMP:  "image/bmp",
+		FormatTIFF: "image/tiff",
+		FormatSVG:  "image/svg+xml",
+		FormatPDF:  "application/pdf",
+		FormatEPS:  "application/postscript",
+	}
+	extensions = map[string]string{
+		".png":  FormatPNG,
+		".jpg":  FormatJPEG,
+		".jpeg": FormatJPEG,
+		".gif":  FormatGIF,
+		".bmp":  FormatBMP,
+		".tif":  FormatTIFF,
+		".tiff": FormatTIFF,
+		".svg":  FormatSVG,
+		".pdf":  FormatPDF,
+		".eps":  FormatEPS,
+		".ps":   FormatEPS,
+	}
+	renderersMutex sync.RWMutex
+)
+
+// NewRenderer retourne le moteur de rendu du format demandé (sans tenir compte de la casse)
+func NewRenderer(format string) (Renderer, error) {
+	renderersMutex.RLock()
+	defer renderersMutex.RUnlock()
+
+	r, ok := renderers[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("format de sortie inconnu : %q", format)
+	}
+	return r, nil
+}
+
+// RegisterRenderer ajoute ou remplace le moteur de rendu d'un format
+func RegisterRenderer(format string, r Renderer) {
+	renderersMutex.Lock()
+	defer renderersMutex.Unlock()
+
+	renderers[strings.ToLower(format)] = r
+}
+
+// RegisterExtension associe une extension de fichier (".webp" par exemple) à un format
+func RegisterExtension(ext string, format string) {
+	renderersMutex.Lock()
+	defer renderersMutex.Unlock()
+
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	extensions[strings.ToLower(ext)] = strings.ToLower(format)
+}
+
+// Formats retourne la liste triée des formats disposant d'un moteur de rendu
+func Formats() []string {
+	renderersMutex.RLock()
+	defer renderersMutex.RUnlock()
+
+	formats := make([]string, 0, len(renderers))
+	for format := range renderers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Render écrit la matrice dans w au format demandé
+func Render(w io.Writer, format string, m *Matrix, opts RenderOptions) error {
+	r, err := NewRenderer(format)
+	if err != nil {
+		return err
+	}
+	return r.Render(w, m, opts)
+}
+
+// DataURI rend la matrice au format demandé et la retourne sous forme d'URI
+// data: encodée en base64 (par exemple data:image/png;base64,...), pour
+// l'intégrer directement dans une page ou un e-mail HTML
+func DataURI(format string, m *Matrix, opts RenderOptions) (string, error) {
+	mimeType, ok := mimeTypes[strings.ToLower(format)]
+	if !ok {
+		return "", fmt.Errorf("format sans type MIME pour une URI data : %q", format)
+	}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, format, m, opts); err != nil {
+		return "", err
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// SaveQRImageFormat sauvegarde la matrice QR dans le format demandé
+func SaveQRImageFormat(matrix *image.RGBA, outputFile string, format string, opts RenderOptions) error {
+	r, err := NewRenderer(format)
+	if err != nil {
+		return err
+	}
+	return saveRendered(outputFile, r, matrix, opts)
+}
+
+// saveRendered crée le fichier de sortie et y écrit la matrice avec le moteur de rendu
+func saveRendered(outputFile string, r Renderer, matrix *image.RGBA, opts RenderOptions) error {
+	// Création du fichier de sortie
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la création du fichier : %v", err)
+	}
+
+	// Un rendu impossible ne laisse pas de fichier incomplet
+	if err := r.Render(file, NewMatrixFromImage(matrix), opts); err != nil {
+		file.Close()
+		os.Remove(outputFile)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("erreur lors de l'écriture du fichier : %v", err)
+	}
+	return nil
+}
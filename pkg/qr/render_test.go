@@ -0,0 +1,184 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewRenderer(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows(cornerRows))
+	opts := NewDefaultRenderOptions()
+
+	tests := []struct {
+		format  string
+		prefix  string
+		wantErr bool
+	}{
+		{FormatPNG, "\x89PNG", false},
+		{FormatSVG, "<?xml", false},
+		{FormatPDF, "%PDF-", false},
+		{FormatEPS, "%!PS-Adobe", false},
+		{FormatTerminal, "", false},
+		{FormatSixel, "\x1bP", false},
+		{FormatKitty, "\x1b_G", false},
+		{"PNG", "\x89PNG", false},
+		{"webp", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			r, err := NewRenderer(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRenderer(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var buf bytes.Buffer
+			if err := r.Render(&buf, m, opts); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if buf.Len() == 0 || !strings.HasPrefix(buf.String(), tt.prefix) {
+				t.Errorf("sortie %q inattendue, attendu le préfixe %q", buf.String()[:min(buf.Len(), 16)], tt.prefix)
+			}
+		})
+	}
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	called := false
+	RegisterRenderer("Test", RendererFunc(func(w io.Writer, m *Matrix, opts RenderOptions) error {
+		called = true
+		_, err := io.WriteString(w, "ok")
+		return err
+	}))
+	defer func() {
+		renderersMutex.Lock()
+		delete(renderers, "test")
+		renderersMutex.Unlock()
+	}()
+
+	found := false
+	for _, format := range Formats() {
+		found = found || format == "test"
+	}
+	if !found {
+		t.Errorf("Formats() = %v, le format enregistré est absent", Formats())
+	}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, "test", NewMatrixFromImage(matrixFromRows(cornerRows)), RenderOptions{}); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !called || buf.String() != "ok" {
+		t.Errorf("moteur enregistré non utilisé : sortie %q", buf.String())
+	}
+}
+
+func TestDataURI(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows(cornerRows))
+	opts := NewDefaultRenderOptions()
+
+	uri, err := DataURI(FormatPNG, m, opts)
+	if err != nil {
+		t.Fatalf("DataURI() error = %v", err)
+	}
+	const prefix = "data:image/png;base64,"
+	if !strings.HasPrefix(uri, prefix) {
+		t.Fatalf("URI %q sans le préfixe %q", uri[:min(len(uri), 32)], prefix)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, prefix))
+	if err != nil {
+		t.Fatalf("base64 invalide : %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("PNG illisible : %v", err)
+	}
+
+	uri, err = DataURI(FormatSVG, m, opts)
+	if err != nil || !strings.HasPrefix(uri, "data:image/svg+xml;base64,") {
+		t.Errorf("DataURI(svg) = %q, %v", uri[:min(len(uri), 32)], err)
+	}
+
+	if _, err := DataURI(FormatTerminal, m, opts); err == nil {
+		t.Error("DataURI(terminal) devrait échouer, ce format n'a pas de type MIME")
+	}
+}
+
+func TestSaveQRImageFormat(t *testing.T) {
+	dir := t.TempDir()
+	matrix := matrixFromRows(cornerRows)
+	opts := NewDefaultRenderOptions()
+
+	path := filepath.Join(dir, "qr.pdf")
+	if err := SaveQRImageFormat(matrix, path, FormatPDF, opts); err != nil {
+		t.Fatalf("SaveQRImageFormat() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fichier non créé : %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Errorf("le fichier ne contient pas un PDF")
+	}
+
+	if err := SaveQRImageFormat(matrix, filepath.Join(dir, "qr.webp"), "webp", opts); err == nil {
+		t.Error("un format inconnu devrait être refusé")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "qr.webp")); !os.IsNotExist(err) {
+		t.Error("aucun fichier ne devrait être créé pour un format inconnu")
+	}
+}
+
+func TestRegisterExtension(t *testing.T) {
+	RegisterExtension("WEBP", FormatGIF)
+	defer func() {
+		renderersMutex.Lock()
+		delete(extensions, ".webp")
+		renderersMutex.Unlock()
+	}()
+
+	if got := FormatFromFilename("qr.webp"); got != FormatGIF {
+		t.Errorf("FormatFromFilename(qr.webp) = %q, attendu %q", got, FormatGIF)
+	}
+}
+
+func TestSaveQRImageWithQuietZoneByExtension(t *testing.T) {
+	dir := t.TempDir()
+	matrix := matrixFromRows(cornerRows)
+
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{"qr.png", "\x89PNG"},
+		{"qr.jpg", "\xff\xd8"},
+		{"qr.gif", "GIF89a"},
+		{"qr.bmp", "BM"},
+		{"qr.tiff", "II*\x00"},
+		{"qr.svg", "<?xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := SaveQRImageWithQuietZone(matrix, path, 3, 2); err != nil {
+				t.Fatalf("SaveQRImageWithQuietZone() error = %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("fichier non créé : %v", err)
+			}
+			if !strings.HasPrefix(string(data), tt.prefix) {
+				t.Errorf("le fichier commence par %q, attendu %q", data[:min(len(data), 8)], tt.prefix)
+			}
+		})
+	}
+}
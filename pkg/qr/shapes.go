@@ -0,0 +1,256 @@
+package qr
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Formes des modules (PNG et formats matriciels, SVG)
+const (
+	ShapeSquare         = "square"
+	ShapeCircle         = "circle"
+	ShapeRounded        = "rounded"
+	ShapeDiamond        = "diamond"
+	ShapeVerticalBars   = "vertical-bars"
+	ShapeHorizontalBars = "horizontal-bars"
+	ShapeLiquid         = "liquid"
+)
+
+// DefaultModuleRadius est le rayon des coins de la forme arrondie, en fraction de module
+const DefaultModuleRadius = 0.25
+
+// barInset est le retrait de chaque côté d'une barre, en fraction de module
+const barInset = 0.1
+
+// shapeSamples est le nombre de sous-échantillons par côté de pixel pour l'anticrénelage
+const shapeSamples = 4
+
+// Shapes retourne la liste des formes de module disponibles
+func Shapes() []string {
+	return []string{ShapeSquare, ShapeCircle, ShapeRounded, ShapeDiamond, ShapeVerticalBars, ShapeHorizontalBars, ShapeLiquid}
+}
+
+// moduleGeometry décrit la forme dessinée dans un module sombre, en coordonnées
+// relatives au module (de 0 à 1)
+type moduleGeometry struct {
+	// Rectangle occupé dans le module
+	x0, y0, x1, y1 float64
+
+	// Rayons des coins : haut gauche, haut droit, bas droit, bas gauche
+	radii [4]float64
+
+	// Losange inscrit dans le rectangle plutôt que rectangle arrondi
+	diamond bool
+}
+
+// fullModule est la géométrie d'un module carré
+var fullModule = moduleGeometry{x1: 1, y1: 1}
+
+// contains indique si le point (x, y) du module appartient à la forme
+func (g moduleGeometry) contains(x, y float64) bool {
+	if x < g.x0 || x > g.x1 || y < g.y0 || y > g.y1 {
+		return false
+	}
+	if g.diamond {
+		cx, cy := (g.x0+g.x1)/2, (g.y0+g.y1)/2
+		return math.Abs(x-cx)/(cx-g.x0)+math.Abs(y-cy)/(cy-g.y0) <= 1
+	}
+
+	// Centres des arcs de coin : haut gauche, haut droit, bas droit, bas gauche
+	corners := [4][2]float64{
+		{g.x0 + g.radii[0], g.y0 + g.radii[0]},
+		{g.x1 - g.radii[1], g.y0 + g.radii[1]},
+		{g.x1 - g.radii[2], g.y1 - g.radii[2]},
+		{g.x0 + g.radii[3], g.y1 - g.radii[3]},
+	}
+	for i, c := range corners {
+		r := g.radii[i]
+		if r == 0 {
+			continue
+		}
+		outsideX := (i == 0 || i == 3) && x < c[0] || (i == 1 || i == 2) && x > c[0]
+		outsideY := (i == 0 || i == 1) && y < c[1] || (i == 2 || i == 3) && y > c[1]
+		if outsideX && outsideY && (x-c[0])*(x-c[0])+(y-c[1])*(y-c[1]) > r*r {
+			return false
+		}
+	}
+	return true
+}
+
+// shaper calcule la forme de chaque module sombre selon son voisinage : les
+// modules des motifs de fonction suivent FunctionShape, les autres ModuleShape
+type shaper struct {
+	m             *Matrix
+	function      *Matrix
+	moduleShape   string
+	functionShape string
+	radius        float64
+}
+
+// newShaper valide les formes des options et prépare leur calcul
+func newShaper(m *Matrix, opts RenderOptions) (*shaper, error) {
+	s := &shaper{
+		m:             m,
+		moduleShape:   strings.ToLower(opts.ModuleShape),
+		functionShape: strings.ToLower(opts.FunctionShape),
+		radius:        opts.ModuleRadius,
+	}
+	if s.moduleShape == "" {
+		s.moduleShape = ShapeSquare
+	}
+	if s.functionShape == "" {
+		s.functionShape = ShapeSquare
+	}
+	if s.radius == 0 {
+		s.radius = DefaultModuleRadius
+	}
+
+	for _, shape := range []string{s.moduleShape, s.functionShape} {
+		if !slices.Contains(Shapes(), shape) {
+			return nil, fmt.Errorf("forme de module inconnue : %q (attendu %s)", shape, strings.Join(Shapes(), ", "))
+		}
+	}
+	if s.radius < 0 || s.radius > 0.5 {
+		return nil, fmt.Errorf("rayon de module invalide : %g (attendu de 0 à 0,5)", s.radius)
+	}
+
+	if !s.square() {
+		s.function = NewMatrix(m.size)
+		for y := 0; y < m.size; y++ {
+			for x := 0; x < m.size; x++ {
+				s.function.Set(x, y, isFunctionPattern(x, y, m.size))
+			}
+		}
+	}
+	return s, nil
+}
+
+// square indique si tous les modules sont carrés, ce qui permet les rendus rapides
+func (s *shaper) square() bool {
+	return s.moduleShape == ShapeSquare && s.functionShape == ShapeSquare
+}
+
+// linked indique si le voisin (x, y) est un module sombre de la même catégorie
+// (motif de fonction ou données) que le module courant
+func (s *shaper) linked(x, y int, function bool) bool {
+	return s.m.Get(x, y) && s.function.Get(x, y) == function
+}
+
+// geometry retourne la forme du module sombre (x, y)
+func (s *shaper) geometry(x, y int) moduleGeometry {
+	if s.square() {
+		return fullModule
+	}
+	function := s.function.Get(x, y)
+	shape := s.moduleShape
+	if function {
+		shape = s.functionShape
+	}
+
+	g := fullModule
+	switch shape {
+	case ShapeCircle:
+		g.radii = [4]float64{0.5, 0.5, 0.5, 0.5}
+	case ShapeRounded:
+		g.radii = [4]float64{s.radius, s.radius, s.radius, s.radius}
+	case ShapeDiamond:
+		g.diamond = true
+	case ShapeVerticalBars:
+		// Les barres se prolongent jusqu'aux voisins du dessus et du dessous
+		g.x0, g.x1 = barInset, 1-barInset
+		end := 0.5 - barInset
+		if !s.linked(x, y-1, function) {
+			g.radii[0], g.radii[1] = end, end
+		}
+		if !s.linked(x, y+1, function) {
+			g.radii[2], g.radii[3] = end, end
+		}
+	case ShapeHorizontalBars:
+		g.y0, g.y1 = barInset, 1-barInset
+		end := 0.5 - barInset
+		if !s.linked(x-1, y, function) {
+			g.radii[0], g.radii[3] = end, end
+		}
+		if !s.linked(x+1, y, function) {
+			g.radii[1], g.radii[2] = end, end
+		}
+	case ShapeLiquid:
+		// Un coin n'est arrondi que si ses deux voisins directs sont clairs
+		left, right := s.linked(x-1, y, function), s.linked(x+1, y, function)
+		up, down := s.linked(x, y-1, function), s.linked(x, y+1, function)
+		for i, linked := range [4]bool{left || up, right || up, right || down, left || down} {
+			if !linked {
+				g.radii[i] = 0.5
+			}
+		}
+	}
+	return g
+}
+
+// coverage retourne, pour un module de size pixels de côté, la couverture de
+// chaque pixel par la forme, de 0 à shapeSamples²
+func (g moduleGeometry) coverage(size int) []uint8 {
+	cells := make([]uint8, size*size)
+	step := 1 / float64(size*shapeSamples)
+	for py := 0; py < size; py++ {
+		for px := 0; px < size; px++ {
+			var n uint8
+			for sy := 0; sy < shapeSamples; sy++ {
+				for sx := 0; sx < shapeSamples; sx++ {
+					x := (float64(px*shapeSamples+sx) + 0.5) * step
+					y := (float64(py*shapeSamples+sy) + 0.5) * step
+					if g.contains(x, y) {
+						n++
+					}
+				}
+			}
+			cells[py*size+px] = n
+		}
+	}
+	return cells
+}
+
+// shadePalette construit la palette anticrénelée : 0 pour le fond, 1 pour la
+// première encre (les modules) suivie de ses mélanges avec le fond pour les
+// couvertures partielles, puis chaque encre supplémentaire avec ses mélanges
+func shadePalette(bg color.Color, inks ...color.Color) color.Palette {
+	levels := shapeSamples * shapeSamples
+	palette := color.Palette{bg}
+	r0, g0, b0, a0 := bg.RGBA()
+	for k, ink := range inks {
+		r1, g1, b1, a1 := ink.RGBA()
+		if k == 0 {
+			palette = append(palette, ink)
+		}
+		for i := 1; i < levels; i++ {
+			mix := func(a, b uint32) uint16 {
+				return uint16((a*uint32(levels-i) + b*uint32(i)) / uint32(levels))
+			}
+			premultiplied := color.RGBA64{R: mix(r0, r1), G: mix(g0, g1), B: mix(b0, b1), A: mix(a0, a1)}
+			palette = append(palette, color.NRGBAModel.Convert(premultiplied))
+		}
+		if k > 0 {
+			palette = append(palette, ink)
+		}
+	}
+	return palette
+}
+
+// shadeIndex retourne l'indice de palette correspondant à la couverture d'un
+// pixel par l'encre numéro ink
+func shadeIndex(ink int, coverage uint8) uint8 {
+	levels := shapeSamples * shapeSamples
+	switch {
+	case coverage == 0:
+		return 0
+	case ink == 0 && coverage == uint8(levels):
+		return 1
+	case ink == 0:
+		return coverage + 1
+	default:
+		return uint8(1+levels*ink) + coverage - 1
+	}
+}
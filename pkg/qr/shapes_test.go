@@ -0,0 +1,166 @@
+package qr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestModuleGeometryContains(t *testing.T) {
+	circle := moduleGeometry{x1: 1, y1: 1, radii: [4]float64{0.5, 0.5, 0.5, 0.5}}
+	diamond := moduleGeometry{x1: 1, y1: 1, diamond: true}
+	bar := moduleGeometry{x0: barInset, x1: 1 - barInset, y1: 1, radii: [4]float64{0.4, 0.4, 0, 0}}
+
+	tests := []struct {
+		name string
+		g    moduleGeometry
+		x, y float64
+		want bool
+	}{
+		{"Carré, coin", fullModule, 0.01, 0.01, true},
+		{"Cercle, centre", circle, 0.5, 0.5, true},
+		{"Cercle, coin", circle, 0.05, 0.05, false},
+		{"Cercle, milieu d'un bord", circle, 0.02, 0.5, true},
+		{"Losange, centre", diamond, 0.5, 0.5, true},
+		{"Losange, coin", diamond, 0.2, 0.2, false},
+		{"Losange, sommet", diamond, 0.5, 0.02, true},
+		{"Barre, retrait latéral", bar, 0.05, 0.5, false},
+		{"Barre, extrémité arrondie", bar, 0.12, 0.02, false},
+		{"Barre, extrémité droite", bar, 0.12, 0.98, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.g.contains(tt.x, tt.y); got != tt.want {
+				t.Errorf("contains(%g, %g) = %v, attendu %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShaperGeometry(t *testing.T) {
+	// Dans un symbole de 5 modules, tous les modules relèvent des motifs de
+	// fonction : la forme est donc donnée aux deux catégories
+	m := NewMatrixFromImage(matrixFromRows(cornerRows))
+	end := 0.5 - barInset
+
+	tests := []struct {
+		name  string
+		shape string
+		x, y  int
+		want  moduleGeometry
+	}{
+		{"Liquide, coin isolé", ShapeLiquid, 0, 0, moduleGeometry{x1: 1, y1: 1, radii: [4]float64{0.5, 0, 0, 0}}},
+		{"Liquide, angle intérieur", ShapeLiquid, 2, 2, moduleGeometry{x1: 1, y1: 1, radii: [4]float64{0.5, 0, 0, 0}}},
+		{"Liquide, module entouré", ShapeLiquid, 3, 2, fullModule},
+		{"Barres verticales, haut", ShapeVerticalBars, 0, 0, moduleGeometry{x0: barInset, x1: 1 - barInset, y1: 1, radii: [4]float64{end, end, 0, 0}}},
+		{"Barres verticales, bas", ShapeVerticalBars, 0, 1, moduleGeometry{x0: barInset, x1: 1 - barInset, y1: 1, radii: [4]float64{0, 0, end, end}}},
+		{"Barres horizontales, module seul", ShapeHorizontalBars, 2, 3, moduleGeometry{y0: barInset, x1: 1, y1: 1 - barInset, radii: [4]float64{end, end, end, end}}},
+		{"Arrondi", ShapeRounded, 0, 0, moduleGeometry{x1: 1, y1: 1, radii: [4]float64{0.25, 0.25, 0.25, 0.25}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newShaper(m, RenderOptions{ModuleShape: tt.shape, FunctionShape: tt.shape})
+			if err != nil {
+				t.Fatalf("newShaper() error = %v", err)
+			}
+			if got := s.geometry(tt.x, tt.y); got != tt.want {
+				t.Errorf("geometry(%d, %d) = %+v, attendu %+v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewShaperValidation(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows(cornerRows))
+
+	tests := []struct {
+		name    string
+		opts    RenderOptions
+		wantErr bool
+	}{
+		{"Valeurs par défaut", RenderOptions{}, false},
+		{"Casse ignorée", RenderOptions{ModuleShape: "Circle"}, false},
+		{"Forme inconnue", RenderOptions{ModuleShape: "star"}, true},
+		{"Forme de fonction inconnue", RenderOptions{FunctionShape: "star"}, true},
+		{"Rayon trop grand", RenderOptions{ModuleShape: ShapeRounded, ModuleRadius: 0.6}, true},
+		{"Rayon négatif", RenderOptions{ModuleShape: ShapeRounded, ModuleRadius: -0.1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newShaper(m, tt.opts); (err != nil) != tt.wantErr {
+				t.Errorf("newShaper() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRasterizeShapes(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	opts := RenderOptions{QuietZone: 4, Scale: 8, ModuleShape: ShapeCircle}
+
+	img, err := rasterize(m, opts)
+	if err != nil {
+		t.Fatalf("rasterize() error = %v", err)
+	}
+	if len(img.Palette) != 2+shapeSamples*shapeSamples-1 {
+		t.Fatalf("palette de %d couleurs, attendu les teintes d'anticrénelage", len(img.Palette))
+	}
+
+	for y := 0; y < m.Size(); y++ {
+		for x := 0; x < m.Size(); x++ {
+			left, top := (x+4)*8, (y+4)*8
+			center, corner := img.ColorIndexAt(left+4, top+4), img.ColorIndexAt(left, top)
+			switch {
+			case !m.Get(x, y):
+				if center != 0 || corner != 0 {
+					t.Fatalf("module clair (%d,%d) dessiné", x, y)
+				}
+			case isFunctionPattern(x, y, m.Size()):
+				// Les motifs de fonction restent carrés
+				if center != 1 || corner != 1 {
+					t.Fatalf("module de fonction (%d,%d) non carré : centre %d, coin %d", x, y, center, corner)
+				}
+			default:
+				if center != 1 || corner != 0 {
+					t.Fatalf("module de données (%d,%d) non circulaire : centre %d, coin %d", x, y, center, corner)
+				}
+			}
+		}
+	}
+}
+
+func TestRenderSVGShapes(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows(cornerRows))
+
+	var buf bytes.Buffer
+	opts := RenderOptions{ModuleShape: ShapeCircle, FunctionShape: ShapeCircle}
+	if err := RenderSVG(&buf, m, opts); err != nil {
+		t.Fatalf("RenderSVG() error = %v", err)
+	}
+	doc := buf.String()
+
+	if strings.Contains(doc, "crispEdges") {
+		t.Error("les formes arrondies ne doivent pas être rendues en crispEdges")
+	}
+	d := svgPathAttr.FindStringSubmatch(doc)
+	if d == nil {
+		t.Fatal("aucun chemin dans le document")
+	}
+	// Un sous-chemin fermé et quatre arcs par module sombre
+	if got, want := strings.Count(d[1], "z"), m.countDark(); got != want {
+		t.Errorf("%d sous-chemins, attendu %d modules sombres", got, want)
+	}
+	if got, want := strings.Count(d[1], "A"), 4*m.countDark(); got != want {
+		t.Errorf("%d arcs, attendu %d", got, want)
+	}
+	if !strings.HasPrefix(d[1], "M0.5 0H0.5A0.5 0.5 0 0 1 1 0.5") {
+		t.Errorf("premier module mal tracé : %q", d[1][:min(len(d[1]), 40)])
+	}
+
+	if err := RenderSVG(&buf, m, RenderOptions{ModuleShape: "star"}); err == nil {
+		t.Error("une forme inconnue devrait être refusée")
+	}
+}
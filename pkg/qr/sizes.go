@@ -0,0 +1,111 @@
+package qr
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Jeux de tailles prédéfinis pour les exports multirésolution
+const (
+	SizesIOS     = "ios"
+	SizesAndroid = "android"
+	SizesWeb     = "web"
+)
+
+// SizePresets retourne la liste des jeux de tailles prédéfinis
+func SizePresets() []string {
+	return []string{SizesIOS, SizesAndroid, SizesWeb}
+}
+
+// SizeVariant est une déclinaison d'un export multirésolution : le côté de
+// l'image en pixels, zone calme comprise, et le suffixe ajouté au nom de fichier.
+// Une déclinaison optionnelle est omise, plutôt que refusée, quand les modules
+// n'y tiennent pas.
+type SizeVariant struct {
+	Suffix   string
+	Px       int
+	Optional bool
+}
+
+// densities associe aux jeux relatifs les multiplicateurs de la taille de base
+// et leurs suffixes : facteurs d'échelle iOS et densités d'écran Android
+var densities = map[string][]struct {
+	suffix string
+	factor float64
+}{
+	SizesIOS:     {{"", 1}, {"@2x", 2}, {"@3x", 3}},
+	SizesAndroid: {{"-mdpi", 1}, {"-hdpi", 1.5}, {"-xhdpi", 2}, {"-xxhdpi", 3}, {"-xxxhdpi", 4}},
+}
+
+// faviconSizes sont les favicons du jeu web : un symbole de version 1 et sa
+// zone calme y tiennent à partir de 29 pixels, 16 pixels ne suffisent jamais
+var faviconSizes = []int{16, 32, 48}
+
+// webSizes sont l'icône tactile Apple et les icônes de manifeste d'application web
+var webSizes = []int{180, 192, 512}
+
+// ParseSizes lit un jeu de tailles : le nom d'un jeu prédéfini (SizePresets) ou
+// une liste de côtés en pixels séparés par des virgules. Les jeux iOS et Android
+// multiplient la taille de base ; le jeu web et les listes l'ignorent. Les
+// favicons du jeu web sont optionnels. Dans une liste, le premier côté sert de
+// référence : ses multiples entiers prennent le suffixe @2x, @3x…, les autres
+// tailles le suffixe -<côté>px.
+func ParseSizes(spec string, base int) ([]SizeVariant, error) {
+	name := strings.ToLower(strings.TrimSpace(spec))
+	if factors, ok := densities[name]; ok {
+		if base <= 0 {
+			return nil, fmt.Errorf("taille de base invalide pour le jeu %s : %d pixels", name, base)
+		}
+		variants := make([]SizeVariant, 0, len(factors))
+		for _, d := range factors {
+			variants = append(variants, SizeVariant{Suffix: d.suffix, Px: int(math.Round(float64(base) * d.factor))})
+		}
+		return variants, nil
+	}
+	if name == SizesWeb {
+		variants := make([]SizeVariant, 0, len(faviconSizes)+len(webSizes))
+		for _, px := range slices.Concat(faviconSizes, webSizes) {
+			variants = append(variants, SizeVariant{
+				Suffix:   fmt.Sprintf("-%dx%d", px, px),
+				Px:       px,
+				Optional: slices.Contains(faviconSizes, px),
+			})
+		}
+		return variants, nil
+	}
+
+	var variants []SizeVariant
+	var seen []int
+	for _, field := range strings.Split(spec, ",") {
+		px, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || px <= 0 {
+			return nil, fmt.Errorf("jeu de tailles invalide : %q (attendu %s ou des côtés en pixels séparés par des virgules)",
+				spec, strings.Join(SizePresets(), ", "))
+		}
+		if slices.Contains(seen, px) {
+			return nil, fmt.Errorf("taille répétée dans le jeu de tailles : %d pixels", px)
+		}
+		seen = append(seen, px)
+
+		suffix := ""
+		switch ref := seen[0]; {
+		case px == ref:
+		case px%ref == 0:
+			suffix = fmt.Sprintf("@%dx", px/ref)
+		default:
+			suffix = fmt.Sprintf("-%dpx", px)
+		}
+		variants = append(variants, SizeVariant{Suffix: suffix, Px: px})
+	}
+	return variants, nil
+}
+
+// Filename insère le suffixe de la déclinaison avant l'extension du fichier
+func (v SizeVariant) Filename(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + v.Suffix + ext
+}
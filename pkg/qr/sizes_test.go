@@ -0,0 +1,61 @@
+package qr
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		base    int
+		want    []SizeVariant
+		wantErr bool
+	}{
+		{"Liste de multiples", "128,256,512", 0, []SizeVariant{{"", 128, false}, {"@2x", 256, false}, {"@4x", 512, false}}, false},
+		{"Liste avec espaces et non-multiple", " 100, 150 ,300", 0, []SizeVariant{{"", 100, false}, {"-150px", 150, false}, {"@3x", 300, false}}, false},
+		{"iOS", "iOS", 100, []SizeVariant{{"", 100, false}, {"@2x", 200, false}, {"@3x", 300, false}}, false},
+		{"Android", "android", 48, []SizeVariant{{"-mdpi", 48, false}, {"-hdpi", 72, false}, {"-xhdpi", 96, false}, {"-xxhdpi", 144, false}, {"-xxxhdpi", 192, false}}, false},
+		{"Web, base ignorée", "web", 0, []SizeVariant{
+			{"-16x16", 16, true}, {"-32x32", 32, true}, {"-48x48", 48, true},
+			{"-180x180", 180, false}, {"-192x192", 192, false}, {"-512x512", 512, false},
+		}, false},
+		{"Jeu relatif sans base", "ios", 0, nil, true},
+		{"Taille nulle", "128,0", 0, nil, true},
+		{"Taille répétée", "128,256,128", 0, nil, true},
+		{"Jeu inconnu", "windows", 0, nil, true},
+		{"Vide", "", 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSizes(tt.spec, tt.base)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSizes(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseSizes(%q) = %v, attendu %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizeVariantFilename(t *testing.T) {
+	tests := []struct {
+		path    string
+		variant SizeVariant
+		want    string
+	}{
+		{"qrcode.png", SizeVariant{Suffix: "@2x"}, "qrcode@2x.png"},
+		{"out/icon.v2.svg", SizeVariant{Suffix: "-hdpi"}, "out/icon.v2-hdpi.svg"},
+		{"qrcode", SizeVariant{Suffix: "-180x180"}, "qrcode-180x180"},
+		{"qrcode.png", SizeVariant{}, "qrcode.png"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.variant.Filename(tt.path); got != tt.want {
+			t.Errorf("Filename(%q) = %q, attendu %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Ancrages du symbole sur l'image de fond
+const (
+	AnchorTopLeft     = "top-left"
+	AnchorTop         = "top"
+	AnchorTopRight    = "top-right"
+	AnchorLeft        = "left"
+	AnchorCenter      = "center"
+	AnchorRight       = "right"
+	AnchorBottomLeft  = "bottom-left"
+	AnchorBottom      = "bottom"
+	AnchorBottomRight = "bottom-right"
+)
+
+// Anchors retourne la liste des ancrages disponibles
+func Anchors() []string {
+	return []string{
+		AnchorTopLeft, AnchorTop, AnchorTopRight,
+		AnchorLeft, AnchorCenter, AnchorRight,
+		AnchorBottomLeft, AnchorBottom, AnchorBottomRight,
+	}
+}
+
+// StampOptions place le symbole rendu sur une image existante
+type StampOptions struct {
+	// Ancrage parmi Anchors (AnchorBottomRight s'il est vide), ignoré si
+	// Position est définie
+	Anchor string
+
+	// Distance en pixels entre le symbole (ou sa plaque) et les bords de
+	// l'image du côté de l'ancrage
+	Margin int
+
+	// Coin haut gauche du symbole (ou de sa plaque) dans l'image, prioritaire sur Anchor
+	Position *image.Point
+
+	// Côté du symbole en pixels, zone calme comprise ; 0 garde la taille
+	// donnée par les options de rendu
+	Size int
+
+	// Opacité du symbole, de 0 à 1 (1 si elle est nulle) ; la plaque reste opaque
+	Opacity float64
+
+	// Plaque unie posée sous le symbole, débordant de PlatePadding pixels,
+	// pour qu'il reste lisible sur une image chargée ou avec une opacité réduite
+	Plate        bool
+	PlatePadding int
+
+	// Couleur de la plaque (blanc si elle n'est pas définie)
+	PlateColor color.Color
+}
+
+// layout retourne les rectangles de la plaque et du symbole de taille size
+// dans l'image bounds
+func (s StampOptions) layout(bounds image.Rectangle, size image.Point) (plate, symbol image.Rectangle, err error) {
+	if s.Margin < 0 || s.PlatePadding < 0 {
+		return plate, symbol, fmt.Errorf("marges invalides : %d pixels, plaque de %d pixels", s.Margin, s.PlatePadding)
+	}
+	padding := 0
+	if s.Plate {
+		padding = s.PlatePadding
+	}
+	outer := image.Pt(size.X+2*padding, size.Y+2*padding)
+
+	var at image.Point
+	if s.Position != nil {
+		at = bounds.Min.Add(*s.Position)
+	} else {
+		anchor := strings.ToLower(s.Anchor)
+		if anchor == "" {
+			anchor = AnchorBottomRight
+		}
+		if !slices.Contains(Anchors(), anchor) {
+			return plate, symbol, fmt.Errorf("ancrage inconnu : %q (attendu %s)", s.Anchor, strings.Join(Anchors(), ", "))
+		}
+		// Début, centre ou fin de chaque axe selon l'ancrage
+		place := func(lo, hi, length int, start, end bool) int {
+			switch {
+			case start:
+				return lo + s.Margin
+			case end:
+				return hi - s.Margin - length
+			default:
+				return lo + (hi-lo-length)/2
+			}
+		}
+		at.X = place(bounds.Min.X, bounds.Max.X, outer.X, strings.HasSuffix(anchor, "left"), strings.HasSuffix(anchor, "right"))
+		at.Y = place(bounds.Min.Y, bounds.Max.Y, outer.Y, strings.HasPrefix(anchor, "top"), strings.HasPrefix(anchor, "bottom"))
+	}
+
+	plate = image.Rectangle{Min: at, Max: at.Add(outer)}
+	if !plate.In(bounds) {
+		return plate, symbol, fmt.Errorf("le symbole de %d×%d pixels, plaque comprise, déborde de l'image de %d×%d pixels en %v",
+			outer.X, outer.Y, bounds.Dx(), bounds.Dy(), plate.Min.Sub(bounds.Min))
+	}
+	symbol = plate.Inset(padding)
+	return plate, symbol, nil
+}
+
+// Stamp rend la matrice avec RenderImage et la dessine sur dst selon s :
+// plaque éventuelle, puis symbole composé avec son opacité. Il retourne le
+// rectangle occupé par le symbole.
+func Stamp(dst draw.Image, m *Matrix, opts RenderOptions, s StampOptions) (image.Rectangle, error) {
+	if s.Size < 0 {
+		return image.Rectangle{}, fmt.Errorf("taille de symbole invalide : %d pixels", s.Size)
+	}
+	if s.Opacity < 0 || s.Opacity > 1 {
+		return image.Rectangle{}, fmt.Errorf("opacité invalide : %g (attendu de 0 à 1)", s.Opacity)
+	}
+	if s.Size > 0 {
+		opts.SizePx, opts.SizeMM, opts.ModuleMM = s.Size, 0, 0
+	}
+	img, err := RenderImage(m, opts)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+
+	plate, symbol, err := s.layout(dst.Bounds(), img.Bounds().Size())
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	if s.Plate {
+		draw.Draw(dst, plate, image.NewUniform(toNRGBA(s.PlateColor, color.White)), image.Point{}, draw.Over)
+	}
+
+	opacity := s.Opacity
+	if opacity == 0 {
+		opacity = 1
+	}
+	mask := image.NewUniform(color.Alpha{A: uint8(math.Round(opacity * 0xFF))})
+	draw.DrawMask(dst, symbol, img, img.Bounds().Min, mask, image.Point{}, draw.Over)
+	return symbol, nil
+}
+
+// StampImage copie l'image de fond et y pose le symbole avec Stamp
+func StampImage(base image.Image, m *Matrix, opts RenderOptions, s StampOptions) (*image.NRGBA, error) {
+	out := image.NewNRGBA(base.Bounds())
+	draw.Draw(out, out.Rect, base, base.Bounds().Min, draw.Src)
+	if _, err := Stamp(out, m, opts, s); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
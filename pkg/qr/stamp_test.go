@@ -0,0 +1,89 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestStampLayout(t *testing.T) {
+	bounds := image.Rect(0, 0, 400, 300)
+	size := image.Pt(100, 100)
+
+	tests := []struct {
+		name      string
+		stamp     StampOptions
+		wantPlate image.Rectangle
+		wantErr   bool
+	}{
+		{"Ancrage par défaut", StampOptions{Margin: 10}, image.Rect(290, 190, 390, 290), false},
+		{"Haut gauche", StampOptions{Anchor: "Top-Left", Margin: 10}, image.Rect(10, 10, 110, 110), false},
+		{"Centre, marge ignorée", StampOptions{Anchor: AnchorCenter, Margin: 10}, image.Rect(150, 100, 250, 200), false},
+		{"Bas, plaque", StampOptions{Anchor: AnchorBottom, Plate: true, PlatePadding: 5}, image.Rect(145, 190, 255, 300), false},
+		{"Position", StampOptions{Position: &image.Point{X: 20, Y: 30}, Anchor: "ignoré"}, image.Rect(20, 30, 120, 130), false},
+		{"Débordement", StampOptions{Position: &image.Point{X: 350, Y: 0}}, image.Rectangle{}, true},
+		{"Ancrage inconnu", StampOptions{Anchor: "middle"}, image.Rectangle{}, true},
+		{"Marge négative", StampOptions{Margin: -1}, image.Rectangle{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plate, symbol, err := tt.stamp.layout(bounds, size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("layout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if plate != tt.wantPlate {
+				t.Errorf("plaque %v, attendu %v", plate, tt.wantPlate)
+			}
+			if symbol.Size() != size || !symbol.In(plate) {
+				t.Errorf("symbole %v hors de la plaque %v", symbol, plate)
+			}
+		})
+	}
+}
+
+func TestStampImage(t *testing.T) {
+	m := NewMatrixFromImage(benchmarkSymbol(2))
+	red := color.NRGBA{R: 0xFF, A: 0xFF}
+	base := solidLogo(500, 400, red)
+	opts := RenderOptions{QuietZone: 4, Scale: 10, Foreground: color.Black, Background: color.Transparent}
+
+	tests := []struct {
+		name  string
+		stamp StampOptions
+		x, y  int
+		want  color.NRGBA
+	}{
+		// Symbole de 33 modules : 330 pixels, ou 198 pour une taille de 200
+		{"Fond transparent, image visible", StampOptions{Anchor: AnchorTopLeft}, 5, 5, red},
+		{"Module sombre", StampOptions{Anchor: AnchorTopLeft}, 45, 45, color.NRGBA{A: 0xFF}},
+		{"Plaque sous la zone calme", StampOptions{Anchor: AnchorTopLeft, Plate: true, PlatePadding: 10}, 5, 5, color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+		{"Hors du symbole", StampOptions{Size: 200}, 10, 10, red},
+		{"Module sombre ancré en bas à droite", StampOptions{Size: 200}, 500 - 198 + 4*6 + 1, 400 - 198 + 4*6 + 1, color.NRGBA{A: 0xFF}},
+		{"Opacité", StampOptions{Anchor: AnchorTopLeft, Opacity: 0.5}, 45, 45, color.NRGBA{R: 0x7F, A: 0xFF}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := StampImage(base, m, opts, tt.stamp)
+			if err != nil {
+				t.Fatalf("StampImage() error = %v", err)
+			}
+			if out.Bounds() != base.Bounds() {
+				t.Fatalf("image de %v, attendu %v", out.Bounds(), base.Bounds())
+			}
+			if got := out.NRGBAAt(tt.x, tt.y); got != tt.want {
+				t.Errorf("pixel (%d, %d) = %v, attendu %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+
+	for _, s := range []StampOptions{{Size: 1000}, {Opacity: 1.5}, {Size: -1}} {
+		if _, err := StampImage(base, m, opts, s); err == nil {
+			t.Errorf("StampImage(%+v) devrait échouer", s)
+		}
+	}
+}
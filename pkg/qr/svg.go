@@ -0,0 +1,511 @@
+package qr
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Directions des arêtes de contour, une par bit
+const (
+	edgeRight = 1 << iota
+	edgeDown
+	edgeLeft
+	edgeUp
+)
+
+// SaveQRImageSVG sauvegarde la matrice QR en image vectorielle SVG
+func SaveQRImageSVG(matrix *image.RGBA, outputFile string, opts RenderOptions) error {
+	return saveRendered(outputFile, RendererFunc(RenderSVG), matrix, opts)
+}
+
+// RenderSVG écrit la matrice en SVG dans w : un fond couvrant la zone calme et un
+// unique <path> regroupant tous les modules sombres. Le viewBox est exprimé en
+// modules, zone calme comprise ; SizePx, SizeMM ou à défaut Scale fixent la
+// taille d'affichage. Des yeux personnalisés sont tracés à part, un chemin pour
+// les cadres et un pour les pupilles.
+func RenderSVG(w io.Writer, m *Matrix, opts RenderOptions) error {
+	m, opts, err := prepareHalftone(m, opts)
+	if err != nil {
+		return err
+	}
+	opts = opts.polarity()
+	total := m.size + 2*opts.QuietZone
+	frame, err := newFrameLayout(opts.Frame, total, opts)
+	if err != nil {
+		return err
+	}
+	m, logo, err := prepareLogo(m, opts)
+	if err != nil {
+		return err
+	}
+	eyes, err := newEyeStyle(opts)
+	if err != nil {
+		return err
+	}
+	modules := m
+	if eyes != nil {
+		modules = withoutEyes(m)
+	}
+	shapes, err := newShaper(modules, opts)
+	if err != nil {
+		return err
+	}
+
+	// Les options sont toutes validées avant la première écriture : une erreur
+	// ne laisse pas de document partiel dans w
+	if eyes != nil && len(opts.EyeColors) > len(eyeOrigins(m.size)) {
+		return fmt.Errorf("%d couleurs d'yeux, le symbole n'a que trois motifs de repérage", len(opts.EyeColors))
+	}
+	gradient, err := newGradientFill(opts.Gradient, m.size)
+	if err != nil {
+		return err
+	}
+	var logoPNG []byte
+	if logo != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, logo.logo.Image); err != nil {
+			return fmt.Errorf("erreur lors de l'encodage du logo : %v", err)
+		}
+		logoPNG = buf.Bytes()
+	}
+	ids, err := svgIDPrefix(m, opts, gradient, logo, logoPNG)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+
+	// Un cadre agrandit le document ; les dimensions demandées restent celles du symbole
+	width, height := float64(total), float64(total)
+	if frame != nil {
+		width, height = frame.width, frame.height
+	}
+	ratio := func(side float64) float64 { return side / float64(total) }
+
+	fmt.Fprintf(bw, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" version=\"1.1\" viewBox=\"0 0 %s %s\"", formatNumber(width), formatNumber(height))
+	if logo != nil {
+		fmt.Fprintf(bw, " xmlns:xlink=\"http://www.w3.org/1999/xlink\"")
+	}
+	switch {
+	case opts.SizePx > 0:
+		fmt.Fprintf(bw, " width=\"%d\" height=\"%d\"",
+			int(math.Round(float64(opts.SizePx)*ratio(width))), int(math.Round(float64(opts.SizePx)*ratio(height))))
+	case opts.SizeMM > 0:
+		fmt.Fprintf(bw, " width=\"%smm\" height=\"%smm\"", formatNumber(opts.SizeMM*ratio(width)), formatNumber(opts.SizeMM*ratio(height)))
+	case opts.Scale > 0:
+		fmt.Fprintf(bw, " width=\"%d\" height=\"%d\"",
+			int(math.Round(float64(opts.Scale)*width)), int(math.Round(float64(opts.Scale)*height)))
+	}
+	if opts.Title != "" {
+		fmt.Fprintf(bw, " role=\"img\"")
+	}
+	// Le lissage ne sert qu'aux formes arrondies ; les carrés restent nets
+	if shapes.square() && eyes.square() {
+		fmt.Fprintf(bw, " shape-rendering=\"crispEdges\"")
+	}
+	fmt.Fprintf(bw, ">\n")
+
+	if opts.Title != "" {
+		fmt.Fprintf(bw, "<title>%s</title>\n", escapeXML(opts.Title))
+	}
+	if opts.Description != "" {
+		fmt.Fprintf(bw, "<desc>%s</desc>\n", escapeXML(opts.Description))
+	}
+
+	// Fond, zone calme et cadre compris
+	if fill := svgFill(opts.Background, color.White); fill != "" {
+		fmt.Fprintf(bw, "<rect width=\"%s\" height=\"%s\"%s/>\n", formatNumber(width), formatNumber(height), fill)
+	}
+	if frame != nil {
+		fmt.Fprintf(bw, "<g transform=\"translate(%s %s)\">\n", formatNumber(frame.symbolX), formatNumber(frame.symbolY))
+	}
+
+	// Dégradé partagé par les modules et les yeux sans couleur propre
+	foreground := svgFill(opts.Foreground, color.Black)
+	if gradient != nil {
+		svgGradient(bw, gradient, float64(opts.QuietZone), ids+"foreground")
+		foreground = " fill=\"url(#" + ids + "foreground)\""
+	}
+
+	// Modules sombres en un seul chemin
+	var path string
+	switch {
+	case !shapes.square():
+		path = svgShapesPath(shapes, opts.QuietZone)
+	case opts.Outline:
+		path = svgOutlinePath(modules, opts.QuietZone)
+	default:
+		path = svgRunsPath(modules, opts.QuietZone)
+	}
+	if foreground != "" && path != "" {
+		fmt.Fprintf(bw, "<path%s d=\"%s\"/>\n", foreground, path)
+	}
+
+	if eyes != nil {
+		// Un chemin par remplissage, dans l'ordre d'apparition
+		var fills []string
+		paths := make(map[string]*strings.Builder)
+		add := func(fill string, g moduleGeometry, ox, oy float64) {
+			if fill == "" {
+				return
+			}
+			if paths[fill] == nil {
+				fills = append(fills, fill)
+				paths[fill] = &strings.Builder{}
+			}
+			svgGeometry(paths[fill], g, ox, oy)
+		}
+
+		frameFill, pupilFill := svgFill(eyes.frameColor, color.Black), svgFill(eyes.pupilColor, color.Black)
+		if gradient != nil && opts.EyeFrameColor == nil {
+			frameFill = foreground
+		}
+		if gradient != nil && opts.EyePupilColor == nil {
+			pupilFill = foreground
+		}
+		for i, o := range eyeOrigins(m.size) {
+			ox, oy := float64(o.X+opts.QuietZone), float64(o.Y+opts.QuietZone)
+			frameColor, pupilColor := frameFill, pupilFill
+			if i < len(opts.EyeColors) && opts.EyeColors[i] != nil {
+				frameColor = svgFill(opts.EyeColors[i], color.Black)
+				pupilColor = frameColor
+			}
+			if frameColor != "" {
+				// L'évidement du cadre est obtenu par la règle pair-impair
+				outer, inner := eyes.frame(i)
+				add(frameColor+" fill-rule=\"evenodd\"", outer, ox, oy)
+				add(frameColor+" fill-rule=\"evenodd\"", inner, ox, oy)
+			}
+			add(pupilColor, eyes.pupil(i), ox, oy)
+		}
+		for _, fill := range fills {
+			fmt.Fprintf(bw, "<path%s d=\"%s\"/>\n", fill, paths[fill].String())
+		}
+	}
+
+	if logo != nil {
+		svgLogo(bw, logo, logoPNG, float64(opts.QuietZone), ids+"logo")
+	}
+
+	if frame != nil {
+		fmt.Fprintf(bw, "</g>\n")
+		svgFrame(bw, frame)
+	}
+
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// svgIDPrefix retourne le préfixe des identifiants du document : celui des
+// options, ou une empreinte de la matrice, de la zone calme, du dégradé et du
+// logo. Deux symboles d'une même page ne partagent ainsi un identifiant que
+// pour des définitions identiques.
+func svgIDPrefix(m *Matrix, opts RenderOptions, gradient *gradientFill, logo *logoLayout, logoPNG []byte) (string, error) {
+	if opts.IDPrefix != "" {
+		for i, r := range opts.IDPrefix {
+			letter := r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+			if !letter && (i == 0 || !(r == '-' || r == '.' || ('0' <= r && r <= '9'))) {
+				return "", fmt.Errorf("préfixe d'identifiants SVG invalide : %q (lettres, chiffres, -, _ et ., en commençant par une lettre)", opts.IDPrefix)
+			}
+		}
+		return opts.IDPrefix, nil
+	}
+
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, int64(m.size))
+	binary.Write(h, binary.LittleEndian, m.bits)
+	fmt.Fprintf(h, "%d", opts.QuietZone)
+	if gradient != nil {
+		fmt.Fprintf(h, "%v", *gradient)
+	}
+	if logo != nil {
+		fmt.Fprintf(h, "%v", logo.image)
+		h.Write(logoPNG)
+	}
+	return fmt.Sprintf("qr%012x-", h.Sum64()>>16), nil
+}
+
+// svgGradient écrit le dégradé des modules sous l'identifiant id, en
+// coordonnées de l'image décalées de la zone calme
+func svgGradient(w io.Writer, f *gradientFill, offset float64, id string) {
+	n := func(v float64) string { return formatNumber(offset + v) }
+	if f.radial {
+		fmt.Fprintf(w, "<defs><radialGradient id=\"%s\" gradientUnits=\"userSpaceOnUse\" cx=\"%s\" cy=\"%s\" r=\"%s\">",
+			id, n(f.x1), n(f.y1), formatNumber(f.radius))
+	} else {
+		fmt.Fprintf(w, "<defs><linearGradient id=\"%s\" gradientUnits=\"userSpaceOnUse\" x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\">",
+			id, n(f.x1), n(f.y1), n(f.x2), n(f.y2))
+	}
+	for i, c := range f.colors {
+		fmt.Fprintf(w, "<stop offset=\"%s\" stop-color=\"#%02x%02x%02x\"", formatNumber(float64(i)/float64(len(f.colors)-1)), c.R, c.G, c.B)
+		if c.A < 0xFF {
+			fmt.Fprintf(w, " stop-opacity=\"%s\"", strconv.FormatFloat(float64(c.A)/255, 'f', 3, 64))
+		}
+		fmt.Fprintf(w, "/>")
+	}
+	if f.radial {
+		fmt.Fprintf(w, "</radialGradient></defs>\n")
+	} else {
+		fmt.Fprintf(w, "</linearGradient></defs>\n")
+	}
+}
+
+// svgFrame écrit la bordure, le bandeau et la légende du cadre ; une légende
+// évidée fait partie du chemin du bandeau, la règle pair-impair la découpant
+func svgFrame(w io.Writer, l *frameLayout) {
+	shapes, text := l.shapes.svg(), l.text.svg()
+	if l.knockout {
+		shapes, text = shapes+text, ""
+	}
+	if fill := svgFill(l.color, color.Black); fill != "" && shapes != "" {
+		fmt.Fprintf(w, "<path%s fill-rule=\"evenodd\" shape-rendering=\"geometricPrecision\" d=\"%s\"/>\n", fill, shapes)
+	}
+	if fill := svgFill(l.textColor, color.Black); fill != "" && text != "" {
+		fmt.Fprintf(w, "<path%s shape-rendering=\"geometricPrecision\" d=\"%s\"/>\n", fill, text)
+	}
+}
+
+// svgLogo écrit le logo, déjà encodé en PNG, incorporé et détouré par sa forme,
+// dont le détourage porte l'identifiant id
+func svgLogo(w io.Writer, l *logoLayout, data []byte, offset float64, id string) {
+	var clip strings.Builder
+	svgGeometry(&clip, l.image, offset, offset)
+	fmt.Fprintf(w, "<clipPath id=\"%s\"><path shape-rendering=\"geometricPrecision\" d=\"%s\"/></clipPath>\n", id, clip.String())
+
+	x, y, width, height := l.fit()
+	fmt.Fprintf(w, "<image x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" clip-path=\"url(#%s)\" xlink:href=\"data:image/png;base64,%s\"/>\n",
+		formatNumber(offset+x), formatNumber(offset+y), formatNumber(width), formatNumber(height), id,
+		base64.StdEncoding.EncodeToString(data))
+}
+
+// moduleRun est une suite horizontale de modules sombres
+type moduleRun struct {
+	x, y, length int
+}
+
+// darkRuns retourne les suites horizontales de modules sombres, ligne par ligne
+func darkRuns(m *Matrix) []moduleRun {
+	var runs []moduleRun
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; {
+			if !m.Get(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < m.size && m.Get(x, y) {
+				x++
+			}
+			runs = append(runs, moduleRun{x: start, y: y, length: x - start})
+		}
+	}
+	return runs
+}
+
+// svgShapesPath décrit chaque module sombre par sa forme
+func svgShapesPath(s *shaper, offset int) string {
+	var d strings.Builder
+	for y := 0; y < s.m.size; y++ {
+		for x := 0; x < s.m.size; x++ {
+			if s.m.Get(x, y) {
+				svgGeometry(&d, s.geometry(x, y), float64(x+offset), float64(y+offset))
+			}
+		}
+	}
+	return d.String()
+}
+
+// svgGeometry ajoute au chemin la forme g placée en (ox, oy) : losange, ou
+// rectangle dont les coins arrondis sont tracés en arcs
+func svgGeometry(d *strings.Builder, g moduleGeometry, ox, oy float64) {
+	x0, y0, x1, y1 := ox+g.x0, oy+g.y0, ox+g.x1, oy+g.y1
+
+	if g.diamond {
+		cx, cy := (x0+x1)/2, (y0+y1)/2
+		fmt.Fprintf(d, "M%s %sL%s %sL%s %sL%s %sz", formatNumber(cx), formatNumber(y0), formatNumber(x1), formatNumber(cy),
+			formatNumber(cx), formatNumber(y1), formatNumber(x0), formatNumber(cy))
+		return
+	}
+
+	r := g.radii
+	fmt.Fprintf(d, "M%s %sH%s", formatNumber(x0+r[0]), formatNumber(y0), formatNumber(x1-r[1]))
+	svgArc(d, r[1], x1, y0+r[1])
+	fmt.Fprintf(d, "V%s", formatNumber(y1-r[2]))
+	svgArc(d, r[2], x1-r[2], y1)
+	fmt.Fprintf(d, "H%s", formatNumber(x0+r[3]))
+	svgArc(d, r[3], x0, y1-r[3])
+	fmt.Fprintf(d, "V%s", formatNumber(y0+r[0]))
+	svgArc(d, r[0], x0+r[0], y0)
+	d.WriteString("z")
+}
+
+// svgArc trace un quart de cercle de rayon r dans le sens horaire jusqu'à (x, y)
+func svgArc(d *strings.Builder, r, x, y float64) {
+	if r > 0 {
+		fmt.Fprintf(d, "A%s %s 0 0 1 %s %s", formatNumber(r), formatNumber(r), formatNumber(x), formatNumber(y))
+	}
+}
+
+// svgRunsPath décrit chaque suite horizontale de modules sombres par un rectangle
+func svgRunsPath(m *Matrix, offset int) string {
+	var d strings.Builder
+	for _, r := range darkRuns(m) {
+		fmt.Fprintf(&d, "M%d %dh%dv1h-%dz", r.x+offset, r.y+offset, r.length, r.length)
+	}
+	return d.String()
+}
+
+// svgOutlinePath trace le contour de chaque zone sombre connexe
+func svgOutlinePath(m *Matrix, offset int) string {
+	var d strings.Builder
+	for _, loop := range outlineLoops(m) {
+		fmt.Fprintf(&d, "M%d %d", loop[0].X+offset, loop[0].Y+offset)
+		// Le dernier côté, qui revient au point de départ, est fermé par z
+		for i := 1; i < len(loop); i++ {
+			if dx := loop[i].X - loop[i-1].X; dx != 0 {
+				fmt.Fprintf(&d, "h%d", dx)
+			} else {
+				fmt.Fprintf(&d, "v%d", loop[i].Y-loop[i-1].Y)
+			}
+		}
+		d.WriteByte('z')
+	}
+	return d.String()
+}
+
+// outlineLoops retourne le contour de chaque zone sombre connexe sous forme de
+// boucles fermées de sommets, un par changement de direction. Les contours
+// extérieurs tournent dans le sens horaire (axe y vers le bas) et les trous dans
+// le sens inverse, si bien que la règle de remplissage nonzero les évide.
+func outlineLoops(m *Matrix) [][]image.Point {
+	n := m.size + 1
+	edges := make([]uint8, n*n)
+
+	// Arêtes orientées laissant le module sombre à leur droite
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if !m.Get(x, y) {
+				continue
+			}
+			if !m.Get(x, y-1) {
+				edges[y*n+x] |= edgeRight
+			}
+			if !m.Get(x+1, y) {
+				edges[y*n+x+1] |= edgeDown
+			}
+			if !m.Get(x, y+1) {
+				edges[(y+1)*n+x+1] |= edgeLeft
+			}
+			if !m.Get(x-1, y) {
+				edges[(y+1)*n+x] |= edgeUp
+			}
+		}
+	}
+
+	// Chaque sommet a autant d'arêtes entrantes que sortantes : un parcours ne
+	// peut donc s'arrêter qu'en revenant à son point de départ
+	var loops [][]image.Point
+	for start := range edges {
+		for edges[start] != 0 {
+			x, y := start%n, start/n
+			loop := []image.Point{{x, y}}
+
+			dir := lowestEdge(edges[start])
+			for dir != 0 {
+				// Avancer tant que la direction ne change pas, pour fusionner les segments
+				for {
+					edges[y*n+x] &^= dir
+					dx, dy := edgeDelta(dir)
+					x, y = x+dx, y+dy
+					if edges[y*n+x]&dir == 0 {
+						break
+					}
+				}
+				dir = nextEdge(edges[y*n+x], dir)
+				if dir != 0 {
+					loop = append(loop, image.Point{x, y})
+				}
+			}
+			loops = append(loops, loop)
+		}
+	}
+
+	return loops
+}
+
+// lowestEdge retourne la première direction présente dans l'ensemble
+func lowestEdge(set uint8) uint8 {
+	return set & -set
+}
+
+// edgeDelta retourne le déplacement correspondant à une direction
+func edgeDelta(dir uint8) (int, int) {
+	switch dir {
+	case edgeRight:
+		return 1, 0
+	case edgeDown:
+		return 0, 1
+	case edgeLeft:
+		return -1, 0
+	default:
+		return 0, -1
+	}
+}
+
+// nextEdge choisit l'arête suivante au sommet courant en privilégiant le virage
+// à droite, pour que deux zones ne se touchant que par un coin restent séparées.
+// Un demi-tour est impossible : le module sombre est toujours à droite.
+func nextEdge(set uint8, dir uint8) uint8 {
+	for _, candidate := range []uint8{rotateEdge(dir, 1), dir, rotateEdge(dir, 3)} {
+		if set&candidate != 0 {
+			return candidate
+		}
+	}
+	return 0
+}
+
+// rotateEdge tourne une direction de quarts de tour dans le sens horaire
+func rotateEdge(dir uint8, quarters int) uint8 {
+	shift := 0
+	for dir>>shift != 1 {
+		shift++
+	}
+	return 1 << ((shift + quarters) % 4)
+}
+
+// svgFill retourne l'attribut de remplissage SVG d'une couleur, vide si elle est
+// totalement transparente ; fallback est utilisée si la couleur n'est pas définie
+func svgFill(c color.Color, fallback color.Color) string {
+	if c == nil {
+		c = fallback
+	}
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if nrgba.A == 0 {
+		return ""
+	}
+
+	fill := fmt.Sprintf(" fill=\"#%02x%02x%02x\"", nrgba.R, nrgba.G, nrgba.B)
+	if nrgba.A < 0xFF {
+		fill += " fill-opacity=\"" + strconv.FormatFloat(float64(nrgba.A)/255, 'f', 3, 64) + "\""
+	}
+	return fill
+}
+
+// escapeXML échappe un texte pour l'insérer dans un élément XML
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
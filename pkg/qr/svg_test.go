@@ -0,0 +1,300 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Zones touchant par un coin et zone évidée, pièges classiques du traçage de contours
+var cornerRows = []string{
+	"11000",
+	"11000",
+	"00111",
+	"00101",
+	"00111",
+}
+
+var (
+	svgPathAttr    = regexp.MustCompile(` d="([^"]*)"`)
+	svgPathCommand = regexp.MustCompile(`([MhvzHV])([^MhvzHV]*)`)
+)
+
+// parseSVGPath découpe un chemin SVG composé de M, h, v et z en polygones fermés
+func parseSVGPath(t *testing.T, d string) [][]image.Point {
+	t.Helper()
+
+	var polygons [][]image.Point
+	var current []image.Point
+	var x, y int
+	for _, cmd := range svgPathCommand.FindAllStringSubmatch(d, -1) {
+		args := strings.Fields(cmd[2])
+		values := make([]int, len(args))
+		for i, arg := range args {
+			v, err := strconv.Atoi(arg)
+			if err != nil {
+				t.Fatalf("argument %q invalide dans le chemin", arg)
+			}
+			values[i] = v
+		}
+
+		switch cmd[1] {
+		case "M":
+			x, y = values[0], values[1]
+			current = []image.Point{{x, y}}
+		case "h":
+			x += values[0]
+			current = append(current, image.Point{x, y})
+		case "v":
+			y += values[0]
+			current = append(current, image.Point{x, y})
+		case "z":
+			polygons = append(polygons, current)
+			current = nil
+		default:
+			t.Fatalf("commande %q inattendue dans le chemin", cmd[1])
+		}
+	}
+	if current != nil {
+		t.Fatalf("sous-chemin non fermé : %v", current)
+	}
+	return polygons
+}
+
+// rasterizePolygons remplit une grille size×size à partir de polygones fermés à
+// côtés horizontaux et verticaux, décalés de offset, selon la règle nonzero
+// appliquée au centre de chaque module
+func rasterizePolygons(t *testing.T, polygons [][]image.Point, size, offset int) *Matrix {
+	t.Helper()
+
+	winding := make([]int, size*size)
+	for _, polygon := range polygons {
+		for i, from := range polygon {
+			// Le dernier côté revient au premier sommet
+			to := polygon[(i+1)%len(polygon)]
+			if from.X != to.X && from.Y != to.Y {
+				t.Fatalf("côté oblique de %v vers %v", from, to)
+			}
+			if from.X != to.X {
+				continue
+			}
+
+			// Un côté vertical change l'enroulement des modules situés à sa gauche
+			top, bottom, sign := from.Y-offset, to.Y-offset, 1
+			if bottom < top {
+				top, bottom, sign = bottom, top, -1
+			}
+			for row := max(top, 0); row < bottom && row < size; row++ {
+				for col := 0; col < from.X-offset && col < size; col++ {
+					winding[row*size+col] += sign
+				}
+			}
+		}
+	}
+
+	m := NewMatrix(size)
+	for i, w := range winding {
+		m.Set(i%size, i/size, w != 0)
+	}
+	return m
+}
+
+// assertSameModules vérifie que deux matrices ont les mêmes modules sombres
+func assertSameModules(t *testing.T, got, want *Matrix) {
+	t.Helper()
+	for y := 0; y < want.Size(); y++ {
+		for x := 0; x < want.Size(); x++ {
+			if got.Get(x, y) != want.Get(x, y) {
+				t.Errorf("module (%d,%d) : sombre = %v, attendu %v", x, y, got.Get(x, y), want.Get(x, y))
+			}
+		}
+	}
+}
+
+// renderSVGString rend la matrice en SVG et retourne le document
+func renderSVGString(t *testing.T, m *Matrix, opts RenderOptions) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := RenderSVG(&buf, m, opts); err != nil {
+		t.Fatalf("RenderSVG() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestRenderSVGPathMatchesMatrix(t *testing.T) {
+	symbol := NewMatrixFromImage(matrixFromRows(referenceSymbol))
+
+	corners := NewMatrixFromImage(matrixFromRows(cornerRows))
+
+	tests := []struct {
+		name    string
+		m       *Matrix
+		outline bool
+	}{
+		{"Symbole en segments", symbol, false},
+		{"Symbole en contours", symbol, true},
+		{"Coins et trou en segments", corners, false},
+		{"Coins et trou en contours", corners, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewDefaultRenderOptions()
+			opts.Outline = tt.outline
+			doc := renderSVGString(t, tt.m, opts)
+
+			paths := svgPathAttr.FindAllStringSubmatch(doc, -1)
+			if len(paths) != 1 {
+				t.Fatalf("%d éléments <path>, attendu un seul", len(paths))
+			}
+
+			polygons := parseSVGPath(t, paths[0][1])
+			assertSameModules(t, rasterizePolygons(t, polygons, tt.m.Size(), opts.QuietZone), tt.m)
+		})
+	}
+}
+
+func TestRenderSVGDocument(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows(referenceSymbol))
+
+	opts := NewDefaultRenderOptions()
+	opts.QuietZone = 2
+	opts.Scale = 3
+	opts.Title = `Lien <vers> "example" & co`
+	opts.Description = "QR code de test"
+	opts.Foreground = color.NRGBA{R: 0x12, G: 0x34, B: 0x56, A: 0x80}
+	doc := renderSVGString(t, m, opts)
+
+	for _, want := range []string{
+		`viewBox="0 0 25 25"`,
+		`width="75" height="75"`,
+		`role="img"`,
+		`<title>Lien &lt;vers&gt; &#34;example&#34; &amp; co</title>`,
+		`<desc>QR code de test</desc>`,
+		`<rect width="25" height="25" fill="#ffffff"/>`,
+		`fill="#123456" fill-opacity="0.502"`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("le document ne contient pas %s", want)
+		}
+	}
+
+	// Fond transparent et taille libre
+	opts = NewDefaultRenderOptions()
+	opts.Scale = 0
+	opts.Background = color.Transparent
+	doc = renderSVGString(t, m, opts)
+	if strings.Contains(doc, "<rect") || strings.Contains(doc, "width=") {
+		t.Errorf("fond transparent ou taille libre non respectés :\n%s", doc)
+	}
+}
+
+func TestRenderSVGInvalidOptions(t *testing.T) {
+	// Assez de modules pour que le chemin dépasse le tampon d'écriture
+	m := NewMatrixFromImage(benchmarkSymbol(20))
+	red := color.NRGBA{R: 0xFF, A: 0xFF}
+
+	tests := []struct {
+		name string
+		opts RenderOptions
+	}{
+		{"Quatre couleurs d'yeux", RenderOptions{QuietZone: 4, EyeColors: make([]color.Color, 4)}},
+		{"Dégradé d'une couleur", RenderOptions{QuietZone: 4, Gradient: &Gradient{Colors: []color.Color{red}}}},
+		{"Type de dégradé inconnu", RenderOptions{QuietZone: 4, Gradient: &Gradient{Type: "conic", Colors: []color.Color{red, red}}}},
+		{"Préfixe d'identifiants invalide", RenderOptions{QuietZone: 4, IDPrefix: "1 qr"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := RenderSVG(&buf, m, tt.opts); err == nil {
+				t.Fatal("des options invalides devraient être refusées")
+			}
+			// Rien n'est écrit avant la validation, pour -o - et DataURI
+			if buf.Len() != 0 {
+				t.Errorf("document partiel écrit malgré l'erreur : %d octets", buf.Len())
+			}
+		})
+	}
+}
+
+func TestRenderSVGIDPrefix(t *testing.T) {
+	colors := []color.Color{color.NRGBA{R: 0xFF, A: 0xFF}, color.NRGBA{B: 0xFF, A: 0xFF}}
+	gradient := &Gradient{Colors: colors}
+	render := func(m *Matrix, opts RenderOptions) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := RenderSVG(&buf, m, opts); err != nil {
+			t.Fatalf("RenderSVG() error = %v", err)
+		}
+		return buf.String()
+	}
+	id := regexp.MustCompile(`<linearGradient id="([^"]+)"`)
+	gradientID := func(doc string) string {
+		match := id.FindStringSubmatch(doc)
+		if match == nil || !strings.Contains(doc, `url(#`+match[1]+`)`) {
+			t.Fatalf("dégradé sans identifiant référencé : %s", doc)
+		}
+		return match[1]
+	}
+
+	a, b := NewMatrixFromImage(benchmarkSymbol(2)), NewMatrixFromImage(benchmarkSymbol(3))
+	first := gradientID(render(a, RenderOptions{Gradient: gradient}))
+	tests := []struct {
+		name     string
+		m        *Matrix
+		opts     RenderOptions
+		wantSame bool
+	}{
+		{"Même symbole, mêmes définitions", a, RenderOptions{Gradient: gradient}, true},
+		{"Autre symbole", b, RenderOptions{Gradient: gradient}, false},
+		{"Autre dégradé", a, RenderOptions{Gradient: &Gradient{Angle: 90, Colors: colors}}, false},
+		{"Autre zone calme", a, RenderOptions{QuietZone: 2, Gradient: gradient}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gradientID(render(tt.m, tt.opts)); (got == first) != tt.wantSame {
+				t.Errorf("identifiant %q, premier document %q, identiques attendus : %v", got, first, tt.wantSame)
+			}
+		})
+	}
+
+	if got := gradientID(render(a, RenderOptions{Gradient: gradient, IDPrefix: "menu-"})); got != "menu-foreground" {
+		t.Errorf("identifiant %q, attendu le préfixe des options", got)
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	tests := []struct {
+		path  string
+		want  string
+		known bool
+	}{
+		{"qr.svg", FormatSVG, true},
+		{"QR.SVG", FormatSVG, true},
+		{"qr.png", FormatPNG, true},
+		{"qr", FormatPNG, false},
+		{"qr.pdf", FormatPDF, true},
+		{"qr.ps", FormatEPS, true},
+		{"qr.jpg", FormatJPEG, true},
+		{"photo.JPEG", FormatJPEG, true},
+		{"qr.gif", FormatGIF, true},
+		{"qr.bmp", FormatBMP, true},
+		{"qr.tif", FormatTIFF, true},
+		{"dossier.tiff/qr", FormatPNG, false},
+		{"qr.webp", FormatPNG, false},
+	}
+
+	for _, tt := range tests {
+		if got := FormatFromFilename(tt.path); got != tt.want {
+			t.Errorf("FormatFromFilename(%q) = %q, attendu %q", tt.path, got, tt.want)
+		}
+		if got, ok := ExtensionFormat(tt.path); ok != tt.known || (ok && got != tt.want) {
+			t.Errorf("ExtensionFormat(%q) = %q, %v, attendu %q, %v", tt.path, got, ok, tt.want, tt.known)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package qr
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// Caractères de demi-bloc indexés par (haut, bas) : deux lignes de modules par ligne de texte
+var halfBlocks = [2][2]string{
+	{" ", "▄"},
+	{"▀", "█"},
+}
+
+// RenderTerminal écrit la matrice dans w sous forme de texte, zone calme comprise.
+// Chaque ligne de texte porte deux lignes de modules grâce aux demi-blocs ▀▄█ ;
+// en mode ASCII, chaque module devient deux caractères (## ou espaces) pour
+// conserver des proportions carrées. Les caractères pleins représentent les
+// modules sombres, ou les modules clairs si TerminalInverted est activé.
+func RenderTerminal(w io.Writer, m *Matrix, opts RenderOptions) error {
+	// Sans couleurs, la réflectance inversée dessine les modules clairs
+	if opts.Inverted && (opts.TerminalASCII || !opts.TerminalColor) {
+		opts.TerminalInverted = !opts.TerminalInverted
+	}
+	opts = opts.polarity()
+	total := m.size + 2*opts.QuietZone
+	bw := bufio.NewWriter(w)
+
+	// inked indique si le module (x, y), zone calme comprise, est dessiné
+	inked := func(x, y int) bool {
+		dark := m.Get(x-opts.QuietZone, y-opts.QuietZone)
+		return dark != opts.TerminalInverted
+	}
+
+	if opts.TerminalASCII {
+		for y := 0; y < total; y++ {
+			for x := 0; x < total; x++ {
+				if inked(x, y) {
+					bw.WriteString("##")
+				} else {
+					bw.WriteString("  ")
+				}
+			}
+			bw.WriteByte('\n')
+		}
+		return bw.Flush()
+	}
+
+	if opts.TerminalColor {
+		renderTerminalColor(bw, m, opts)
+		return bw.Flush()
+	}
+
+	for y := 0; y < total; y += 2 {
+		for x := 0; x < total; x++ {
+			// Une ligne impaire finale n'a pas de moitié basse
+			bottom := y+1 < total && inked(x, y+1)
+			bw.WriteString(halfBlocks[boolIndex(inked(x, y))][boolIndex(bottom)])
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// renderTerminalColor dessine chaque paire de lignes avec ▀ : la couleur du texte
+// peint la moitié haute et celle du fond la moitié basse. Une moitié transparente
+// laisse apparaître le fond du terminal ; le caractère est alors ▄ ou une espace.
+func renderTerminalColor(bw *bufio.Writer, m *Matrix, opts RenderOptions) {
+	total := m.size + 2*opts.QuietZone
+	fg := toNRGBA(opts.Foreground, color.Black)
+	bg := toNRGBA(opts.Background, color.White)
+
+	// moduleColor retourne la couleur d'un module, transparente hors de l'image
+	moduleColor := func(x, y int) color.NRGBA {
+		switch {
+		case y >= total:
+			return color.NRGBA{}
+		case m.Get(x-opts.QuietZone, y-opts.QuietZone):
+			return fg
+		default:
+			return bg
+		}
+	}
+
+	for y := 0; y < total; y += 2 {
+		var lastText, lastBack string
+		for x := 0; x < total; x++ {
+			top, bottom := moduleColor(x, y), moduleColor(x, y+1)
+
+			char, text, back := "▀", ansiColor(38, top), ansiColor(48, bottom)
+			switch {
+			case top.A == 0 && bottom.A == 0:
+				char, text = " ", lastText
+			case top.A == 0:
+				char, text, back = "▄", ansiColor(38, bottom), ansiColor(48, top)
+			}
+
+			// Les séquences ne sont répétées que lorsque la couleur change
+			if text != lastText {
+				bw.WriteString(text)
+				lastText = text
+			}
+			if back != lastBack {
+				bw.WriteString(back)
+				lastBack = back
+			}
+			bw.WriteString(char)
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+}
+
+// ansiColor retourne la séquence ANSI 24 bits de la couleur, pour le texte (38)
+// ou le fond (48) ; une couleur transparente rétablit la couleur par défaut
+func ansiColor(layer int, c color.NRGBA) string {
+	if c.A == 0 {
+		return fmt.Sprintf("\x1b[%dm", layer+1)
+	}
+	return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", layer, c.R, c.G, c.B)
+}
+
+// boolIndex convertit un booléen en indice de tableau
+func boolIndex(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,160 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestRenderTerminal(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows([]string{
+		"101",
+		"110",
+		"011",
+	}))
+
+	tests := []struct {
+		name      string
+		quietZone int
+		inverted  bool
+		reversed  bool
+		ascii     bool
+		want      []string
+	}{
+		{
+			name: "Demi-blocs sans zone calme",
+			want: []string{"█▄▀", " ▀▀"},
+		},
+		{
+			name:      "Demi-blocs avec zone calme",
+			quietZone: 1,
+			want:      []string{" ▄ ▄ ", " ▀█▄ ", "     "},
+		},
+		{
+			name:      "Demi-blocs inversés",
+			quietZone: 1,
+			inverted:  true,
+			want:      []string{"█▀█▀█", "█▄ ▀█", "▀▀▀▀▀"},
+		},
+		{
+			name:  "ASCII",
+			ascii: true,
+			want:  []string{"##  ##", "####  ", "  ####"},
+		},
+		{
+			name:      "ASCII inversé avec zone calme",
+			ascii:     true,
+			inverted:  true,
+			quietZone: 1,
+			want: []string{
+				"##########",
+				"##  ##  ##",
+				"##    ####",
+				"####    ##",
+				"##########",
+			},
+		},
+		{
+			name:      "ASCII en réflectance inversée",
+			ascii:     true,
+			reversed:  true,
+			quietZone: 1,
+			want: []string{
+				"##########",
+				"##  ##  ##",
+				"##    ####",
+				"####    ##",
+				"##########",
+			},
+		},
+		{
+			name:      "Réflectance inversée sur terminal sombre",
+			quietZone: 1,
+			inverted:  true,
+			reversed:  true,
+			want:      []string{" ▄ ▄ ", " ▀█▄ ", "     "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewDefaultRenderOptions()
+			opts.QuietZone = tt.quietZone
+			opts.TerminalInverted = tt.inverted
+			opts.Inverted = tt.reversed
+			opts.TerminalASCII = tt.ascii
+
+			var buf bytes.Buffer
+			if err := RenderTerminal(&buf, m, opts); err != nil {
+				t.Fatalf("RenderTerminal() error = %v", err)
+			}
+
+			want := strings.Join(tt.want, "\n") + "\n"
+			if got := buf.String(); got != want {
+				t.Errorf("RenderTerminal() =\n%s\nattendu\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestRenderTerminalColor(t *testing.T) {
+	m := NewMatrixFromImage(matrixFromRows([]string{
+		"100",
+		"010",
+		"110",
+	}))
+
+	const (
+		redText   = "\x1b[38;2;255;0;0m"
+		whiteText = "\x1b[38;2;255;255;255m"
+		redBack   = "\x1b[48;2;255;0;0m"
+		whiteBack = "\x1b[48;2;255;255;255m"
+		noBack    = "\x1b[49m"
+		reset     = "\x1b[0m"
+	)
+
+	tests := []struct {
+		name       string
+		background color.Color
+		want       []string
+	}{
+		{
+			name:       "Fond opaque",
+			background: color.White,
+			want: []string{
+				redText + whiteBack + "▀" + whiteText + redBack + "▀" + whiteBack + "▀" + reset,
+				// Dernière ligne sans moitié basse
+				redText + noBack + "▀▀" + whiteText + "▀" + reset,
+			},
+		},
+		{
+			name:       "Fond transparent",
+			background: color.Transparent,
+			want: []string{
+				redText + noBack + "▀▄ " + reset,
+				redText + noBack + "▀▀ " + reset,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := RenderOptions{
+				Foreground:    color.NRGBA{R: 0xff, A: 0xff},
+				Background:    tt.background,
+				TerminalColor: true,
+			}
+
+			var buf bytes.Buffer
+			if err := RenderTerminal(&buf, m, opts); err != nil {
+				t.Fatalf("RenderTerminal() error = %v", err)
+			}
+
+			want := strings.Join(tt.want, "\n") + "\n"
+			if got := buf.String(); got != want {
+				t.Errorf("RenderTerminal() = %q, attendu %q", got, want)
+			}
+		})
+	}
+}
@@ -1,9 +1,40 @@
 package qr
 
 import (
+	"fmt"
+	"io"
+	"os"
 	"strconv"
+	"sync"
 )
 
+var (
+	// Destination des traces de génération
+	logOutput io.Writer = os.Stdout
+
+	// Mutex pour protéger l'accès à la destination des traces
+	logMutex sync.RWMutex
+)
+
+// SetLogOutput redirige les traces de génération vers w (io.Discard pour les désactiver)
+func SetLogOutput(w io.Writer) {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	logOutput = w
+}
+
+// logf écrit une trace de génération, sans formater le message si les traces sont désactivées
+func logf(format string, args ...any) {
+	logMutex.RLock()
+	w := logOutput
+	logMutex.RUnlock()
+
+	if w == io.Discard {
+		return
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
 // Min renvoie le minimum de deux entiers
 func min(a, b int) int {
 	if a < b {
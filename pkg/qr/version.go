@@ -80,3 +80,52 @@ var FormatInfo = map[string]map[int]string{
 		7: "000100000111011",
 	},
 }
+
+// ECCodewordsPerBlock donne, pour chaque niveau de correction, le nombre de mots
+// de correction de chaque bloc Reed-Solomon selon la version (indice 1 à 40),
+// d'après le tableau 9 de la norme ISO/IEC 18004
+var ECCodewordsPerBlock = map[string][41]int{
+	"L": {0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	"M": {0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	"Q": {0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	"H": {0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// ECBlockCount donne, pour chaque niveau de correction, le nombre de blocs
+// Reed-Solomon selon la version (indice 1 à 40), d'après le même tableau
+var ECBlockCount = map[string][41]int{
+	"L": {0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	"M": {0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	"Q": {0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	"H": {0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// misdecodeProtection est le nombre de mots de correction que les petites
+// versions réservent à la détection des fausses lectures, indexé par version
+// puis par niveau ; ils ne servent pas à corriger
+var misdecodeProtection = map[int]map[string]int{
+	1: {"L": 3, "M": 2, "Q": 1, "H": 1},
+	2: {"L": 2},
+	3: {"L": 1},
+}
+
+// totalCodewords retourne le nombre de mots de code, données et correction,
+// d'un symbole de la version donnée : les modules restants une fois les motifs
+// de fonction retirés, divisés par 8 (les bits de reste sont ignorés)
+func totalCodewords(version int) int {
+	modules := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		modules -= (25*align-10)*align - 55
+	}
+	if version >= 7 {
+		modules -= 36
+	}
+	return modules / 8
+}
+
+// dataCodewords retourne le nombre de mots de données d'un symbole de la version
+// et du niveau donnés : les mots de code moins la correction de tous les blocs
+func dataCodewords(version int, level string) int {
+	return totalCodewords(version) - ECBlockCount[level][version]*ECCodewordsPerBlock[level][version]
+}